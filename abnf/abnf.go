@@ -0,0 +1,161 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+// Package abnf compile grammar text in ABNF notation, as specified by
+// RFC 5234 and RFC 7405, into parsers composed with AST combinators, so
+// that grammars from RFCs can be used as is. For example,
+//
+//	; simplified from RFC 3986.
+//	URI       = scheme ":" hier-part [ "?" query ]
+//	scheme    = ALPHA *( ALPHA / DIGIT / "+" / "-" / "." )
+//	hier-part = "//" host *( "/" segment )
+//	...
+//
+// Supported syntax:
+//
+//	name = elements       rule definition.
+//	name =/ elements      incremental alternatives for rule `name`.
+//	"text"  %i"text"      literal, matched ignoring case.
+//	%s"text"              literal, case sensitive.
+//	%x41  %d65  %b1000001 character, by its code point.
+//	%x41.42.43            sequence of characters.
+//	%x30-39               range of characters.
+//	a b                   concatenation.
+//	a / b                 alternatives.
+//	n*m e                 repetition, default n is 0 and m is infinity.
+//	n e                   repetition, exactly n times.
+//	[ e ]                 optional.
+//	( e )                 grouping.
+//	; comment
+//
+// Grammar text is parsed into peg.Grammar and parsers are built as
+// described by package peg, except that terminals are matched using
+// parsec.AtomExact and parsec.TokenExact, white space is explicit in
+// ABNF. Rule names are case insensitive, references are resolved to
+// the first definition of the rule. Core rules, like ALPHA, DIGIT,
+// HEXDIG, CRLF etc. are added to the grammar if referenced and not
+// defined.
+//
+// Numeric values are matched as unicode code points, prose values, like
+// `<text>`, are not supported.
+package abnf
+
+import "fmt"
+import "strings"
+import "unicode/utf8"
+
+import "github.com/prataprc/goparsec"
+import "github.com/prataprc/goparsec/peg"
+
+// Compile ABNF grammar text into parsers composed with combinators from
+// ast, and return the parser for the first rule.
+func Compile(ast *parsec.AST, text []byte) (parsec.Parser, error) {
+	g, err := Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return g.Build(ast, "")
+}
+
+// Parse ABNF grammar text. Return *parsec.ParseError for malformed text,
+// and parsec.Diagnostics for invalid rules.
+func Parse(text []byte) (*peg.Grammar, error) {
+	var diags parsec.Diagnostics
+
+	defs, err := parse(text)
+	if err != nil {
+		return nil, err
+	}
+	g := &peg.Grammar{Text: text}
+	rules := make(map[string]*peg.Rule) // indexed by lower case name.
+	for _, def := range defs {
+		name := strings.ToLower(def.rule.Name)
+		rule, ok := rules[name]
+		switch {
+		case def.incremental && !ok:
+			fmsg := "incremental alternatives for undefined rule %q"
+			diags = append(diags, errorf(text, def.rule.Pos, fmsg, def.rule.Name))
+		case def.incremental:
+			rule.Expr = alternatives(rule.Expr, def.rule.Expr)
+		case ok:
+			fmsg := "rule %q redefined"
+			diags = append(diags, errorf(text, def.rule.Pos, fmsg, def.rule.Name))
+		default:
+			rules[name] = def.rule
+			g.Rules = append(g.Rules, def.rule)
+		}
+	}
+
+	// resolve references, adding core rules as they are referenced.
+	core := corerules()
+	var resolve func(expr *peg.Expr)
+	resolve = func(expr *peg.Expr) {
+		if expr.Op == peg.Ref && strings.HasPrefix(expr.Text, "<") {
+			fmsg := "prose value %v not supported"
+			diags = append(diags, errorf(text, expr.Pos, fmsg, expr.Text))
+		} else if expr.Op == peg.Ref {
+			name := strings.ToLower(expr.Text)
+			rule, ok := rules[name]
+			if !ok && core[name] != nil {
+				rule = core[name]
+				rules[name], g.Rules = rule, append(g.Rules, rule)
+			}
+			if rule != nil {
+				expr.Text = rule.Name
+			}
+		}
+		for _, arg := range expr.Args {
+			resolve(arg)
+		}
+	}
+	for i := 0; i < len(g.Rules); i++ {
+		resolve(g.Rules[i].Expr)
+	}
+
+	if len(diags) > 0 {
+		return nil, diags
+	} else if err := g.Validate(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// definition of a rule, incremental definitions add alternatives to
+// an existing rule.
+type definition struct {
+	rule        *peg.Rule
+	incremental bool
+}
+
+func parse(text []byte) ([]*definition, error) {
+	s := parsec.NewScanner(text).SetWSPattern(`^(?:[ \t\r\n]+|;[^\n]*)+`)
+	node, _, err := parsec.Parse(y, s)
+	if err != nil {
+		return nil, err
+	}
+	return node.([]*definition), nil
+}
+
+// alternatives return expr with alternatives from alt appended.
+func alternatives(expr, alt *peg.Expr) *peg.Expr {
+	if expr.Op != peg.Choice {
+		expr = &peg.Expr{Op: peg.Choice, Args: []*peg.Expr{expr}, Pos: expr.Pos}
+	}
+	if alt.Op == peg.Choice {
+		expr.Args = append(expr.Args, alt.Args...)
+	} else {
+		expr.Args = append(expr.Args, alt)
+	}
+	return expr
+}
+
+// errorf return a *parsec.ParseError for grammar text at pos.
+func errorf(text []byte, pos int, fmsg string, args ...interface{}) *parsec.ParseError {
+	prefix := string(text[:pos])
+	return &parsec.ParseError{
+		Cursor:  pos,
+		Lineno:  strings.Count(prefix, "\n") + 1,
+		Column:  utf8.RuneCountInString(prefix[strings.LastIndex(prefix, "\n")+1:]) + 1,
+		Message: fmt.Sprintf(fmsg, args...),
+	}
+}
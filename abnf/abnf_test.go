@@ -0,0 +1,156 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package abnf
+
+import "reflect"
+import "strings"
+import "testing"
+
+import "github.com/prataprc/goparsec"
+
+var uriText = []byte(`
+; simplified from RFC 3986.
+URI         = scheme ":" hier-part [ "?" query ]
+scheme      = ALPHA *( ALPHA / DIGIT / "+" / "-" / "." )
+hier-part   = "//" authority path
+authority   = host [ ":" port ]
+host        = 1*( ALPHA / DIGIT / "-" / "." )
+port        = *DIGIT
+path        = *( "/" segment )
+segment     = *pchar
+pchar       = ALPHA / DIGIT / "-" / "." / "_" / "~" / pct-encoded
+pct-encoded = "%" HEXDIG HEXDIG
+query       = *( pchar / "/" / "?" / "=" / "&" )
+`)
+
+func TestCompile(t *testing.T) {
+	ast := parsec.NewAST("uri", 100)
+	y, err := Compile(ast, uriText)
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+	text := "HTTP://example.com:8080/a/b%2Fc?x=1&y"
+	root, s := ast.Parsewith(y, parsec.NewScanner([]byte(text)))
+	if ast.Error() != nil {
+		t.Fatalf("unexpected %v", ast.Error())
+	} else if !s.Endof() {
+		t.Errorf("expected end of text, got %v", s.GetCursor())
+	}
+	testcases := [][]string{
+		{"scheme", "HTTP"},
+		{"host", "example.com"},
+		{"port", "8080"},
+		{"pct-encoded", "%2F"},
+		{"query", "x=1&y"},
+	}
+	for _, tcase := range testcases {
+		if nodes := collect(root, tcase[0]); len(nodes) == 0 {
+			t.Errorf("expected %v", tcase[0])
+		} else if value := nodes[0].GetValue(); value != tcase[1] {
+			t.Errorf("expected %v, got %v", tcase[1], value)
+		}
+	}
+
+	// white space is explicit.
+	ast = parsec.NewAST("uri", 100)
+	y, _ = Compile(ast, uriText)
+	_, s = ast.Parsewith(y, parsec.NewScanner([]byte("http: //example.com")))
+	if ast.Error() == nil && s.Endof() {
+		t.Errorf("expected error")
+	}
+}
+
+func TestValues(t *testing.T) {
+	testcases := []struct {
+		grammar string
+		text    string
+		ok      bool
+	}{
+		{`a = "Hello" SP %s"World"`, "hELLO World", true},
+		{`a = "Hello" SP %s"World"`, "Hello world", false},
+		{`a = %i"ok"`, "OK", true},
+		{`a = %x41.42 %d67 %b1000100 %x45-46`, "ABCDF", true},
+		{`a = %x41.42 %d67 %b1000100 %x45-46`, "ABCDG", false},
+		{`a = %xE9 %x4E16-4E17`, "é丗", true},
+		{`a = 2*3DIGIT 2ALPHA *1"x"`, "123ab", true},
+		{`a = 2*3DIGIT 2ALPHA *1"x"`, "1ab", false},
+		{`a = 2*3DIGIT 2ALPHA *1"x"`, "12aBx", true},
+		{`a = *2( "-" ) "+"`, "--+", true},
+		{`a = *2( "-" ) "+"`, "---+", false},
+		{"a = b\nb = \"x\"\na =/ \"y\" / \"z\"", "z", true},
+		{"a = B\nb = LWSP \"x\"", " \r\n x", true},
+		{"a = B\nb = LWSP \"x\"", "\r\nx", false},
+		{"a = 1*VCHAR CRLF", "x=y\r\n", true},
+	}
+	for _, tcase := range testcases {
+		ast := parsec.NewAST("values", 100)
+		y, err := Compile(ast, []byte(tcase.grammar))
+		if err != nil {
+			t.Errorf("%q unexpected %v", tcase.grammar, err)
+			continue
+		}
+		_, s := ast.Parsewith(y, parsec.NewScanner([]byte(tcase.text)))
+		if ok := ast.Error() == nil && s.Endof(); ok != tcase.ok {
+			t.Errorf("%q %q expected %v, got %v", tcase.grammar, tcase.text, tcase.ok, ok)
+		}
+	}
+}
+
+func TestCoreRules(t *testing.T) {
+	g, err := Parse([]byte("id = HEXDIG crlf\ndigit = %x30-37"))
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+	names := make([]string, 0)
+	for _, rule := range g.Rules {
+		names = append(names, rule.Name)
+	}
+	ref := []string{"id", "digit", "HEXDIG", "CRLF", "CR", "LF"}
+	if !reflect.DeepEqual(names, ref) {
+		t.Errorf("expected %v, got %v", ref, names)
+	}
+	// rule names and quoted strings are case insensitive.
+	hexdig := `digit / "A"i / "B"i / "C"i / "D"i / "E"i / "F"i`
+	if out := g.Rule("HEXDIG").Expr.String(); out != hexdig {
+		t.Errorf("expected %v, got %v", hexdig, out)
+	}
+	if ref, out := "CR LF", g.Rule("CRLF").Expr.String(); out != ref {
+		t.Errorf("expected %v, got %v", ref, out)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	testcases := []struct {
+		text string
+		err  string
+	}{
+		{"a = \"x\"\nb = (\"y\"", "parse error at line 2 col 9, expected one of"},
+		{"a = \"x\"\nA = \"y\"", `parse error at line 2 col 1, rule "A" redefined`},
+		{"a = \"x\"\nb =/ \"y\"", "parse error at line 2 col 1, incremental alternatives"},
+		{"a = <any text>", "parse error at line 1 col 5, prose value <any text> not supported"},
+		{"a = b", `parse error at line 1 col 5, undefined rule "b"`},
+		{"a = %x39-30", "parse error at line 1 col 5, error parsing regexp"},
+		{"a = %x110000", "parse error at line 1 col 5, error parsing regexp"},
+		{"a = 3*2\"x\"", "parse error at line 1 col 5, invalid repetition 3..2"},
+	}
+	for _, tcase := range testcases {
+		_, err := Parse([]byte(tcase.text))
+		if err == nil {
+			t.Errorf("%q expected error", tcase.text)
+		} else if !strings.HasPrefix(err.Error(), tcase.err) {
+			t.Errorf("%q expected %v, got %v", tcase.text, tcase.err, err)
+		}
+	}
+}
+
+func collect(q parsec.Queryable, name string) []parsec.Queryable {
+	nodes := make([]parsec.Queryable, 0)
+	if q.GetName() == name {
+		nodes = append(nodes, q)
+	}
+	for _, child := range q.GetChildren() {
+		nodes = append(nodes, collect(child, name)...)
+	}
+	return nodes
+}
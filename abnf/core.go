@@ -0,0 +1,41 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package abnf
+
+import "strings"
+
+import "github.com/prataprc/goparsec/peg"
+
+// coretext defines core rules, from RFC 5234 Appendix B.1.
+var coretext = []byte(`
+ALPHA  = %x41-5A / %x61-7A   ; A-Z / a-z
+BIT    = "0" / "1"
+CHAR   = %x01-7F             ; any 7-bit US-ASCII character, excluding NUL
+CR     = %x0D                ; carriage return
+CRLF   = CR LF               ; Internet standard newline
+CTL    = %x00-1F / %x7F      ; controls
+DIGIT  = %x30-39             ; 0-9
+DQUOTE = %x22                ; " (Double Quote)
+HEXDIG = DIGIT / "A" / "B" / "C" / "D" / "E" / "F"
+HTAB   = %x09                ; horizontal tab
+LF     = %x0A                ; linefeed
+LWSP   = *(WSP / CRLF WSP)   ; linear white space, past newline
+OCTET  = %x00-FF             ; 8 bits of data
+SP     = %x20
+VCHAR  = %x21-7E             ; visible (printing) characters
+WSP    = SP / HTAB           ; white space
+`)
+
+// corerules return a new copy of core rules, indexed by lower case name.
+func corerules() map[string]*peg.Rule {
+	defs, err := parse(coretext)
+	if err != nil {
+		panic(err)
+	}
+	rules := make(map[string]*peg.Rule)
+	for _, def := range defs {
+		rules[strings.ToLower(def.rule.Name)] = def.rule
+	}
+	return rules
+}
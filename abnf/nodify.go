@@ -0,0 +1,218 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package abnf
+
+import "fmt"
+import "strconv"
+import "strings"
+import "unicode/utf8"
+
+import "github.com/prataprc/goparsec"
+import "github.com/prataprc/goparsec/peg"
+
+// y is the parser for ABNF grammar text.
+//
+//	rulelist      -> rule+ EOF
+//	rule          -> rulename defined-as alternation
+//	defined-as    -> "=/" / "="
+//	alternation   -> concatenation *("/" concatenation)
+//	concatenation -> 1*repetition
+//	repetition    -> [REPEAT] element
+//	element       -> rulename !defined-as / group / option
+//	               / CHARVAL / NUMVAL / PROSE
+//	group         -> "(" alternation ")"
+//	option        -> "[" alternation "]"
+var y = rulelist()
+
+func rulelist() parsec.Parser {
+	var alternation parsec.Parser
+
+	rulename := parsec.Token(`[A-Za-z][A-Za-z0-9-]*`, "RULENAME")
+	defined := parsec.OrdChoice(parsec.First,
+		parsec.Atom("=/", "INCREMENTAL"), parsec.Atom("=", "DEFINE"))
+	ref := parsec.And(refNode, rulename, parsec.NotFollowedBy(defined))
+	group := parsec.Between(parsec.First,
+		parsec.Atom("(", "OPEN"), parsec.Atom(")", "CLOSE"), &alternation)
+	option := parsec.Between(
+		optionNode, parsec.Atom("[", "OPENSQR"), parsec.Atom("]", "CLOSESQR"),
+		&alternation)
+	charval := parsec.Token(`(?:%[siSI])?"[\x20\x21\x23-\x7E]*"`, "CHARVAL")
+	numval := parsec.Token(
+		`(?i)%(?:b[01]+(?:(?:\.[01]+)+|-[01]+)?`+
+			`|d[0-9]+(?:(?:\.[0-9]+)+|-[0-9]+)?`+
+			`|x[0-9a-f]+(?:(?:\.[0-9a-f]+)+|-[0-9a-f]+)?)`,
+		"NUMVAL")
+	prose := parsec.Token(`<[\x20-\x3D\x3F-\x7E]*>`, "PROSE")
+	element := parsec.OrdChoice(
+		elementNode, ref, group, option, charval, numval, prose)
+	repeat := parsec.Token(`(?:[0-9]*\*[0-9]*|[0-9]+)`, "REPEAT")
+	repetition := parsec.And(
+		repetitionNode, parsec.Maybe(parsec.First, repeat), element)
+	concatenation := parsec.Many(concatenationNode, repetition)
+	alternation = parsec.And(
+		alternationNode,
+		concatenation,
+		parsec.Kleene(nil, parsec.And(nil, parsec.Atom("/", "SLASH"), concatenation)))
+	rule := parsec.And(ruleNode, rulename, defined, &alternation)
+	return parsec.And(rulelistNode, parsec.Many(nil, rule), parsec.EndWS())
+}
+
+//----------
+// Nodifiers
+//----------
+
+func rulelistNode(ns []parsec.ParsecNode) parsec.ParsecNode {
+	items := ns[0].([]parsec.ParsecNode)
+	defs := make([]*definition, 0, len(items))
+	for _, item := range items {
+		defs = append(defs, item.(*definition))
+	}
+	return defs
+}
+
+func ruleNode(ns []parsec.ParsecNode) parsec.ParsecNode {
+	name := ns[0].(*parsec.Terminal)
+	return &definition{
+		rule:        &peg.Rule{Name: name.Value, Expr: ns[2].(*peg.Expr), Pos: name.Position},
+		incremental: ns[1].(*parsec.Terminal).Name == "INCREMENTAL",
+	}
+}
+
+func alternationNode(ns []parsec.ParsecNode) parsec.ParsecNode {
+	items := exprs(ns)
+	if len(items) == 1 {
+		return items[0]
+	}
+	return &peg.Expr{Op: peg.Choice, Args: items, Pos: items[0].Pos}
+}
+
+func concatenationNode(ns []parsec.ParsecNode) parsec.ParsecNode {
+	items := exprs(ns)
+	if len(items) == 1 {
+		return items[0]
+	}
+	return &peg.Expr{Op: peg.Sequence, Args: items, Pos: items[0].Pos}
+}
+
+func repetitionNode(ns []parsec.ParsecNode) parsec.ParsecNode {
+	expr := ns[1].(*peg.Expr)
+	t, ok := ns[0].(*parsec.Terminal)
+	if !ok {
+		return expr
+	}
+	rep := &peg.Expr{Op: peg.Repetition, Args: []*peg.Expr{expr}, Pos: t.Position}
+	if i := strings.IndexByte(t.Value, '*'); i < 0 {
+		rep.Min = atoi(t.Value, 0)
+		rep.Max = rep.Min
+	} else {
+		rep.Min, rep.Max = atoi(t.Value[:i], 0), atoi(t.Value[i+1:], -1)
+	}
+	return rep
+}
+
+func optionNode(ns []parsec.ParsecNode) parsec.ParsecNode {
+	expr := ns[0].(*peg.Expr)
+	return &peg.Expr{
+		Op: peg.Repetition, Args: []*peg.Expr{expr}, Min: 0, Max: 1, Pos: expr.Pos,
+	}
+}
+
+func refNode(ns []parsec.ParsecNode) parsec.ParsecNode {
+	t := ns[0].(*parsec.Terminal)
+	return &peg.Expr{Op: peg.Ref, Text: t.Value, Pos: t.Position}
+}
+
+func elementNode(ns []parsec.ParsecNode) parsec.ParsecNode {
+	switch n := ns[0].(type) {
+	case *peg.Expr:
+		return n
+	case *parsec.Terminal:
+		switch n.Name {
+		case "CHARVAL":
+			return charval(n)
+		case "NUMVAL":
+			return numval(n)
+		case "PROSE":
+			// prose values are reported while resolving references.
+			return &peg.Expr{Op: peg.Ref, Text: n.Value, Pos: n.Position}
+		}
+	}
+	return nil
+}
+
+//--------
+// Helpers
+//--------
+
+// charval is case insensitive, unless prefixed with `%s`.
+func charval(t *parsec.Terminal) *peg.Expr {
+	text, sensitive := t.Value, false
+	if text[0] == '%' {
+		sensitive, text = text[1] == 's' || text[1] == 'S', text[2:]
+	}
+	text = text[1 : len(text)-1]
+	return &peg.Expr{
+		Op: peg.Literal, Text: text, Exact: true, Pos: t.Position,
+		Fold: !sensitive && strings.ToLower(text) != strings.ToUpper(text),
+	}
+}
+
+// numval is a single code point, a sequence of code points or a range
+// of code points.
+func numval(t *parsec.Terminal) *peg.Expr {
+	base := map[byte]int{'b': 2, 'd': 10, 'x': 16}[t.Value[1]|0x20]
+	body := t.Value[2:]
+	expr := &peg.Expr{Op: peg.Regexp, Exact: true, Pos: t.Position}
+	if i := strings.IndexByte(body, '-'); i >= 0 {
+		lo, hi := codepoint(body[:i], base), codepoint(body[i+1:], base)
+		expr.Text = fmt.Sprintf(`[\x{%x}-\x{%x}]`, lo, hi)
+		return expr
+	}
+	var runes []rune
+	var pattern string
+	valid := true
+	for _, part := range strings.Split(body, ".") {
+		r := codepoint(part, base)
+		valid = valid && utf8.ValidRune(r)
+		runes, pattern = append(runes, r), pattern+fmt.Sprintf(`\x{%x}`, r)
+	}
+	if valid {
+		expr.Op, expr.Text = peg.Literal, string(runes)
+		return expr
+	}
+	// invalid code points are reported while validating the pattern.
+	expr.Text = pattern
+	return expr
+}
+
+// codepoint return -1 if text is out of range.
+func codepoint(text string, base int) rune {
+	n, err := strconv.ParseInt(text, base, 32)
+	if err != nil {
+		return -1
+	}
+	return rune(n)
+}
+
+// exprs from a list of nodes, nested lists are flattened and other
+// nodes, like separators, are skipped.
+func exprs(ns []parsec.ParsecNode) []*peg.Expr {
+	items := make([]*peg.Expr, 0, len(ns))
+	for _, n := range ns {
+		switch x := n.(type) {
+		case *peg.Expr:
+			items = append(items, x)
+		case []parsec.ParsecNode:
+			items = append(items, exprs(x)...)
+		}
+	}
+	return items
+}
+
+func atoi(text string, def int) int {
+	if n, err := strconv.Atoi(text); err == nil {
+		return n
+	}
+	return def
+}
@@ -0,0 +1,624 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "fmt"
+import "regexp"
+import "regexp/syntax"
+import "strings"
+
+// Combinators record their structure when they are constructed, so
+// that a grammar can be analyzed without parsing any input. Parsers
+// that do not record their structure, like custom Parser functions,
+// are analyzed as terminals that consume input, rules referenced only
+// from such parsers are reported as unreachable.
+
+// IssueKind classifies issues found by Analyze.
+type IssueKind int
+
+const (
+	// NullableLoop is a repetition whose body can match without
+	// consuming input, like Kleene(nil, Maybe(nil, x)).
+	NullableLoop IssueKind = iota + 1
+	// LeftRecursion is a cycle of rules that invoke each other without
+	// consuming input.
+	LeftRecursion
+	// UnreachableRule is a rule that is not reachable from root.
+	UnreachableRule
+	// ShadowedChoice is an OrdChoice alternative that can never match,
+	// because an earlier alternative matches whenever it does.
+	ShadowedChoice
+)
+
+func (kind IssueKind) String() string {
+	switch kind {
+	case NullableLoop:
+		return "nullable loop"
+	case LeftRecursion:
+		return "left recursion"
+	case UnreachableRule:
+		return "unreachable rule"
+	case ShadowedChoice:
+		return "shadowed choice"
+	}
+	return fmt.Sprintf("IssueKind(%d)", int(kind))
+}
+
+// Issue found by static analysis of a grammar.
+type Issue struct {
+	Kind    IssueKind
+	Rule    string // rule where the issue is found, empty if unnamed.
+	Message string
+}
+
+func (issue Issue) String() string {
+	if issue.Rule == "" {
+		return fmt.Sprintf("%v: %v", issue.Kind, issue.Message)
+	}
+	return fmt.Sprintf("%v: rule %q: %v", issue.Kind, issue.Rule, issue.Message)
+}
+
+// Analyze the grammar reachable from root parser, or reference to a
+// parser, and return the issues found. Rules referenced via *Parser
+// are named after the AST combinator defining them, if any. Unreachable
+// rules are reported only by Grammar.Analyze.
+func Analyze(root interface{}) []Issue {
+	a := newanalyzer(nil)
+	a.analyze(root)
+	return a.issues
+}
+
+// Analyze the grammar for `root` rule, same as package level Analyze,
+// and report rules that are not reachable from root.
+func (g *Grammar) Analyze(root string) []Issue {
+	a := newanalyzer(g.names())
+	if rule, ok := g.rules[root]; ok {
+		a.analyze(rule)
+	}
+	for _, name := range g.order {
+		if _, ok := a.refs[g.rules[name]]; !ok {
+			a.report(UnreachableRule, name, "not reachable from "+root)
+		}
+	}
+	return a.issues
+}
+
+// LeftRecursive return the rules that can invoke themselves, directly
+// or via other rules, without consuming input, in the order of
+// definition. Unlike Analyze, all rules are considered, whether they
+// are reachable from a root or not.
+func (g *Grammar) LeftRecursive() []string {
+	a := newanalyzer(g.names())
+	for _, name := range g.order {
+		a.discover(g.rules[name])
+	}
+	a.nullability()
+	edges, sccs := a.leftcomponents()
+	leftrec := make(map[*structure]bool)
+	for _, scc := range sccs {
+		if a.cycle(scc, edges) == nil {
+			continue
+		}
+		for _, rule := range scc {
+			leftrec[rule] = true
+		}
+	}
+	var rules []string
+	for _, name := range g.order {
+		if leftrec[a.refs[g.rules[name]]] {
+			rules = append(rules, name)
+		}
+	}
+	return rules
+}
+
+// names of rules, keyed by their reference.
+func (g *Grammar) names() map[*Parser]string {
+	names := make(map[*Parser]string)
+	for name, rule := range g.rules {
+		names[rule] = name
+	}
+	return names
+}
+
+// structure of a combinator.
+type structure struct {
+	kind    string        // combinator, like "And", "Token" etc..
+	name    string        // name of AST combinator, or terminal.
+	parsers []interface{} // sub-parsers, in the order of invocation.
+	after   []interface{} // sub-parsers invoked after consuming input.
+	min     int           // bounds for Repeat, max < 0 is unbounded.
+	max     int
+	text    string // pattern for Token, string for Atom.
+	exact   bool   // terminal does not skip leading white space.
+	pratt   *Pratt // expression parser, for Pratt.
+}
+
+func (st *structure) String() string {
+	switch {
+	case st.text != "" || st.kind == "Atom" || st.kind == "AtomExact":
+		return fmt.Sprintf("%v(%q)", st.kind, st.text)
+	case st.name != "":
+		return fmt.Sprintf("%v(%q)", st.kind, st.name)
+	}
+	return st.kind
+}
+
+// record the structure of parser, reported when parser is invoked with
+// probe.
+func record(st *structure, parser Parser) Parser {
+	return func(s Scanner) (ParsecNode, Scanner) {
+		if _, ok := s.(probe); ok {
+			return st, s
+		}
+		return parser(s)
+	}
+}
+
+// nonnil return parsers that are not nil, optional parsers like
+// separators can be nil.
+func nonnil(ps ...interface{}) []interface{} {
+	out := make([]interface{}, 0, len(ps))
+	for _, p := range ps {
+		if p != nil {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// probe is a Scanner to learn the structure of parsers, parsers that
+// did not record their structure fail on using it.
+type probe struct{}
+
+func (p probe) SetWSPattern(string) Scanner        { panic(p) }
+func (p probe) TrackLineno() Scanner               { panic(p) }
+func (p probe) Clone() Scanner                     { panic(p) }
+func (p probe) GetCursor() int                     { panic(p) }
+func (p probe) Match(string) ([]byte, Scanner)     { panic(p) }
+func (p probe) MatchString(string) (bool, Scanner) { panic(p) }
+func (p probe) SubmatchAll(string) (map[string][]byte, Scanner) {
+	panic(p)
+}
+func (p probe) SkipWS() ([]byte, Scanner)        { panic(p) }
+func (p probe) SkipAny(string) ([]byte, Scanner) { panic(p) }
+func (p probe) Lineno() int                      { panic(p) }
+func (p probe) Endof() bool                      { panic(p) }
+
+type analyzer struct {
+	names     map[*Parser]string
+	refs      map[*Parser]*structure      // rules, as "Rule" structure.
+	rules     []*structure                // rules, in the order of discovery.
+	args      map[*structure][]*structure // resolved sub-parsers.
+	order     []*structure                // structures, in the order of discovery.
+	ruleof    map[*structure]string       // rule where structure is found.
+	nullables map[*structure]bool
+	issues    []Issue
+}
+
+func newanalyzer(names map[*Parser]string) *analyzer {
+	return &analyzer{
+		names:     names,
+		refs:      make(map[*Parser]*structure),
+		args:      make(map[*structure][]*structure),
+		ruleof:    make(map[*structure]string),
+		nullables: make(map[*structure]bool),
+	}
+}
+
+func (a *analyzer) analyze(root interface{}) {
+	a.discover(root)
+	a.nullability()
+	for _, st := range a.order {
+		a.loops(st)
+		a.shadowed(st)
+	}
+	a.leftrecursion()
+}
+
+// discover structures and rules reachable from root, and return the
+// structure of root.
+func (a *analyzer) discover(root interface{}) *structure {
+	st := a.resolve(root)
+	a.walk(st, "")
+	for i := 0; i < len(a.rules); i++ { // rules are discovered while walking.
+		rule := a.rules[i]
+		a.walk(a.args[rule][0], rule.name)
+	}
+	return st
+}
+
+// resolve parser to its structure, references are resolved to a "Rule"
+// structure whose only sub-parser is the parser referenced.
+func (a *analyzer) resolve(parser interface{}) *structure {
+	switch p := parser.(type) {
+	case Parser:
+		return structureof(p)
+	case *Parser:
+		if st, ok := a.refs[p]; ok {
+			return st
+		}
+		name, ok := a.names[p]
+		body := structureof(*p)
+		if !ok && body.name != "" {
+			name = body.name
+		} else if !ok {
+			name = fmt.Sprintf("rule%v", len(a.refs)+1)
+		}
+		st := &structure{kind: "Rule", name: name}
+		a.refs[p], a.rules = st, append(a.rules, st)
+		a.args[st] = []*structure{body}
+		return st
+	}
+	panic(fmt.Errorf("type of parser `%T` not supported", parser))
+}
+
+// structureof parser, recorded by the combinator.
+func structureof(parser Parser) (st *structure) {
+	defer func() {
+		if r := recover(); r != nil { // did not record its structure.
+			st = &structure{kind: "Parser"}
+		}
+	}()
+	if parser != nil {
+		if node, _ := parser(probe{}); node != nil {
+			if st, ok := node.(*structure); ok {
+				return st
+			}
+		}
+	}
+	return &structure{kind: "Parser"}
+}
+
+func (a *analyzer) walk(st *structure, rule string) {
+	if _, ok := a.ruleof[st]; ok || st.kind == "Rule" {
+		return
+	}
+	a.ruleof[st], a.order = rule, append(a.order, st)
+	parsers := append(append([]interface{}(nil), st.parsers...), st.after...)
+	args := make([]*structure, 0, len(parsers))
+	for _, parser := range parsers {
+		args = append(args, a.resolve(parser))
+	}
+	a.args[st] = args
+	for _, arg := range args {
+		a.walk(arg, rule)
+	}
+}
+
+// nullability compute structures that can match without consuming
+// input, iterated until there is no change, since rules can be
+// recursive.
+func (a *analyzer) nullability() {
+	structures := append(append([]*structure(nil), a.order...), a.rules...)
+	for changed := true; changed; {
+		changed = false
+		for _, st := range structures {
+			if !a.nullables[st] && a.nullable(st) {
+				a.nullables[st], changed = true, true
+			}
+		}
+	}
+}
+
+func (a *analyzer) nullable(st *structure) bool {
+	args := a.invoked(st)
+	switch st.kind {
+	case "Token", "TokenExact", "OrdTokens":
+		re, err := syntax.Parse(st.text, syntax.Perl)
+		return err == nil && minlen(re) == 0
+	case "Atom", "AtomExact":
+		return st.text == ""
+	case "String", "Parser":
+		return false
+	case "And", "Between", "Permutation":
+		return a.all(args)
+	case "OrdChoice", "LongestChoice":
+		return a.any(args)
+	case "Repeat":
+		return st.min == 0 || a.all(args)
+	case "Many", "Chainl1", "Chainr1", "Skip", "Recover", "Pratt", "Ref", "Rule":
+		return a.nullables[args[0]]
+	case "ManyUntil": // until, op, sep
+		return a.nullables[args[1]]
+	}
+	// zero width parsers, and repetitions that never fail.
+	return true
+}
+
+// neverfails return true if structure always match, at any position.
+func (a *analyzer) neverfails(st *structure, visited map[*structure]bool) bool {
+	if visited[st] {
+		return false
+	}
+	visited[st] = true
+	defer delete(visited, st)
+
+	args := a.invoked(st)
+	switch st.kind {
+	case "Kleene", "Maybe", "SepBy", "SepEndBy", "EndBy", "Cut", "Commit":
+		return true
+	case "Atom", "AtomExact":
+		return st.text == ""
+	case "Repeat":
+		return st.min == 0
+	case "And", "Between", "Permutation":
+		for _, arg := range args {
+			if !a.neverfails(arg, visited) {
+				return false
+			}
+		}
+		return true
+	case "OrdChoice", "LongestChoice":
+		for _, arg := range args {
+			if a.neverfails(arg, visited) {
+				return true
+			}
+		}
+		return false
+	case "Skip", "Ref", "Rule":
+		return a.neverfails(args[0], visited)
+	}
+	return false
+}
+
+// loops report repetitions that can loop without consuming input.
+func (a *analyzer) loops(st *structure) {
+	args := a.args[st]
+	switch st.kind {
+	case "Kleene", "Many", "SepBy", "SepEndBy", "EndBy":
+	case "ManyUntil":
+		args = args[1:]
+	case "Repeat":
+		if st.max >= 0 {
+			return
+		}
+	default:
+		return
+	}
+	if a.all(args) {
+		a.report(NullableLoop, a.ruleof[st], st.String()+" can repeat without consuming input")
+	}
+}
+
+// shadowed report OrdChoice alternatives that can never match.
+func (a *analyzer) shadowed(st *structure) {
+	if st.kind != "OrdChoice" {
+		return
+	}
+	args := a.args[st]
+	for j := 1; j < len(args); j++ {
+		for i := 0; i < j; i++ {
+			if a.shadows(args[i], args[j]) {
+				fmsg := "alternative %v of %v is shadowed by alternative %v"
+				a.report(ShadowedChoice, a.ruleof[st], fmt.Sprintf(fmsg, j+1, st, i+1))
+				break
+			}
+		}
+	}
+}
+
+// shadows return true if x matches whenever y matches.
+func (a *analyzer) shadows(x, y *structure) bool {
+	switch {
+	case x == y || a.neverfails(x, make(map[*structure]bool)):
+		return true
+	case isexact(x) && !isexact(y):
+		return false
+	case isatom(x) && isatom(y):
+		return strings.HasPrefix(y.text, x.text)
+	case istoken(x) && istoken(y):
+		return x.text == y.text
+	case istoken(x) && isatom(y):
+		re, err := regexp.Compile(x.text)
+		return err == nil && re.MatchString(y.text)
+	}
+	return false
+}
+
+// leftrecursion report cycles of rules invoking each other without
+// consuming input.
+func (a *analyzer) leftrecursion() {
+	edges, sccs := a.leftcomponents()
+	cycles := make(map[*structure][]*structure) // first rule -> cycle.
+	for _, scc := range sccs {
+		if cycle := a.cycle(scc, edges); cycle != nil {
+			cycles[cycle[0]] = cycle
+		}
+	}
+	for _, rule := range a.rules {
+		if cycle, ok := cycles[rule]; ok {
+			names := make([]string, 0, len(cycle))
+			for _, rule := range cycle {
+				names = append(names, rule.name)
+			}
+			a.report(LeftRecursion, rule.name, strings.Join(names, " -> "))
+		}
+	}
+}
+
+// leftcomponents return the rules that every rule can invoke before
+// consuming input, and the strongly connected components of rules
+// invoking each other, via such edges.
+func (a *analyzer) leftcomponents() (
+	map[*structure][]*structure, [][]*structure) {
+
+	edges := make(map[*structure][]*structure)
+	for _, rule := range a.rules {
+		visited := make(map[*structure]bool)
+		edges[rule] = a.leftrules(a.args[rule][0], visited, nil)
+	}
+
+	index, lowlink := make(map[*structure]int), make(map[*structure]int)
+	onstack, stack := make(map[*structure]bool), []*structure{}
+	var sccs [][]*structure
+	var connect func(rule *structure)
+	connect = func(rule *structure) {
+		index[rule], lowlink[rule] = len(index), len(index)
+		stack, onstack[rule] = append(stack, rule), true
+		for _, next := range edges[rule] {
+			if _, ok := index[next]; !ok {
+				connect(next)
+				if lowlink[next] < lowlink[rule] {
+					lowlink[rule] = lowlink[next]
+				}
+			} else if onstack[next] && index[next] < lowlink[rule] {
+				lowlink[rule] = index[next]
+			}
+		}
+		if lowlink[rule] != index[rule] {
+			return
+		}
+		var scc []*structure
+		for {
+			next := stack[len(stack)-1]
+			stack, onstack[next] = stack[:len(stack)-1], false
+			if scc = append(scc, next); next == rule {
+				break
+			}
+		}
+		sccs = append(sccs, scc)
+	}
+	for _, rule := range a.rules {
+		if _, ok := index[rule]; !ok {
+			connect(rule)
+		}
+	}
+	return edges, sccs
+}
+
+// leftrules return rules that st can invoke before consuming input.
+func (a *analyzer) leftrules(
+	st *structure, visited map[*structure]bool,
+	rules []*structure) []*structure {
+
+	if st.kind == "Rule" {
+		return append(rules, st)
+	} else if visited[st] {
+		return rules
+	}
+	visited[st] = true
+	args := a.invoked(st)
+	switch st.kind {
+	case "And", "Between", "Kleene", "Many", "ManyUntil", "Repeat",
+		"SepBy", "SepEndBy", "EndBy", "Chainl1", "Chainr1":
+		for _, arg := range args { // in sequence.
+			if rules = a.leftrules(arg, visited, rules); !a.nullables[arg] {
+				break
+			}
+		}
+		return rules
+	}
+	for _, arg := range args {
+		rules = a.leftrules(arg, visited, rules)
+	}
+	return rules
+}
+
+// cycle return a cycle of rules, starting from the rule discovered
+// first, in the strongly connected component scc. Return nil if scc
+// is a single rule that does not invoke itself.
+func (a *analyzer) cycle(
+	scc []*structure, edges map[*structure][]*structure) []*structure {
+
+	members := make(map[*structure]bool)
+	for _, rule := range scc {
+		members[rule] = true
+	}
+	var start *structure
+	for _, rule := range a.rules {
+		if members[rule] {
+			start = rule
+			break
+		}
+	}
+	visited := make(map[*structure]bool)
+	var path func(rule *structure, cycle []*structure) []*structure
+	path = func(rule *structure, cycle []*structure) []*structure {
+		cycle = append(cycle, rule)
+		for _, next := range edges[rule] {
+			if next == start {
+				return append(cycle, start)
+			} else if members[next] && !visited[next] {
+				visited[next] = true
+				if c := path(next, cycle); c != nil {
+					return c
+				}
+			}
+		}
+		return nil
+	}
+	return path(start, nil)
+}
+
+// invoked return sub-parsers that are invoked before consuming input.
+func (a *analyzer) invoked(st *structure) []*structure {
+	if st.kind == "Rule" {
+		return a.args[st]
+	}
+	return a.args[st][:len(st.parsers)]
+}
+
+func (a *analyzer) all(args []*structure) bool {
+	for _, arg := range args {
+		if !a.nullables[arg] {
+			return false
+		}
+	}
+	return true
+}
+
+func (a *analyzer) any(args []*structure) bool {
+	for _, arg := range args {
+		if a.nullables[arg] {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *analyzer) report(kind IssueKind, rule, msg string) {
+	a.issues = append(a.issues, Issue{Kind: kind, Rule: rule, Message: msg})
+}
+
+func isatom(st *structure) bool {
+	return st.kind == "Atom" || st.kind == "AtomExact"
+}
+
+func istoken(st *structure) bool {
+	return st.kind == "Token" || st.kind == "TokenExact"
+}
+
+func isexact(st *structure) bool {
+	return (isatom(st) || istoken(st)) && st.exact
+}
+
+// minlen return the minimum number of bytes matched by re.
+func minlen(re *syntax.Regexp) int {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return len(string(re.Rune))
+	case syntax.OpCharClass, syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		return 1
+	case syntax.OpCapture, syntax.OpPlus:
+		return minlen(re.Sub[0])
+	case syntax.OpRepeat:
+		return re.Min * minlen(re.Sub[0])
+	case syntax.OpConcat:
+		n := 0
+		for _, sub := range re.Sub {
+			n += minlen(sub)
+		}
+		return n
+	case syntax.OpAlternate:
+		n := minlen(re.Sub[0])
+		for _, sub := range re.Sub[1:] {
+			if m := minlen(sub); m < n {
+				n = m
+			}
+		}
+		return n
+	}
+	return 0
+}
@@ -0,0 +1,183 @@
+package parsec
+
+import "reflect"
+import "testing"
+
+func TestAnalyzeLoops(t *testing.T) {
+	x := Atom("x", "X")
+	testcases := []struct {
+		y   Parser
+		ref []string
+	}{
+		{Kleene(nil, x), nil},
+		{Kleene(nil, Maybe(nil, x)), []string{"Kleene"}},
+		{Kleene(nil, Maybe(nil, x), Atom(",", "COMMA")), nil},
+		{Many(nil, Token(`[0-9]*`, "DIGITS")), []string{"Many"}},
+		{ManyUntil(nil, Lookahead(x), End()), []string{"ManyUntil"}},
+		{AtLeast(nil, 1, And(nil, Kleene(nil, x), Cut())), []string{"Repeat"}},
+		{AtMost(nil, 2, Maybe(nil, x)), nil},
+		{SepBy(nil, Maybe(nil, x), Skip(Maybe(nil, x))), []string{"SepBy"}},
+	}
+	for i, tcase := range testcases {
+		var out []string
+		for _, issue := range Analyze(tcase.y) {
+			if issue.Kind != NullableLoop {
+				t.Errorf("%v unexpected %v", i, issue)
+			}
+			out = append(out, issue.Message[:len(tcase.ref[0])])
+		}
+		if !reflect.DeepEqual(out, tcase.ref) {
+			t.Errorf("%v expected %v, got %v", i, tcase.ref, out)
+		}
+	}
+}
+
+func TestNoProgress(t *testing.T) {
+	x := Atom("x", "X")
+	ast := NewAST("loops", 100)
+	for _, y := range []Parser{
+		Kleene(nil, Maybe(nil, x)),
+		Many(nil, Maybe(nil, x)),
+		ManyUntil(nil, Maybe(nil, x), Atom(";", "SEMI")),
+		AtLeast(nil, 0, Maybe(nil, x)),
+		SepBy(nil, Maybe(nil, x), Maybe(nil, Atom(",", "COMMA"))),
+		ast.Kleene("kleene", nil, ast.Maybe("maybe", nil, x)),
+		ast.Many("many", nil, ast.Maybe("maybe", nil, x)),
+		ast.ManyUntil("until", nil, ast.Maybe("maybe", nil, x), Atom(";", "SEMI")),
+	} {
+		node, _, err := Parse(y, NewScanner([]byte("x x y")))
+		if node != nil {
+			t.Errorf("unexpected %v", node)
+		} else if err == nil {
+			t.Errorf("expected error")
+		} else if ref := "parse error at line 1 col 4, repetition made no progress"; err.Error() != ref {
+			t.Errorf("expected %q, got %q", ref, err)
+		}
+	}
+
+	// AST.Parsewith.
+	y := ast.Kleene("kleene", nil, ast.Maybe("maybe", nil, x))
+	if root, _ := ast.Parsewith(y, NewScanner([]byte("x y"))); root != nil {
+		t.Errorf("unexpected %v", root)
+	} else if ast.Error() == nil {
+		t.Errorf("expected error")
+	}
+}
+
+func TestAnalyzeLeftRecursion(t *testing.T) {
+	var sum, prod, value Parser
+
+	ast := NewAST("expr", 100)
+	sum = ast.OrdChoice("sum", nil, ast.And("add", nil, &sum, Atom("+", "ADD"), &prod), &prod)
+	prod = ast.OrdChoice("prod", nil, ast.And("mul", nil, &prod, Atom("*", "MUL"), &value), &value)
+	value = ast.OrdChoice("value", nil,
+		Int(), ast.And("group", nil, Atom("(", "OPEN"), &sum, Atom(")", "CLOSE")))
+
+	issues := Analyze(&sum)
+	ref := []Issue{
+		{LeftRecursion, "sum", "sum -> sum"},
+		{LeftRecursion, "prod", "prod -> prod"},
+	}
+	if !reflect.DeepEqual(issues, ref) {
+		t.Errorf("expected %v, got %v", ref, issues)
+	}
+
+	// indirect, through nullable prefix.
+	g := NewGrammar("indirect")
+	g.Define("a", OrdChoice(nil, And(nil, g.Ref("b"), Atom("x", "X")), Int()))
+	g.Define("b", And(nil, Maybe(nil, Atom("-", "NEG")), g.Ref("c")))
+	g.Define("c", And(nil, Lookahead(Int()), g.Ref("a")))
+	issues = g.Analyze("a")
+	ref = []Issue{{LeftRecursion, "a", "a -> b -> c -> a"}}
+	if !reflect.DeepEqual(issues, ref) {
+		t.Errorf("expected %v, got %v", ref, issues)
+	}
+
+	// through pratt operand and unnamed rules.
+	var expr Parser
+	expr = NewPratt(OrdChoice(nil, Int(), &expr)).Infix(Atom("+", "ADD"), 10, AssocLeft, nil).Parser()
+	issues = Analyze(And(nil, &expr, End()))
+	ref = []Issue{{LeftRecursion, "rule1", "rule1 -> rule1"}}
+	if !reflect.DeepEqual(issues, ref) {
+		t.Errorf("expected %v, got %v", ref, issues)
+	}
+	if out, ref := issues[0].String(), `left recursion: rule "rule1": rule1 -> rule1`; out != ref {
+		t.Errorf("expected %v, got %v", ref, out)
+	}
+}
+
+func TestGrammarLeftRecursive(t *testing.T) {
+	// a -> b -> a and a -> c -> a, d is not reachable from a, e is not
+	// left recursive.
+	g := NewGrammar("leftrec")
+	g.Define("a", OrdChoice(nil, g.Ref("b"), And(nil, g.Ref("c"), Int()), Int()))
+	g.Define("b", And(nil, g.Ref("a"), Atom("+", "ADD")))
+	g.Define("c", And(nil, Maybe(nil, Atom("-", "NEG")), g.Ref("a")))
+	g.Define("d", And(nil, g.Ref("d"), Atom("*", "MUL")))
+	g.Define("e", And(nil, Atom("(", "OPEN"), g.Ref("e"), Atom(")", "CLOSE")))
+	ref := []string{"a", "b", "c", "d"}
+	if out := g.LeftRecursive(); !reflect.DeepEqual(out, ref) {
+		t.Errorf("expected %v, got %v", ref, out)
+	}
+}
+
+func TestAnalyzeGrammar(t *testing.T) {
+	g := NewGrammar("analyze")
+	g.Define("root", And(nil, g.Ref("used"), End()))
+	g.Define("used", Int())
+	g.Define("a", And(nil, Atom("a", "A"), g.Ref("b")))
+	g.Define("b", OrdChoice(nil, g.Ref("a"), g.Ref("used")))
+	g.Define("c", g.Ref("c"))
+	if _, err := g.Build("root"); err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+	issues := g.Analyze("root")
+	ref := []Issue{
+		{UnreachableRule, "a", "not reachable from root"},
+		{UnreachableRule, "b", "not reachable from root"},
+		{UnreachableRule, "c", "not reachable from root"},
+	}
+	if !reflect.DeepEqual(issues, ref) {
+		t.Errorf("expected %v, got %v", ref, issues)
+	}
+	issues = g.Analyze("c")
+	if len(issues) != 5 || issues[0] != (Issue{LeftRecursion, "c", "c -> c"}) {
+		t.Errorf("unexpected %v", issues)
+	}
+}
+
+func TestAnalyzeShadowed(t *testing.T) {
+	custom := func(s Scanner) (ParsecNode, Scanner) { return nil, s }
+	testcases := []struct {
+		y   Parser
+		ref []string
+	}{
+		{OrdChoice(nil, Atom("==", "EQ"), Atom("=", "ASSIGN")), nil},
+		{OrdChoice(nil, Atom("=", "ASSIGN"), Atom("==", "EQ")),
+			[]string{"alternative 2 of OrdChoice is shadowed by alternative 1"}},
+		{OrdChoice(nil, AtomExact("=", "ASSIGN"), Atom("==", "EQ")), nil},
+		{OrdChoice(nil, Atom("=", "ASSIGN"), AtomExact("==", "EQ")),
+			[]string{"alternative 2 of OrdChoice is shadowed by alternative 1"}},
+		{OrdChoice(nil, Ident(), Atom("if", "IF"), Int(), Ident()),
+			[]string{
+				"alternative 2 of OrdChoice is shadowed by alternative 1",
+				"alternative 4 of OrdChoice is shadowed by alternative 1",
+			}},
+		{NewAST("x", 10).OrdChoice("value", nil, Maybe(nil, Int()), Parser(custom)),
+			[]string{`alternative 2 of OrdChoice("value") is shadowed by alternative 1`}},
+		{OrdChoice(nil, Parser(custom), Parser(custom)), nil},
+		{LongestChoice(nil, Atom("=", "ASSIGN"), Atom("==", "EQ")), nil},
+	}
+	for i, tcase := range testcases {
+		var out []string
+		for _, issue := range Analyze(tcase.y) {
+			if issue.Kind != ShadowedChoice {
+				t.Errorf("%v unexpected %v", i, issue)
+			}
+			out = append(out, issue.Message)
+		}
+		if !reflect.DeepEqual(out, tcase.ref) {
+			t.Errorf("%v expected %v, got %v", i, tcase.ref, out)
+		}
+	}
+}
@@ -45,9 +45,10 @@ type Queryable interface {
 // combinators like And, OrdChoice, Many etc.. match input string, it is
 // possible to fail them via ASTNodify callback function, by returning nil.
 // This is useful in cases like:
-//  * where lookahead matching is required.
 //  * exceptional cases for a regex pattern.
 //
+// For lookahead matching use Lookahead and Not combinators.
+//
 // Note that some combinators like Kleene shall not interpret the return
 // value from ASTNodify callback. `node` will always be of NonTerminal
 // type, although callback can process it and return a different type,
@@ -56,12 +57,34 @@ type ASTNodify func(name string, s Scanner, node Queryable) Queryable
 
 // AST to parse and construct Abstract Syntax Tree whose nodes confirm
 // to `Queryable` interface, facilitating tree processing algorithms.
+//
+// Parsers composed with AST combinators, along with the AST settings,
+// form an immutable grammar once composed. Parse the grammar from any
+// number of goroutines using Parse, each parse using its own scanner,
+// every parse return its own Result. Parsewith, Reparse, Error and
+// other methods that refer to the last parse are not safe for
+// concurrent use. Settings, like SetDebug, SetPackrat and SetTracer,
+// shall be applied before parsing.
 type AST struct {
-	name   string
-	y      Parser
-	root   Queryable
-	ntpool chan *NonTerminal
-	debug  bool
+	name    string
+	last    *Result // from the last call to Parsewith, or Reparse.
+	ntpool  chan *NonTerminal
+	debug   bool
+	packrat int
+	// incremental parsing, refer to Reparse.
+	incremental bool
+	// tracing
+	tracer Tracer
+}
+
+// Result of a parse using AST, refer to AST.Parse.
+type Result struct {
+	ast  *AST
+	y    Parser
+	s    Scanner // scanner the parse started with, for Reparse.
+	news Scanner // scanner with remaining input.
+	root Queryable
+	err  error
 }
 
 // NewAST return a new instance of AST, maxnodes is size of internal buffer
@@ -78,17 +101,85 @@ func (ast *AST) SetDebug() *AST {
 	return ast
 }
 
+// SetPackrat enables memoization of parser results for every call to
+// Parsewith, refer to Packrat() for details. Use PackratStats() on the
+// scanner returned by Parsewith to learn the cache efficiency.
+func (ast *AST) SetPackrat(size int) *AST {
+	ast.packrat = size
+	return ast
+}
+
+// SetIncremental enables incremental parsing for every call to
+// Parsewith, so that its results can be reused by Reparse. Refer to
+// Incremental() for details.
+func (ast *AST) SetIncremental(size int) *AST {
+	ast.packrat, ast.incremental = size, true
+	return ast
+}
+
+// Parse execute the root parser, y, with scanner s and return the
+// Result. Parse is safe for concurrent use, provided every parse use
+// its own scanner.
+func (ast *AST) Parse(y Parser, s Scanner) *Result {
+	if ast.incremental {
+		Incremental(s, ast.packrat)
+	} else if ast.packrat > 0 {
+		Packrat(s, ast.packrat)
+	}
+	return ast.parse(y, s)
+}
+
 // Parsewith execute the root parser, y, with scanner s. AST will
 // remember the root parser, and root node. Return the root-node as
-// Queryable, if success and scanner with remaining input.
+// Queryable, if success and scanner with remaining input. If y fails
+// to match the input text, or the parse is aborted on hitting Limits,
+// use Error() to learn why. Use Parse for concurrent parsing.
 func (ast *AST) Parsewith(y Parser, s Scanner) (Queryable, Scanner) {
-	ast.root, ast.y = nil, y
+	ast.last = ast.Parse(y, s)
+	return ast.last.root, ast.last.news
+}
+
+// Reparse the input text from the last call to Parsewith, or Reparse,
+// after applying the edit, with the same root parser. Return the new
+// root-node and scanner, same as Parsewith. If incremental parsing is
+// enabled via SetIncremental, nodes from the last parse are reused where
+// the edit does not affect them, else the text is parsed afresh. Only
+// SimpleScanner supports edits.
+func (ast *AST) Reparse(edit Edit) (Queryable, Scanner) {
+	if ast.last == nil {
+		panic(fmt.Errorf("ast %q: nothing to reparse", ast.name))
+	}
+	ast.last = ast.last.Reparse(edit)
+	return ast.last.root, ast.last.news
+}
+
+func (ast *AST) parse(y Parser, s Scanner) (r *Result) {
+	r = &Result{ast: ast, y: y, s: s}
+	defer resetsession(s)()
+	defer func() {
+		if x := recover(); x != nil {
+			r.root, r.news, r.err = nil, s, recoverparse(x)
+		}
+	}()
 	node, news := y(s)
-	if node == nil {
-		return nil, news
+	if r.news = news; node == nil {
+		r.err = newParseError(news)
+		return r
 	}
-	ast.root = node.(Queryable)
-	return ast.root, news
+	r.root, r.err = node.(Queryable), diagnosticsof(news)
+	return r
+}
+
+// Error return the *ParseError from the last call to Parsewith, if the
+// root parser failed to match the input text, or the error for hitting
+// Limits, if the parse was aborted. If root parser succeeded
+// after recovering from errors, via Recover method, return Diagnostics.
+// Else return nil.
+func (ast *AST) Error() error {
+	if ast.last == nil {
+		return nil
+	}
+	return ast.last.err
 }
 
 // Reset the AST, forget the root parser, and root node. Reuse the AST object
@@ -104,45 +195,80 @@ func (ast *AST) Reset() *AST {
 		}
 		ast.putnt(node)
 	}
-	if node, ok := ast.root.(*NonTerminal); ok {
+	if node, ok := ast.lastroot().(*NonTerminal); ok {
 		freetree(node)
 	}
-	ast.y, ast.root = nil, nil
+	ast.last = nil
 	return ast
 }
 
+// Root return the root node, nil if the parse failed.
+func (r *Result) Root() Queryable {
+	return r.root
+}
+
+// Scanner return the scanner with remaining input, after the parse.
+func (r *Result) Scanner() Scanner {
+	return r.news
+}
+
+// Error return the error from the parse, same as AST.Error.
+func (r *Result) Error() error {
+	return r.err
+}
+
+// Reparse the input text of this parse after applying the edit, same
+// as AST.Reparse, and return the new Result.
+func (r *Result) Reparse(edit Edit) *Result {
+	s := edited(r.s, edit)
+	if !ismemoized(s) && r.ast.packrat > 0 {
+		Packrat(s, r.ast.packrat)
+	}
+	return r.ast.parse(r.y, s)
+}
+
 // And combinator, same as package level And combinator function.
 // `name` identifies the NonTerminal nodes constructed by this
 // combinator.
 func (ast *AST) And(name string, callb ASTNodify, parsers ...interface{}) Parser {
-	return func(s Scanner) (ParsecNode, Scanner) {
+	st := &structure{kind: "And", name: name, parsers: parsers}
+	return ast.memoize(st, func(s Scanner) (ParsecNode, Scanner) {
 		var node ParsecNode
 		var err error
+		var cut bool
 		nt, news := ast.getnt(name), s.Clone()
 		for i, parser := range parsers {
 			if node, news, err = ast.doParse(parser, news); err != nil {
 				fmsg := "while parsing %vth in %q: %v"
 				panic(fmt.Errorf(fmsg, i+1, name, err))
+			} else if node == nil && cut && abortable(news) {
+				ast.putnt(nt)
+				panic(newParseError(news))
 			} else if node == nil {
 				ast.putnt(nt)
 				return ast.trydebug(nil, s, "And", name, i+1, false)
 			}
 			ast.trydebug(node, news, "And", name, i+1, true)
-			nt.Children = append(nt.Children, node.(Queryable))
+			if _, ok := node.(cutnode); ok {
+				cut = true
+			} else if !isvoid(node) {
+				nt.Children = append(nt.Children, node.(Queryable))
+			}
 		}
 		if q := ast.docallback(name, callb, news, nt); q != nil {
 			return ast.trydebug(q, news, "And", name, -1, true)
 		}
 		ast.putnt(nt)
 		return ast.trydebug(nil, s, "And", name, -1, "skip")
-	}
+	})
 }
 
 // OrdChoice combinator, same as package level OrdChoice combinator
 // function. `nm` identifies the NonTerminal nodes constructed by this
 // combinator.
 func (ast *AST) OrdChoice(nm string, cb ASTNodify, ps ...interface{}) Parser {
-	return func(s Scanner) (ParsecNode, Scanner) {
+	st := &structure{kind: "OrdChoice", name: nm, parsers: ps}
+	return ast.memoize(st, func(s Scanner) (ParsecNode, Scanner) {
 		for i, parser := range ps {
 			news := s.Clone()
 			if n, news, err := ast.doParse(parser, news); err != nil {
@@ -157,7 +283,50 @@ func (ast *AST) OrdChoice(nm string, cb ASTNodify, ps ...interface{}) Parser {
 			}
 		}
 		return ast.trydebug(nil, s, "OrdChoice", nm, -1, false)
-	}
+	})
+}
+
+// LongestChoice combinator, same as package level LongestChoice
+// combinator. If more than one parser matched the longest input, ASTNodify
+// callback is called with NonTerminal `nm`, with nodes from those parsers
+// as its children, in the order of parsers. Callback can resolve the
+// ambiguity by returning one of the children. If callback is nil, node
+// from the first of those parsers is picked.
+func (ast *AST) LongestChoice(nm string, cb ASTNodify, ps ...interface{}) Parser {
+	doparse := ast.doparser(nm)
+	st := &structure{kind: "LongestChoice", name: nm, parsers: ps}
+	return ast.memoize(st, func(s Scanner) (ParsecNode, Scanner) {
+		ns, news := longest(doparse, ps, s)
+		switch {
+		case ns == nil:
+			return ast.trydebug(nil, s, "LongestChoice", nm, -1, false)
+		case len(ns) == 1 || cb == nil:
+			if q := ast.docallback(nm, cb, news, ns[0].(Queryable)); q != nil {
+				return ast.trydebug(q, news, "LongestChoice", nm, -1, true)
+			}
+		default:
+			if q := ast.builder(nm, cb)(news, ns); q != nil {
+				return ast.trydebug(q, news, "LongestChoice", nm, -1, true)
+			}
+		}
+		return ast.trydebug(nil, s, "LongestChoice", nm, -1, "skip")
+	})
+}
+
+// Permutation combinator, same as package level Permutation combinator.
+// `nm` identifies the NonTerminal constructed by this combinator, with
+// nodes from parsers as its children, in the order of parsers.
+func (ast *AST) Permutation(nm string, cb ASTNodify, ps ...interface{}) Parser {
+	doparse, build := ast.doparser(nm), ast.builder(nm, cb)
+	st := &structure{kind: "Permutation", name: nm, parsers: ps}
+	return ast.memoize(st, func(s Scanner) (ParsecNode, Scanner) {
+		if ns, news := permute(doparse, ps, s); ns != nil {
+			if q := build(news, ns); q != nil {
+				return ast.trydebug(q, news, "Permutation", nm, -1, true)
+			}
+		}
+		return ast.trydebug(nil, s, "Permutation", nm, -1, false)
+	})
 }
 
 // Kleene combinator, same as package level Kleene combinator
@@ -175,11 +344,13 @@ func (ast *AST) Kleene(nm string, callb ASTNodify, ps ...interface{}) Parser {
 		panic(fmt.Errorf(fmsg, nm, l))
 	}
 
-	return func(s Scanner) (ParsecNode, Scanner) {
+	st := &structure{kind: "Kleene", name: nm, parsers: nonnil(opScan, sepScan)}
+	return ast.memoize(st, func(s Scanner) (ParsecNode, Scanner) {
 		var node ParsecNode
 		var err error
 		nt, news := ast.getnt(nm), s.Clone()
 		for {
+			from := news.GetCursor()
 			if node, news, err = ast.doParse(opScan, news); err != nil {
 				panic(fmt.Errorf("while opscan-parsing %q: %v", nm, err))
 			} else if node == nil {
@@ -193,9 +364,12 @@ func (ast *AST) Kleene(nm string, callb ASTNodify, ps ...interface{}) Parser {
 					break
 				}
 			}
+			if news.GetCursor() == from {
+				NoProgress(news)
+			}
 		}
 		return ast.docallback(nm, callb, news, nt), news
-	}
+	})
 }
 
 // Many combinator, same as package level Many combinator
@@ -213,11 +387,13 @@ func (ast *AST) Many(nm string, callb ASTNodify, parsers ...interface{}) Parser
 		panic(fmt.Errorf(fmsg, nm, l))
 	}
 
-	return func(s Scanner) (ParsecNode, Scanner) {
+	st := &structure{kind: "Many", name: nm, parsers: nonnil(opScan, sepScan)}
+	return ast.memoize(st, func(s Scanner) (ParsecNode, Scanner) {
 		var node ParsecNode
 		var err error
 		nt, news := ast.getnt(nm), s.Clone()
 		for {
+			from := news.GetCursor()
 			if node, news, err = ast.doParse(opScan, news); err != nil {
 				panic(fmt.Errorf("while opscan-parsing %q: %v", nm, err))
 			} else if node == nil {
@@ -231,6 +407,9 @@ func (ast *AST) Many(nm string, callb ASTNodify, parsers ...interface{}) Parser
 					break
 				}
 			}
+			if news.GetCursor() == from {
+				NoProgress(news)
+			}
 		}
 		if len(nt.Children) > 0 {
 			if q := ast.docallback(nm, callb, news, nt); q != nil {
@@ -239,7 +418,7 @@ func (ast *AST) Many(nm string, callb ASTNodify, parsers ...interface{}) Parser
 		}
 		ast.putnt(nt)
 		return nil, s
-	}
+	})
 }
 
 // ManyUntil combinator, same as package level Many combinator function.
@@ -256,7 +435,10 @@ func (ast *AST) ManyUntil(nm string, callb ASTNodify, ps ...interface{}) Parser
 		panic(fmt.Errorf(fmsg, nm, l))
 	}
 
-	return func(s Scanner) (ParsecNode, Scanner) {
+	st := &structure{
+		kind: "ManyUntil", name: nm, parsers: nonnil(untilScan, opScan, sepScan),
+	}
+	return ast.memoize(st, func(s Scanner) (ParsecNode, Scanner) {
 		var node ParsecNode
 		var err error
 		nt, news := ast.getnt(nm), s.Clone()
@@ -266,6 +448,7 @@ func (ast *AST) ManyUntil(nm string, callb ASTNodify, ps ...interface{}) Parser
 			} else if node != nil {
 				break
 			}
+			from := news.GetCursor()
 			if node, news, err = ast.doParse(opScan, news); err != nil {
 				panic(fmt.Errorf("while opscan-parsing %q: %v", nm, err))
 			} else if node == nil {
@@ -279,6 +462,9 @@ func (ast *AST) ManyUntil(nm string, callb ASTNodify, ps ...interface{}) Parser
 					break
 				}
 			}
+			if news.GetCursor() == from {
+				NoProgress(news)
+			}
 		}
 		if len(nt.Children) > 0 {
 			if q := ast.docallback(nm, callb, news, nt); q != nil {
@@ -287,13 +473,14 @@ func (ast *AST) ManyUntil(nm string, callb ASTNodify, ps ...interface{}) Parser
 		}
 		ast.putnt(nt)
 		return nil, s
-	}
+	})
 }
 
 // Maybe combinator, same as package level Maybe combinator function.
 // `nm` identifies the NonTerminal nodes constructed by this combinator.
 func (ast *AST) Maybe(name string, callb ASTNodify, parser interface{}) Parser {
-	return func(s Scanner) (ParsecNode, Scanner) {
+	st := &structure{kind: "Maybe", name: name, parsers: []interface{}{parser}}
+	return ast.memoize(st, func(s Scanner) (ParsecNode, Scanner) {
 		node, news, err := ast.doParse(parser, s.Clone())
 		if err != nil {
 			panic(fmt.Errorf("while parsing %q: %v", name, err))
@@ -304,31 +491,227 @@ func (ast *AST) Maybe(name string, callb ASTNodify, parser interface{}) Parser {
 			return q, news
 		}
 		return MaybeNone("missing"), s
+	})
+}
+
+// Repeat combinator, same as package level Repeat combinator. `name`
+// identifies the NonTerminal constructed by this combinator.
+func (ast *AST) Repeat(
+	name string, callb ASTNodify, min, max int, parsers ...interface{}) Parser {
+
+	opScan, sepScan := repeatargs(min, max, parsers)
+	doparse, build := ast.doparser(name), ast.builder(name, callb)
+	st := &structure{
+		kind: "Repeat", name: name, parsers: nonnil(opScan, sepScan),
+		min: min, max: max,
 	}
+	return ast.memoize(st, func(s Scanner) (ParsecNode, Scanner) {
+		ns, news := repeat(doparse, min, max, opScan, sepScan, s)
+		if ns != nil {
+			if node := build(news, ns); node != nil {
+				return ast.trydebug(node, news, "Repeat", name, -1, true)
+			}
+		}
+		return ast.trydebug(nil, s, "Repeat", name, -1, false)
+	})
+}
+
+// Times combinator, same as package level Times combinator. `name`
+// identifies the NonTerminal constructed by this combinator.
+func (ast *AST) Times(
+	name string, callb ASTNodify, n int, parsers ...interface{}) Parser {
+
+	return ast.Repeat(name, callb, n, n, parsers...)
+}
+
+// AtLeast combinator, same as package level AtLeast combinator. `name`
+// identifies the NonTerminal constructed by this combinator.
+func (ast *AST) AtLeast(
+	name string, callb ASTNodify, n int, parsers ...interface{}) Parser {
+
+	return ast.Repeat(name, callb, n, -1, parsers...)
+}
+
+// AtMost combinator, same as package level AtMost combinator. `name`
+// identifies the NonTerminal constructed by this combinator.
+func (ast *AST) AtMost(
+	name string, callb ASTNodify, n int, parsers ...interface{}) Parser {
+
+	return ast.Repeat(name, callb, 0, n, parsers...)
+}
+
+// SepBy combinator, same as package level SepBy combinator. `name`
+// identifies the NonTerminal constructed by this combinator, with
+// nodes matched by parser as its children.
+func (ast *AST) SepBy(
+	name string, callb ASTNodify, parser, sep interface{}) Parser {
+
+	return ast.separated("SepBy", name, callb, parser, sep, trailNone)
+}
+
+// SepEndBy combinator, same as package level SepEndBy combinator.
+// `name` identifies the NonTerminal constructed by this combinator,
+// with nodes matched by parser as its children.
+func (ast *AST) SepEndBy(
+	name string, callb ASTNodify, parser, sep interface{}) Parser {
+
+	return ast.separated("SepEndBy", name, callb, parser, sep, trailOptional)
+}
+
+// EndBy combinator, same as package level EndBy combinator. `name`
+// identifies the NonTerminal constructed by this combinator, with
+// nodes matched by parser as its children.
+func (ast *AST) EndBy(
+	name string, callb ASTNodify, parser, sep interface{}) Parser {
+
+	return ast.separated("EndBy", name, callb, parser, sep, trailMandatory)
+}
+
+// Between combinator, same as package level Between combinator. `name`
+// identifies the NonTerminal constructed by this combinator, with node
+// matched by parser as its only child.
+func (ast *AST) Between(
+	name string, callb ASTNodify, open, close, parser interface{}) Parser {
+
+	doparse, build := ast.doparser(name), ast.builder(name, callb)
+	st := &structure{
+		kind: "Between", name: name, parsers: []interface{}{open, parser, close},
+	}
+	return ast.memoize(st, func(s Scanner) (ParsecNode, Scanner) {
+		if n, news := between(doparse, open, close, parser, s); n != nil {
+			if node := build(news, []ParsecNode{n}); node != nil {
+				return ast.trydebug(node, news, "Between", name, -1, true)
+			}
+		}
+		return ast.trydebug(nil, s, "Between", name, -1, false)
+	})
+}
+
+// Chainl1 combinator, same as package level Chainl1 combinator. `name`
+// identifies the NonTerminals constructed by this combinator, one for
+// each op, with left node, op node and right node as its children.
+func (ast *AST) Chainl1(
+	name string, callb ASTNodify, operand, op interface{}) Parser {
+
+	return ast.chain("Chainl1", name, callb, operand, op, false)
+}
+
+// Chainr1 combinator, same as package level Chainr1 combinator. `name`
+// identifies the NonTerminals constructed by this combinator, one for
+// each op, with left node, op node and right node as its children.
+func (ast *AST) Chainr1(
+	name string, callb ASTNodify, operand, op interface{}) Parser {
+
+	return ast.chain("Chainr1", name, callb, operand, op, true)
+}
+
+// Skip combinator, same as package level Skip combinator. And
+// combinator shall not include the node matched by Skip as its child.
+func (ast *AST) Skip(name string, parser interface{}) Parser {
+	doparse := ast.doparser(name)
+	st := &structure{kind: "Skip", name: name, parsers: []interface{}{parser}}
+	return ast.traced(st, limited(func(s Scanner) (ParsecNode, Scanner) {
+		if n, news := doparse(parser, s.Clone()); n != nil {
+			return ast.trydebug(voidnode{}, news, "Skip", name, -1, true)
+		}
+		return ast.trydebug(nil, s, "Skip", name, -1, false)
+	}))
+}
+
+// Lookahead combinator, same as package level Lookahead combinator
+// function. `name` identifies the lookahead while debugging.
+func (ast *AST) Lookahead(name string, parser interface{}) Parser {
+	st := &structure{kind: "Lookahead", name: name, parsers: []interface{}{parser}}
+	return ast.traced(st, limited(func(s Scanner) (ParsecNode, Scanner) {
+		node := predicate(s, func(s Scanner) ParsecNode {
+			node, _, err := ast.doParse(parser, s)
+			if err != nil {
+				panic(fmt.Errorf("while parsing %q: %v", name, err))
+			}
+			return node
+		})
+		if node != nil {
+			return ast.trydebug(voidnode{}, s, "Lookahead", name, -1, true)
+		}
+		return ast.trydebug(nil, s, "Lookahead", name, -1, false)
+	}))
+}
+
+// Not combinator, same as package level NotFollowedBy combinator
+// function. `name` identifies the predicate while debugging.
+func (ast *AST) Not(name string, parser interface{}) Parser {
+	st := &structure{kind: "Not", name: name, parsers: []interface{}{parser}}
+	return ast.traced(st, limited(func(s Scanner) (ParsecNode, Scanner) {
+		ok := notfollowedby(s, func(s Scanner) ParsecNode {
+			node, _, err := ast.doParse(parser, s)
+			if err != nil {
+				panic(fmt.Errorf("while parsing %q: %v", name, err))
+			}
+			return node
+		})
+		if ok {
+			return ast.trydebug(voidnode{}, s, "Not", name, -1, true)
+		}
+		return ast.trydebug(nil, s, "Not", name, -1, false)
+	}))
+}
+
+// Cut is same as package level Cut combinator, once passed, subsequent
+// failure within the enclosing And combinator shall abort Parsewith.
+// Refer to Error method for the reason.
+func (ast *AST) Cut() Parser {
+	return Cut()
+}
+
+// Commit is same as package level Commit combinator.
+func (ast *AST) Commit() Parser {
+	return Commit()
+}
+
+// Recover combinator, same as package level Recover combinator.
+// `name` identifies the Terminal node constructed from skipped text.
+func (ast *AST) Recover(name string, parser, sync interface{}) Parser {
+	doparse := ast.doparser(name)
+	st := &structure{
+		kind: "Recover", name: name, parsers: []interface{}{parser, sync},
+	}
+	return ast.memoize(st, func(s Scanner) (ParsecNode, Scanner) {
+		node, news := recoverwith(s, name,
+			func(s Scanner) (ParsecNode, Scanner) { return doparse(parser, s) },
+			func(s Scanner) (ParsecNode, Scanner) { return doparse(sync, s) },
+		)
+		if node == nil {
+			return ast.trydebug(nil, s, "Recover", name, -1, false)
+		}
+		return ast.trydebug(node, news, "Recover", name, -1, true)
+	})
 }
 
 // End is a parser function to detect end of scanner output.
 func (ast *AST) End(name string) Parser {
-	return func(s Scanner) (ParsecNode, Scanner) {
+	st := &structure{kind: "End", name: name}
+	return record(st, func(s Scanner) (ParsecNode, Scanner) {
 		if s.Endof() {
 			return NewTerminal(name, "", s.GetCursor()), s
 		}
+		expect(s, s.GetCursor(), name)
 		return nil, s
-	}
+	})
 }
 
 // GetValue return the full text, called as value here, that was parsed
 // to contruct this syntax-tree.
 func (ast *AST) GetValue() string {
-	return ast.root.GetValue()
+	return ast.lastroot().GetValue()
 }
 
 // Prettyprint to standard output the syntax-tree in human readable plain text.
 func (ast *AST) Prettyprint() {
-	if ast.root == nil {
+	root := ast.lastroot()
+	if root == nil {
 		fmt.Println("root is nil")
 	}
-	ast.prettyprint(os.Stdout, "", ast.root)
+	ast.prettyprint(os.Stdout, "", root)
 }
 
 // Dotstring return AST in graphviz dot format. Save this string to a
@@ -340,34 +723,129 @@ func (ast *AST) Dotstring(name string) string {
 // Query is an experimental method on AST. Developers can use the
 // selector specification to pick one or more nodes from the AST.
 func (ast *AST) Query(selectors string, ch chan Queryable) {
+	Query(ast.lastroot(), selectors, ch)
+}
+
+// Query is same as AST.Query, on the tree rooted at `root`, like the
+// trees constructed by parsers generated using peg.Grammar.Generate.
+func Query(root Queryable, selectors string, ch chan Queryable) {
 	selast := NewAST("selectorast", 100)
 	y := parseselector(selast)
 	qsel, _ := selast.Parsewith(y, NewScanner([]byte(selectors)))
 	orsels := qsel.GetChildren()
 	for _, orsel := range orsels {
 		qs := orsel.GetChildren()
-		astwalk(nil, 0, ast.root, qs, ch)
+		astwalk(nil, 0, root, qs, ch)
 	}
 	close(ch)
 }
 
+// Rename return a copy of node named `name`, if node is a Terminal or
+// a NonTerminal, else return node as is. Attributes are copied and
+// children are shared, hence nodes shared by packrat cache can be
+// renamed, say by ASTNodify callbacks, without modifying them.
+func Rename(name string, node Queryable) Queryable {
+	switch n := node.(type) {
+	case *Terminal:
+		t := *n
+		t.Name, t.Attributes = name, copyattrs(n.Attributes)
+		return &t
+	case *NonTerminal:
+		nt := *n
+		nt.Name, nt.Attributes = name, copyattrs(n.Attributes)
+		return &nt
+	}
+	return node
+}
+
 //---- local functions
 
+// lastroot return the root node from the last parse, if any.
+func (ast *AST) lastroot() Queryable {
+	if ast.last == nil {
+		return nil
+	}
+	return ast.last.root
+}
+
 func (ast *AST) doParse(
 	parser interface{}, s Scanner) (ParsecNode, Scanner, error) {
 
-	switch p := parser.(type) {
-	case Parser:
-		node, news := p(s)
-		return node, news, nil
-	case *Parser:
-		node, news := (*p)(s)
+	switch parser.(type) {
+	case Parser, *Parser:
+		node, news := doParse(parser, s)
 		return node, news, nil
 	default:
 		return nil, s, errors.New("badtype")
 	}
 }
 
+// doparser return a function to parse sub-parsers of combinator `name`.
+func (ast *AST) doparser(
+	name string) func(interface{}, Scanner) (ParsecNode, Scanner) {
+
+	return func(parser interface{}, s Scanner) (ParsecNode, Scanner) {
+		node, news, err := ast.doParse(parser, s)
+		if err != nil {
+			panic(fmt.Errorf("while parsing %q: %v", name, err))
+		}
+		return node, news
+	}
+}
+
+// builder return a function to construct NonTerminal `name` with nodes
+// as its children.
+func (ast *AST) builder(
+	name string, callb ASTNodify) func(Scanner, []ParsecNode) ParsecNode {
+
+	return func(s Scanner, ns []ParsecNode) ParsecNode {
+		nt := ast.getnt(name)
+		for _, n := range ns {
+			nt.Children = append(nt.Children, n.(Queryable))
+		}
+		if q := ast.docallback(name, callb, s, nt); q != nil {
+			return q
+		}
+		ast.putnt(nt)
+		return nil
+	}
+}
+
+func (ast *AST) separated(
+	ytype, name string, callb ASTNodify,
+	parser, sep interface{}, trail int) Parser {
+
+	doparse, build := ast.doparser(name), ast.builder(name, callb)
+	st := &structure{kind: ytype, name: name, parsers: []interface{}{parser, sep}}
+	return ast.memoize(st, func(s Scanner) (ParsecNode, Scanner) {
+		ns, news := separated(doparse, parser, sep, trail, s)
+		if node := build(news, ns); node != nil {
+			return ast.trydebug(node, news, ytype, name, -1, true)
+		}
+		return ast.trydebug(nil, s, ytype, name, -1, false)
+	})
+}
+
+func (ast *AST) chain(
+	ytype, name string, callb ASTNodify,
+	operand, op interface{}, right bool) Parser {
+
+	doparse, build := ast.doparser(name), ast.builder(name, callb)
+	st := &structure{kind: ytype, name: name, parsers: []interface{}{operand, op}}
+	return ast.memoize(st, func(s Scanner) (ParsecNode, Scanner) {
+		if node, news := chain(doparse, build, operand, op, right, s); node != nil {
+			return ast.trydebug(node, news, ytype, name, -1, true)
+		}
+		return ast.trydebug(nil, s, ytype, name, -1, false)
+	})
+}
+
+// memoize is same as package level memoize, and trace the combinator
+// if tracer is set.
+func (ast *AST) memoize(st *structure, parser Parser) Parser {
+	return ast.traced(st, memoize(st, parser))
+}
+
 func (ast *AST) docallback(
 	name string, callb ASTNodify, s Scanner, node Queryable) Queryable {
 
@@ -375,7 +853,7 @@ func (ast *AST) docallback(
 		q := callb(name, s, node)
 		if q == nil {
 			return nil
-		} else if _, ok := q.(*NonTerminal); !ok {
+		} else if _, ok := q.(*NonTerminal); !ok && !ismemoized(s) {
 			if nt, ok := node.(*NonTerminal); ok {
 				ast.putnt(nt)
 			}
@@ -437,7 +915,7 @@ func (ast *AST) dottext(name string) string {
 	}
 	nodesi, nodesk := make(tnode), make(tnode)
 	edges, nodesi, nodesk, _ :=
-		ast.dotline(0, 1, ast.root, []string{}, nodesi, nodesk)
+		ast.dotline(0, 1, ast.lastroot(), []string{}, nodesi, nodesk)
 	lines = append(lines, edges...)
 	for _, node := range sortnodes(nodesi) {
 		label := nodesi[node]
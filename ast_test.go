@@ -3,6 +3,8 @@ package parsec
 import "fmt"
 import "bytes"
 import "testing"
+import "reflect"
+import "sync"
 import "io/ioutil"
 
 var _ = fmt.Sprintf("dummy")
@@ -12,7 +14,7 @@ func TestASTReset(t *testing.T) {
 	y := ast.And("and", nil, Atom("hello", "TERM"))
 	s := NewScanner([]byte("hello"))
 	node, _ := ast.Parsewith(y, s)
-	if x, y := ast.root.GetValue(), node.GetValue(); x != y {
+	if x, y := ast.lastroot().GetValue(), node.GetValue(); x != y {
 		t.Errorf("expected %v, got %v", x, y)
 	}
 	if len(ast.ntpool) != 0 {
@@ -402,7 +404,7 @@ func TestPrettyprint(t *testing.T) {
 	s := NewScanner(data).TrackLineno()
 	ast.Parsewith(y, s)
 	buf := bytes.NewBuffer(make([]byte, 0, 1024))
-	ast.prettyprint(buf, "", ast.root)
+	ast.prettyprint(buf, "", ast.lastroot())
 	out := string(buf.Bytes())
 	if string(ref) != out {
 		t.Errorf("expected %v", ref)
@@ -456,3 +458,314 @@ func makeexacthtmly(ast *AST) Parser {
 	tag = ast.And("tag", nil, tstart, elements, tend)
 	return tag
 }
+
+func TestASTLookahead(t *testing.T) {
+	ast := NewAST("testlookahead", 100)
+	keyword := ast.And("keyword", nil,
+		Atom("if", "IF"), ast.Not("boundary", TokenExact(`\w`, "W")),
+	)
+	ident := ast.And("ident", nil, ast.Not("reserved", keyword), Ident())
+	call := ast.And("call", nil, ident, ast.Lookahead("args", Atom("(", "OPEN")))
+	y := ast.OrdChoice("stmt", nil, call, ident)
+
+	node, s := ast.Parsewith(y, NewScanner([]byte("iffy(")))
+	if node == nil {
+		t.Fatalf("expected node")
+	} else if node.GetName() != "call" {
+		t.Errorf("expected %v, got %v", "call", node.GetName())
+	} else if cs := node.GetChildren(); len(cs) != 1 {
+		t.Errorf("unexpected %v", cs)
+	} else if s.GetCursor() != 4 {
+		t.Errorf("expected %v, got %v", 4, s.GetCursor())
+	}
+	ast.Reset()
+
+	node, _ = ast.Parsewith(y, NewScanner([]byte("iffy")))
+	if node.GetName() != "ident" {
+		t.Errorf("expected %v, got %v", "ident", node.GetName())
+	} else if cs := node.GetChildren(); len(cs) != 1 {
+		t.Errorf("unexpected %v", cs)
+	}
+	ast.Reset()
+
+	node, _ = ast.Parsewith(y, NewScanner([]byte("if")))
+	if node != nil {
+		t.Errorf("unexpected %v", node)
+	}
+
+	// panic case
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("expected panic")
+			}
+		}()
+		ast.Not("reserved", 10)(NewScanner([]byte("if")))
+	}()
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("expected panic")
+			}
+		}()
+		ast.Lookahead("args", 10)(NewScanner([]byte("if")))
+	}()
+}
+
+func TestASTCut(t *testing.T) {
+	ast := NewAST("testcut", 100)
+	expr := ast.OrdChoice("expr", nil, Int(), Ident())
+	ifstmt := ast.And("ifstmt", nil,
+		Atom("if", "IF"), ast.Cut(), expr, Atom(";", "SEMI"))
+	y := ast.OrdChoice("stmt", nil, ifstmt, ast.And("exprstmt", nil, expr))
+
+	node, _ := ast.Parsewith(y, NewScanner([]byte("if 10;")))
+	if node == nil {
+		t.Errorf("unexpected %v", ast.Error())
+	} else if x := len(node.GetChildren()); x != 3 {
+		t.Errorf("expected %v, got %v", 3, x)
+	}
+
+	node, s := ast.Parsewith(y, NewScanner([]byte("if ;")))
+	if node != nil {
+		t.Errorf("unexpected %v", node)
+	} else if s.GetCursor() != 0 {
+		t.Errorf("expected %v, got %v", 0, s.GetCursor())
+	}
+	perr, ok := ast.Error().(*ParseError)
+	if !ok {
+		t.Fatalf("unexpected %v", ast.Error())
+	} else if perr.Cursor != 3 {
+		t.Errorf("expected %v, got %v", 3, perr.Cursor)
+	} else if ref := []string{"IDENT", "INT"}; !reflect.DeepEqual(perr.Expected, ref) {
+		t.Errorf("expected %v, got %v", ref, perr.Expected)
+	}
+
+	// invoked directly, failure after cut fails the sequence.
+	if node, s := ifstmt(NewScanner([]byte("if ;"))); node != nil {
+		t.Errorf("unexpected %v", node)
+	} else if s.GetCursor() != 0 {
+		t.Errorf("expected %v, got %v", 0, s.GetCursor())
+	}
+}
+
+func TestASTRecover(t *testing.T) {
+	ast := NewAST("testrecover", 100)
+	semi := Atom(";", "SEMI")
+	stmt := ast.And("stmt", nil, Ident(), Atom("=", "EQUAL"), Int(), semi)
+	stmts := ast.Kleene("stmts", nil, ast.Recover("badstmt", stmt, semi))
+	y := ast.And("program", nil, stmts, ast.End("EOF"))
+
+	node, _ := ast.Parsewith(y, NewScanner([]byte("a = 1; b = c; d = 4")))
+	if node == nil {
+		t.Fatalf("unexpected %v", ast.Error())
+	}
+	names := []string{}
+	for _, child := range node.GetChildren()[0].GetChildren() {
+		names = append(names, child.GetName())
+	}
+	if ref := []string{"stmt", "badstmt", "badstmt"}; !reflect.DeepEqual(names, ref) {
+		t.Errorf("expected %v, got %v", ref, names)
+	}
+	diags, ok := ast.Error().(Diagnostics)
+	if !ok || len(diags) != 2 {
+		t.Fatalf("unexpected %v", ast.Error())
+	} else if diags[0].Cursor != 11 {
+		t.Errorf("expected %v, got %v", 11, diags[0].Cursor)
+	} else if diags[1].Cursor != 19 {
+		t.Errorf("expected %v, got %v", 19, diags[1].Cursor)
+	}
+
+	// diagnostics from predicates are discarded.
+	ast.Reset()
+	y = ast.And("program", nil,
+		ast.Lookahead("stmts", stmts), ast.Not("empty", ast.End("EOF")), stmts)
+	ast.Parsewith(y, NewScanner([]byte("a = 1; b = c; d = 4;")))
+	if diags, ok := ast.Error().(Diagnostics); !ok || len(diags) != 1 {
+		t.Errorf("unexpected %v", ast.Error())
+	}
+
+	// parse without errors.
+	ast.Reset()
+	if node, _ = ast.Parsewith(y, NewScanner([]byte("a = 1;"))); node == nil {
+		t.Errorf("expected node")
+	} else if ast.Error() != nil {
+		t.Errorf("unexpected %v", ast.Error())
+	}
+}
+
+func TestASTDerived(t *testing.T) {
+	ast := NewAST("testderived", 100)
+	comma, semi := Atom(",", "COMMA"), Atom(";", "SEMI")
+	list := ast.Between("list", nil,
+		Atom("[", "OPEN"), Atom("]", "CLOSE"),
+		ast.SepEndBy("items", nil, Int(), comma))
+	expr := ast.Chainl1("sub", nil,
+		ast.OrdChoice("operand", nil, list, Int()), Atom("-", "SUB"))
+	pow := ast.Chainr1("pow", nil, Int(), Atom("^", "POW"))
+	stmt := ast.And("stmt", nil,
+		ast.Skip("let", Atom("let", "LET")), ast.OrdChoice("value", nil, pow, expr))
+	y := ast.And("program", nil,
+		ast.EndBy("stmts", nil, stmt, semi), ast.SepBy("names", nil, Ident(), comma),
+		ast.End("EOF"))
+
+	text := "let [1, 2,] - 3 - [] ; let 2 ^ 3 ^ 4; a, b"
+	node, _ := ast.Parsewith(y, NewScanner([]byte(text)))
+	if node == nil {
+		t.Fatalf("unexpected %v", ast.Error())
+	}
+	ref := "program(stmts(stmt(sub(sub(list(items(INT INT)) SUB INT) " +
+		"SUB list(items()))) stmt(pow(INT POW pow(INT POW INT)))) " +
+		"names(IDENT IDENT) EOF)"
+	if out := sexpr(node); out != ref {
+		t.Errorf("expected %v", ref)
+		t.Errorf("got %v", out)
+	}
+}
+
+func TestASTRepeat(t *testing.T) {
+	ast := NewAST("testrepeat", 100)
+	digit := Token(`[0-9]`, "DIGIT")
+	date := ast.And("date", nil,
+		ast.Times("year", nil, 4, digit), Atom("-", "DASH"),
+		ast.Repeat("month", nil, 1, 2, digit), Atom("-", "DASH"),
+		ast.AtMost("day", nil, 2, digit), ast.AtLeast("rest", nil, 0, digit))
+
+	node, _ := ast.Parsewith(date, NewScanner([]byte("2024-1-30")))
+	if node == nil {
+		t.Fatalf("unexpected %v", ast.Error())
+	}
+	ref := "date(year(DIGIT DIGIT DIGIT DIGIT) DASH month(DIGIT) DASH " +
+		"day(DIGIT DIGIT) rest())"
+	if out := sexpr(node); out != ref {
+		t.Errorf("expected %v", ref)
+		t.Errorf("got %v", out)
+	}
+	if node, _ = ast.Parsewith(date, NewScanner([]byte("202-1-30"))); node != nil {
+		t.Errorf("unexpected %v", node)
+	}
+}
+
+func TestASTLongestChoice(t *testing.T) {
+	ast := NewAST("testlongest", 100)
+	ident := Ident()
+	keyword := Atom("if", "IF")
+	y := ast.LongestChoice("word", nil, keyword, ident, Int())
+
+	testcases := [][2]string{{"if", "IF"}, {"iffy", "IDENT"}, {"10", "INT"}}
+	for _, tcase := range testcases {
+		node, _ := ast.Parsewith(y, NewScanner([]byte(tcase[0])))
+		if node.GetName() != tcase[1] {
+			t.Errorf("expected %v, got %v", tcase[1], node.GetName())
+		}
+	}
+
+	// ambiguity
+	var names string
+	pick := func(name string, s Scanner, node Queryable) Queryable {
+		if node.GetName() == "word" {
+			names = sexpr(node)
+			return node.GetChildren()[1]
+		}
+		return node
+	}
+	y = ast.LongestChoice("word", pick, keyword, ident, Int())
+	node, _ := ast.Parsewith(y, NewScanner([]byte("if")))
+	if node.GetName() != "IDENT" {
+		t.Errorf("expected %v, got %v", "IDENT", node.GetName())
+	} else if names != "word(IF IDENT)" {
+		t.Errorf("expected %v, got %v", "word(IF IDENT)", names)
+	}
+	if node, _ = ast.Parsewith(y, NewScanner([]byte("10"))); node.GetName() != "INT" {
+		t.Errorf("expected %v, got %v", "INT", node.GetName())
+	}
+}
+
+func TestASTPermutation(t *testing.T) {
+	ast := NewAST("testpermutation", 100)
+	attr := func(name string) Parser {
+		return ast.And(name, nil, Atom(name, "NAME"), Atom("=", "EQUAL"), Token(`"[^"]*"`, "STRING"))
+	}
+	y := ast.Permutation("attrs", nil,
+		attr("id"), ast.Maybe("class", nil, attr("class")), attr("href"))
+
+	node, _ := ast.Parsewith(y, NewScanner([]byte(`href="/" id="x"`)))
+	if node == nil {
+		t.Fatalf("unexpected %v", ast.Error())
+	}
+	names := []string{}
+	for _, child := range node.GetChildren() {
+		names = append(names, child.GetName())
+	}
+	if ref := []string{"id", "missing", "href"}; !reflect.DeepEqual(names, ref) {
+		t.Errorf("expected %v, got %v", ref, names)
+	}
+}
+
+func TestASTParse(t *testing.T) {
+	ast := NewAST("testparse", 100).SetIncremental(100)
+	list := ast.Between("list", nil, Atom("[", "OPEN"), Atom("]", "CLOSE"),
+		ast.SepBy("items", nil, Int(), Atom(",", "COMMA")))
+	y := ast.And("program", nil, list, ast.End("EOF"))
+
+	r := ast.Parse(y, NewScanner([]byte("[1, 2]")))
+	if r.Error() != nil {
+		t.Fatalf("unexpected %v", r.Error())
+	} else if ref, out := "program(list(items(INT INT)) EOF)", sexpr(r.Root()); out != ref {
+		t.Errorf("expected %v, got %v", ref, out)
+	} else if ref, out := 6, r.Scanner().GetCursor(); out != ref {
+		t.Errorf("expected %v, got %v", ref, out)
+	}
+	if ast.Error() != nil || ast.lastroot() != nil {
+		t.Errorf("unexpected last parse %v", ast.lastroot())
+	}
+
+	r2 := r.Reparse(Edit{Offset: 5, Inserted: []byte(", 3")})
+	if ref, out := "program(list(items(INT INT INT)) EOF)", sexpr(r2.Root()); out != ref {
+		t.Errorf("expected %v, got %v", ref, out)
+	} else if ref, out := "program(list(items(INT INT)) EOF)", sexpr(r.Root()); out != ref {
+		t.Errorf("expected %v, got %v", ref, out)
+	}
+	if r3 := r2.Reparse(Edit{Offset: 0, Deleted: 1}); r3.Root() != nil {
+		t.Errorf("unexpected %v", r3.Root())
+	} else if _, ok := r3.Error().(*ParseError); !ok {
+		t.Errorf("expected ParseError, got %v", r3.Error())
+	}
+}
+
+func TestASTParallel(t *testing.T) {
+	var value Parser
+	ast := NewAST("testparallel", 100).SetPackrat(100)
+	list := ast.Between("list", nil, Atom("[", "OPEN"), Atom("]", "CLOSE"),
+		ast.SepBy("items", nil, &value, Atom(",", "COMMA")))
+	value = ast.OrdChoice("value", nil, Int(), Ident(), list)
+	y := ast.And("program", nil, list, ast.End("EOF"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				text := fmt.Sprintf("[%v, [a%v, [%v]], b]", i, j, i*j)
+				s := NewScanner([]byte(text)).SetWSPattern(fmt.Sprintf(`^[ \t]{1,%v}`, j+1))
+				r := ast.Parse(y, s)
+				if r.Error() != nil {
+					t.Errorf("unexpected %v", r.Error())
+					return
+				}
+				ref := "program(list(items(INT list(items(IDENT list(items(INT)))) IDENT)) EOF)"
+				if out := sexpr(r.Root()); out != ref {
+					t.Errorf("expected %v, got %v", ref, out)
+					return
+				}
+				s = NewScanner([]byte(text[:len(text)-1]))
+				if r = ast.Parse(y, s); r.Root() != nil || r.Error() == nil {
+					t.Errorf("expected error for %q", text[:len(text)-1])
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
@@ -18,6 +18,10 @@ text:
 	var exprText = []byte(`4 + 123 + 23 + 67 +89 + 87 *78`)
 	s := parsec.NewScanner(exprText)
 
+To parse large inputs, like log files or network streams, without
+reading them into memory, use StreamScanner over an io.Reader:
+	s := parsec.NewStreamScanner(file)
+
 Nodify, callback function is supplied while combining parser
 functions. If the underlying parsing logic matches with i/p text,
 then callback will be dispatched with list of matching ParsecNode.
@@ -51,10 +55,22 @@ parsers. They are,
 
  * And, to combine a sequence of terminals and non-terminal parsers.
  * OrdChoice, to choose between specified list of parsers.
+ * LongestChoice, to choose the parser that matches the longest input.
  * Kleene, to repeat the parser zero or more times.
  * Many, to repeat the parser one or more times.
  * ManyUntil, to repeat the parser until a specified end matcher.
  * Maybe, to apply the parser once or none.
+ * Permutation, to match a set of parsers in any order.
+ * Repeat, Times, AtLeast and AtMost, to repeat the parser a bounded
+   number of times.
+ * SepBy, SepEndBy and EndBy, to repeat the parser with separators.
+ * Between, to match the parser within open and close delimiters.
+ * Chainl1 and Chainr1, to fold operands separated by an operator.
+ * Skip, to match the parser and discard its node.
+
+For operator expressions, instead of hand writing precedence levels,
+use the Pratt builder to register prefix, infix, postfix and ternary
+operators with their binding power, on top of an operand parser.
 
 All the above mentioned combinators accept one or more parser function
 as arguments, either by value or by reference. The reason for allowing
@@ -73,6 +89,24 @@ parsing logic, like parsing nested arrays:
 		Y = parsec.OrdChoice(nil, value)
 	}
 
+Rules referenced via pointer can also be left recursive, directly or
+indirectly, constructing left associative nodes. Note that the left
+recursive rule must be invoked via its reference, even from the root:
+
+	var sum Parser
+	sum = parsec.OrdChoice(nil, parsec.And(nil, &sum, addop, prod), prod)
+	Y = parsec.OrdChoice(nil, &sum)
+
+Alternatively, use Grammar to define rules by name and reference them
+using Grammar.Ref, before they are defined, without package level
+variables. Grammar.Build validates the grammar for undefined and unused
+rules:
+
+	g := parsec.NewGrammar("array")
+	g.Define("array", parsec.And(nil, opensqrt, g.Ref("values"), closesqrt))
+	g.Define("values", parsec.Kleene(nil, g.Ref("value"), comma))
+	g.Define("value", parsec.OrdChoice(nil, parsec.Int(), g.Ref("array")))
+	Y, err := g.Build("array")
 
 Terminal parsers
 
@@ -97,6 +131,70 @@ Most of these parsers return Terminal type as ParseNode.
 All of the terminal parsers, except End and NoEnd return Terminal type
 as ParsecNode. While End and NoEnd return a boolean type as ParsecNode.
 
+Parse errors
+
+Parsers signal failure by returning a nil ParsecNode, backtracking to
+the input scanner. To learn why the input text failed to parse, execute
+the root parser using Parse, or use AST.Error after AST.Parsewith. Both
+return a *ParseError that points at the furthest position reached in the
+input text and lists the terminals that were expected at that position.
+
+	node, s, err := parsec.Parse(Y, parsec.NewScanner(text))
+
+Once a keyword or delimiter has been matched, it is often pointless to
+backtrack and try other alternatives. Place a Cut within And combinator
+to commit to the sequence, subsequent failure shall abort the parse and
+Parse shall return the *ParseError for that failure:
+
+	ifstmt := parsec.And(nil, IF, parsec.Cut(), Expr, Block)
+
+To report all syntax errors in the input text, instead of the first,
+use Recover combinator to skip malformed input until a synchronization
+parser matches, and continue. Errors recovered in a successful parse
+are returned by Parse, and AST.Error, as Diagnostics:
+
+	stmts := parsec.Kleene(nil, parsec.Recover(Stmt, parsec.Atom(";", "SEMI")))
+
+Packrat parsing
+
+Backtracking combinators can parse the same input with the same parser
+many times over. Enable memoization for a parse, without changing the
+grammar, using Packrat on the scanner, or AST.SetPackrat for all parses
+using an AST:
+
+	s := parsec.Packrat(parsec.NewScanner(text), 10000)
+	node, s := Y(s)
+	fmt.Println(parsec.PackratStats(s).HitRate())
+
+Indentation
+
+Block structure by indentation, like in Python or YAML, is expressed
+using Indent, SameIndent and Dedent combinators, that track a stack of
+indentation levels in the scanner. Use SetTabWidth to configure the
+width of tabs:
+
+	block := parsec.And(nil,
+		parsec.Indent(), parsec.Many(nil, parsec.And(nil, parsec.SameIndent(), Stmt)),
+		parsec.Dedent())
+
+User state
+
+Context gathered while parsing, like symbol tables, can be carried by
+the scanner using SetState and read using GetState. State is copied to
+clones of the scanner, hence it is restored when combinators backtrack,
+provided state values are treated as immutable.
+
+Limits
+
+To parse untrusted input, guard the parse against pathological input
+using Limit on the scanner. Parse, or AST.Parsewith, aborts when the
+context is done, or the number of combinator invocations or their nesting
+exceeds the limit, returning ctx.Err(), ErrMaxSteps or ErrMaxDepth:
+
+	s := parsec.Limit(parsec.NewScanner(text), parsec.Limits{
+		Context: ctx, MaxSteps: 1000000, MaxDepth: 1000,
+	})
+
 AST and Queryable
 
 This is an experimental feature to use CSS like selectors for quering
@@ -115,5 +213,38 @@ Additionally, following points are worth noting while using AST,
  * ASTNodify function can interpret its Queryable argument and return
    a different type implementing Queryable interface.
 
+Editors and other tools that parse the same text after every change can
+enable incremental parsing via AST.SetIncremental, and use AST.Reparse
+with the Edit to reuse nodes from the previous parse that are not
+affected by the edit.
+
+To learn how an AST parser behaves on input text, set a Tracer via
+AST.SetTracer. TextTracer writes an indented trace of every named
+combinator, and Profiler gathers calls, matches, backtracks and time
+spent for each rule, along with hot-spots in the input that are
+re-parsed most often:
+
+	profiler := parsec.NewProfiler()
+	ast.SetTracer(profiler).Parsewith(y, s)
+	profiler.Report(os.Stdout, 10)
+
+Concurrency
+
+Parsers, once composed, are immutable and can be shared by any number
+of goroutines, provided every parse use its own scanner. Token,
+TokenExact and OrdTokens compile their pattern when the parser is
+constructed, other patterns are compiled and cached by the scanner.
+Per parse state, like packrat cache and parse errors, is kept with
+the scanner.
+For AST, use AST.Parse to parse concurrently, each call return its own
+Result, while Parsewith, Reparse and Error remember the last parse and
+are not safe for concurrent use:
+
+	r := ast.Parse(y, parsec.NewScanner(text))
+	if err := r.Error(); err != nil {
+		...
+	}
+	root := r.Root()
+
 */
 package parsec
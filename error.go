@@ -0,0 +1,218 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "fmt"
+import "sort"
+import "strings"
+
+// ParseError describes why the input text failed to parse. Parsers
+// backtrack on failure, hence the position reported is the furthest
+// position into the input text where a terminal parser failed to match,
+// along with the names of all terminals that were tried at that
+// position.
+type ParseError struct {
+	Cursor   int      // offset into the input text.
+	Lineno   int      // line number of Cursor, starting from 1.
+	Column   int      // column number of Cursor, starting from 1.
+	Expected []string // sorted list of terminal names expected at Cursor.
+	Message  string   // reason for the error, if not a failed match.
+}
+
+// Error implement error interface.
+func (err *ParseError) Error() string {
+	at := fmt.Sprintf("offset %v", err.Cursor)
+	if err.Lineno > 0 {
+		at = fmt.Sprintf("line %v col %v", err.Lineno, err.Column)
+	}
+	if err.Message != "" {
+		return fmt.Sprintf("parse error at %v, %v", at, err.Message)
+	}
+	switch len(err.Expected) {
+	case 0:
+		return fmt.Sprintf("parse error at %v", at)
+	case 1:
+		return fmt.Sprintf("parse error at %v, expected %v", at, err.Expected[0])
+	}
+	expected := strings.Join(err.Expected, ", ")
+	return fmt.Sprintf("parse error at %v, expected one of %v", at, expected)
+}
+
+// Diagnostics is the list of parse errors recovered by Recover
+// combinator, in the order of their position in the input text.
+type Diagnostics []*ParseError
+
+// Error implement error interface.
+func (diags Diagnostics) Error() string {
+	msgs := make([]string, 0, len(diags))
+	for _, err := range diags {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// session is created for every new scanner and shared by all its
+// clones, it is used to gather information across a single parse.
+// State specific to optional features is allocated only when the
+// feature is used.
+type session struct {
+	failcursor  int             // furthest cursor where a terminal failed.
+	expected    map[string]bool // terminals tried at failcursor.
+	diagnostics Diagnostics     // errors recovered so far.
+	memo        *memotable      // packrat cache, if enabled.
+	tracker     *tracker        // text examined, if incremental.
+	leftrec     *leftrec        // rules being parsed, if any.
+	limits      *limiter        // limits for the parse, if any.
+	states      *states         // versions of user state, if any.
+	layout      *layout         // for computing indentation, if any.
+	tracing     *tracing        // for tracing combinators, if any.
+	recovering  bool            // aborted parse is recovered, refer to Parse.
+}
+
+func newsession() *session {
+	return &session{failcursor: -1, expected: make(map[string]bool)}
+}
+
+// reset session before starting a new parse.
+func (sess *session) reset() {
+	sess.failcursor = -1
+	sess.expected = make(map[string]bool)
+	sess.diagnostics = nil
+	if sess.tracker != nil {
+		sess.tracker.examined = sess.tracker.examined[:1]
+	}
+	if sess.limits != nil {
+		sess.limits.reset()
+	}
+	// results from the previous parse are stale, unless carried over
+	// to reparse the edited text.
+	if memo := sess.memo; memo != nil && memo.carried {
+		memo.carried = false
+	} else if memo != nil {
+		sess.memo = newmemotable(memo.size)
+	}
+}
+
+// expect is called by terminal parsers when they fail to match `name`
+// at `cursor`.
+func (sess *session) expect(cursor int, name string) {
+	if cursor > sess.failcursor {
+		sess.failcursor = cursor
+		sess.expected = make(map[string]bool)
+	}
+	if cursor == sess.failcursor && name != "" {
+		sess.expected[name] = true
+	}
+}
+
+// snapshot the furthest failure, to be restored later.
+func (sess *session) snapshot() (int, map[string]bool) {
+	expected := make(map[string]bool, len(sess.expected))
+	for name := range sess.expected {
+		expected[name] = true
+	}
+	return sess.failcursor, expected
+}
+
+func (sess *session) restore(failcursor int, expected map[string]bool) {
+	sess.failcursor, sess.expected = failcursor, expected
+}
+
+// merge a snapshot with the furthest failure since the snapshot.
+func (sess *session) merge(failcursor int, expected map[string]bool) {
+	if failcursor > sess.failcursor {
+		sess.restore(failcursor, expected)
+	} else if failcursor == sess.failcursor {
+		for name := range expected {
+			sess.expected[name] = true
+		}
+	}
+}
+
+// discard diagnostics recovered after mark, by a failed attempt.
+func (sess *session) discard(mark int) {
+	sess.diagnostics = sess.diagnostics[:mark]
+}
+
+// sessioner is implemented by scanners that can track a parse session.
+type sessioner interface {
+	getsession() *session
+}
+
+// linecoler is implemented by scanners that can compute line number and
+// column number for a cursor position.
+type linecoler interface {
+	linecol(cursor int) (lineno, column int)
+}
+
+func sessionof(s Scanner) *session {
+	if x, ok := s.(*SimpleScanner); ok { // common case, avoid itab lookup.
+		return x.sess
+	} else if x, ok := s.(sessioner); ok {
+		return x.getsession()
+	}
+	return nil
+}
+
+// expect record a failed attempt to match terminal `name` at `cursor`.
+func expect(s Scanner, cursor int, name string) {
+	if sess := sessionof(s); sess != nil {
+		sess.expect(cursor, name)
+	}
+}
+
+// resetsession to start a fresh parse with scanner `s`. The caller
+// shall recover an aborted parse, until the returned function is called.
+func resetsession(s Scanner) (done func()) {
+	if sess := sessionof(s); sess != nil {
+		sess.reset()
+		sess.recovering = true
+		return func() { sess.recovering = false }
+	}
+	return func() {}
+}
+
+// abortable return true if the parse using scanner s can be aborted by
+// a panic, that is, it was started by Parse or AST.Parsewith.
+func abortable(s Scanner) bool {
+	sess := sessionof(s)
+	return sess != nil && sess.recovering
+}
+
+// diagnosticsof return the errors recovered during the parse, if any.
+func diagnosticsof(s Scanner) error {
+	if sess := sessionof(s); sess != nil && len(sess.diagnostics) > 0 {
+		return sess.diagnostics
+	}
+	return nil
+}
+
+// newParseError construct ParseError from the parse session of `s`. If
+// no terminal failure was recorded, scanner's cursor is used.
+func newParseError(s Scanner) *ParseError {
+	err := &ParseError{Cursor: s.GetCursor()}
+	if sess := sessionof(s); sess != nil && sess.failcursor >= 0 {
+		err.Cursor = sess.failcursor
+		for name := range sess.expected {
+			err.Expected = append(err.Expected, name)
+		}
+		sort.Strings(err.Expected)
+	}
+	if x, ok := s.(linecoler); ok {
+		err.Lineno, err.Column = x.linecol(err.Cursor)
+	}
+	return err
+}
+
+// NoProgress abort the parse with a *ParseError, when an iteration of a
+// repetition matched without consuming the input at `s`, instead of
+// looping forever. Available for repetitions defined outside this
+// package, refer to Parse for recovering the error.
+func NoProgress(s Scanner) {
+	err := &ParseError{Cursor: s.GetCursor(), Message: "repetition made no progress"}
+	if x, ok := s.(linecoler); ok {
+		err.Lineno, err.Column = x.linecol(err.Cursor)
+	}
+	panic(err)
+}
@@ -0,0 +1,112 @@
+package parsec
+
+import "reflect"
+import "testing"
+
+func TestParseError(t *testing.T) {
+	ident, equal := Token(`[a-z]+`, "IDENT"), Atom("=", "EQUAL")
+	value := OrdChoice(nil, Int(), Atom("true", "TRUE"), Atom("false", "FALSE"))
+	y := And(nil, ident, equal, value)
+
+	// success
+	node, _, err := Parse(y, NewScanner([]byte("a = 10")))
+	if err != nil {
+		t.Errorf("unexpected %v", err)
+	} else if node == nil {
+		t.Errorf("expected node")
+	}
+
+	// failure after backtracking
+	node, s, err := Parse(y, NewScanner([]byte("loglevel =\n  info")))
+	if node != nil {
+		t.Errorf("expected nil")
+	} else if s.GetCursor() != 0 {
+		t.Errorf("expected %v, got %v", 0, s.GetCursor())
+	}
+	perr := err.(*ParseError)
+	ref := []string{"FALSE", "INT", "TRUE"}
+	if perr.Cursor != 13 {
+		t.Errorf("expected %v, got %v", 13, perr.Cursor)
+	} else if perr.Lineno != 2 || perr.Column != 3 {
+		t.Errorf("unexpected %v:%v", perr.Lineno, perr.Column)
+	} else if !reflect.DeepEqual(perr.Expected, ref) {
+		t.Errorf("expected %v, got %v", ref, perr.Expected)
+	}
+	refmsg := "parse error at line 2 col 3, expected one of FALSE, INT, TRUE"
+	if msg := err.Error(); msg != refmsg {
+		t.Errorf("expected %q, got %q", refmsg, msg)
+	}
+
+	// furthest failure wins over earlier ones.
+	node, _, err = Parse(y, NewScanner([]byte("a 10")))
+	if node != nil {
+		t.Errorf("expected nil")
+	} else if x := err.(*ParseError).Expected; !reflect.DeepEqual(x, []string{"EQUAL"}) {
+		t.Errorf("unexpected %v", x)
+	} else if msg := err.Error(); msg != "parse error at line 1 col 3, expected EQUAL" {
+		t.Errorf("unexpected %q", msg)
+	}
+
+	// OrdTokens
+	y = OrdTokens([]string{`\+`, `-`}, []string{"PLUS", "MINUS"})
+	_, _, err = Parse(y, NewScanner([]byte("  *")))
+	ref = []string{"MINUS", "PLUS"}
+	if perr := err.(*ParseError); perr.Cursor != 2 {
+		t.Errorf("expected %v, got %v", 2, perr.Cursor)
+	} else if !reflect.DeepEqual(perr.Expected, ref) {
+		t.Errorf("expected %v, got %v", ref, perr.Expected)
+	}
+
+	// callback failure without terminal failure.
+	y = And(func(_ []ParsecNode) ParsecNode { return nil }, Atom("x", "X"))
+	_, _, err = Parse(y, NewScanner([]byte("x")))
+	if perr := err.(*ParseError); perr.Expected != nil {
+		t.Errorf("unexpected %v", perr.Expected)
+	} else if msg := err.Error(); msg != "parse error at line 1 col 1" {
+		t.Errorf("unexpected %q", msg)
+	}
+}
+
+func TestASTError(t *testing.T) {
+	ast := NewAST("testerror", 100)
+	y := ast.And("and", nil, Ident(), ast.End("EOF"))
+	node, _ := ast.Parsewith(y, NewScanner([]byte("hello world")))
+	if node != nil {
+		t.Errorf("expected nil")
+	}
+	perr := ast.Error().(*ParseError)
+	if perr.Cursor != 5 {
+		t.Errorf("expected %v, got %v", 5, perr.Cursor)
+	} else if !reflect.DeepEqual(perr.Expected, []string{"EOF"}) {
+		t.Errorf("unexpected %v", perr.Expected)
+	}
+	ast.Reset()
+	if ast.Error() != nil {
+		t.Errorf("expected nil")
+	}
+	node, _ = ast.Parsewith(y, NewScanner([]byte("hello")))
+	if node == nil {
+		t.Errorf("expected node")
+	} else if ast.Error() != nil {
+		t.Errorf("unexpected %v", ast.Error())
+	}
+}
+
+func TestSession(t *testing.T) {
+	y := And(nil, Token(`[a-z]+`, "IDENT"), Atom("=", "EQUAL"), Int())
+	s := NewScanner([]byte("a = 10"))
+	if node, _ := y(s); node == nil {
+		t.Errorf("expected node")
+	}
+	// feature state is allocated only when the feature is used.
+	sess := sessionof(s)
+	if sess.memo != nil || sess.tracker != nil || sess.leftrec != nil ||
+		sess.limits != nil || sess.states != nil || sess.layout != nil ||
+		sess.tracing != nil {
+		t.Errorf("unexpected feature state %+v", sess)
+	}
+	SetState(s, 10)
+	if sess.states == nil || sess.states.version != 1 {
+		t.Errorf("expected %v, got %+v", 1, sess.states)
+	}
+}
@@ -236,3 +236,14 @@ func ExampleNodify() {
 	// Output:
 	// 200
 }
+
+func ExampleParse() {
+	// report where and why the input text failed to parse.
+	text := []byte("loglevel = 10\nlogfile = ")
+	line := And(nil, Ident(), Atom("=", "EQUAL"), OrdChoice(nil, Int(), Ident()))
+	y := Many(nil, line)
+	_, _, err := Parse(And(nil, y, End()), NewScanner(text))
+	fmt.Println(err)
+	// Output:
+	// parse error at line 2 col 11, expected one of IDENT, INT
+}
@@ -64,9 +64,9 @@ func init() {
 }
 
 func intWS() parsec.Parser {
+	p := parsec.Int()
 	return func(s parsec.Scanner) (parsec.ParsecNode, parsec.Scanner) {
 		_, s = s.SkipAny(`^[  \n\t]+`)
-		p := parsec.Int()
 		return p(s)
 	}
 }
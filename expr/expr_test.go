@@ -3,6 +3,7 @@
 
 package expr
 
+import "sync"
 import "testing"
 
 import "github.com/prataprc/goparsec"
@@ -18,6 +19,24 @@ func TestExpr(t *testing.T) {
 	}
 }
 
+func TestParallel(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				s := parsec.NewScanner([]byte(exprText))
+				if v, _ := Y(s); v.(int) != 110 {
+					t.Errorf("Mismatch value %v\n", v)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
 func BenchmarkExpr1Op(b *testing.B) {
 	text := []byte(`19 + 10`)
 	for i := 0; i < b.N; i++ {
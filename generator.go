@@ -0,0 +1,458 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "bytes"
+import "math/rand"
+import "regexp/syntax"
+import "strconv"
+import "unicode"
+
+// Generator emits random sentences of a grammar, to test parsers and
+// their consumers with valid, and near-valid, inputs. Sentences are
+// composed by walking the structure recorded by combinators, refer
+// Analyze, choosing a random alternative for OrdChoice, a bounded
+// number of repetitions for Kleene, Many etc.., emitting Atom text and
+// sampling strings that match Token patterns. Typically used as:
+//
+//	gen := NewGenerator(y, 1).SetDepth(8)
+//	for i := 0; i < 100; i++ {
+//		text := gen.Sentence()
+//		...
+//	}
+//
+// A space is emitted before terminals that skip leading white space.
+// Lookahead, NotFollowedBy and zero width parsers, like End and Indent,
+// emit nothing, so do custom Parser functions, hence sentences are not
+// guaranteed to be accepted by grammars depending on them. Create
+// the generator after all rules and Pratt operators are defined.
+type Generator struct {
+	a       *analyzer
+	root    *structure
+	rnd     *rand.Rand
+	depth   int                // limit on nesting of rules.
+	repeat  int                // limit on repetitions beyond the minimum.
+	heights map[*structure]int // minimum nesting of rules to terminate.
+	regexps map[string]*syntax.Regexp
+	out     []byte
+	spans   [][2]int // terminals in out, as [start, end) offsets.
+}
+
+const infinite = int(^uint(0) >> 1)
+
+// strays are characters inserted by NearMiss, rarely used in grammars.
+const strays = "@$`"
+
+// NewGenerator return a generator of sentences for root parser, or
+// reference to a parser. Sentences are reproducible for same seed.
+func NewGenerator(root interface{}, seed int64) *Generator {
+	gen := &Generator{
+		a:       newanalyzer(nil),
+		rnd:     rand.New(rand.NewSource(seed)),
+		depth:   16,
+		repeat:  3,
+		heights: make(map[*structure]int),
+		regexps: make(map[string]*syntax.Regexp),
+	}
+	gen.root = gen.a.discover(root)
+	gen.measure()
+	return gen
+}
+
+// SetDepth limit the nesting of rules, default is 16. Beyond the limit
+// alternatives leading to shortest sentences are chosen, optional
+// parsers are skipped and repetitions are kept to their minimum.
+func (gen *Generator) SetDepth(depth int) *Generator {
+	gen.depth = depth
+	return gen
+}
+
+// SetRepeat limit the number of repetitions, beyond the minimum, for
+// repetitive combinators and regular expressions, default is 3.
+func (gen *Generator) SetRepeat(repeat int) *Generator {
+	gen.repeat = repeat
+	return gen
+}
+
+// Sentence return a random sentence of the grammar.
+func (gen *Generator) Sentence() []byte {
+	gen.out, gen.spans = nil, nil
+	gen.generate(gen.root, 0)
+	return gen.out
+}
+
+// NearMiss return a random sentence mutated by a single edit, a
+// terminal is dropped, duplicated, replaced by another terminal or
+// swapped with the next one, a stray character is inserted, or the
+// sentence is truncated after a terminal. Near misses are likely, but
+// not guaranteed, to be rejected by the grammar.
+func (gen *Generator) NearMiss() []byte {
+	text := gen.Sentence()
+	for i := 0; i < 8; i++ {
+		if out := gen.mutate(text, gen.spans); !bytes.Equal(out, text) {
+			return out
+		}
+	}
+	return append(text, strays[gen.rnd.Intn(len(strays))])
+}
+
+func (gen *Generator) generate(st *structure, depth int) {
+	if depth > gen.depth && gen.heightof(st) == infinite {
+		return // rule can't terminate.
+	}
+	args := gen.a.args[st]
+	switch st.kind {
+	case "Rule":
+		gen.generate(args[0], depth+1)
+	case "Ref", "Skip", "Recover":
+		gen.generate(args[0], depth)
+	case "And", "Between":
+		for _, arg := range args {
+			gen.generate(arg, depth)
+		}
+	case "Permutation":
+		for _, i := range gen.rnd.Perm(len(args)) {
+			gen.generate(args[i], depth)
+		}
+	case "OrdChoice", "LongestChoice":
+		if len(args) > 0 {
+			gen.generate(gen.choose(args, depth), depth)
+		}
+	case "Maybe":
+		if gen.fits(args[0], depth) && gen.rnd.Intn(2) == 0 {
+			gen.generate(args[0], depth)
+		}
+	case "Kleene", "Many", "ManyUntil", "Repeat", "SepBy", "SepEndBy", "EndBy",
+		"Chainl1", "Chainr1":
+		gen.repetition(st, args, depth)
+	case "Pratt":
+		gen.expression(st.pratt, depth)
+	case "Token", "TokenExact", "OrdTokens":
+		gen.terminal(st, gen.sample(st.text))
+	case "Atom", "AtomExact":
+		gen.terminal(st, st.text)
+	case "String":
+		gen.terminal(st, strconv.Quote(gen.sample(`[a-zA-Z0-9 ]{1,8}`)))
+	}
+	// zero width parsers, lookaheads and custom parsers emit nothing.
+}
+
+// choose a random alternative among those that fit within the depth
+// limit, else among those leading to the shortest sentences.
+func (gen *Generator) choose(args []*structure, depth int) *structure {
+	var fits []*structure
+	for _, arg := range args {
+		if gen.fits(arg, depth) {
+			fits = append(fits, arg)
+		}
+	}
+	if len(fits) == 0 {
+		least := infinite
+		for _, arg := range args {
+			if h := gen.heightof(arg); h < least {
+				least, fits = h, []*structure{arg}
+			} else if h == least {
+				fits = append(fits, arg)
+			}
+		}
+	}
+	return fits[gen.rnd.Intn(len(fits))]
+}
+
+// repetition emit op repeatedly, separated by sep if any.
+func (gen *Generator) repetition(st *structure, args []*structure, depth int) {
+	var op, sep *structure
+	switch st.kind {
+	case "Kleene", "Many", "Repeat": // op, optional sep.
+		op = args[0]
+	case "ManyUntil": // until, op, optional sep.
+		args = args[1:]
+		op = args[0]
+	default:
+		op, sep = args[0], args[1]
+	}
+	if sep == nil && len(args) > 1 {
+		sep = args[1]
+	}
+
+	min, max := 0, -1
+	switch st.kind {
+	case "Many", "ManyUntil", "Chainl1", "Chainr1":
+		min = 1
+	case "Repeat":
+		min, max = st.min, st.max
+	}
+	n := min
+	if gen.fits(op, depth) && (sep == nil || gen.fits(sep, depth)) {
+		n += gen.rnd.Intn(gen.repeat + 1)
+	}
+	if max >= 0 && n > max {
+		n = max
+	}
+
+	for i := 0; i < n; i++ {
+		if i > 0 && sep != nil {
+			gen.generate(sep, depth)
+		}
+		gen.generate(op, depth)
+	}
+	switch {
+	case n > 0 && st.kind == "EndBy":
+		gen.generate(sep, depth)
+	case n > 0 && st.kind == "SepEndBy" && gen.rnd.Intn(2) == 0:
+		gen.generate(sep, depth)
+	}
+}
+
+// expression emit operands and operators of Pratt parser, nesting of
+// parenthesis and ternary operators is limited like rules.
+func (gen *Generator) expression(pr *Pratt, depth int) {
+	var prefixes, others []*prattop
+	for _, op := range pr.ops {
+		if op.kind == opPrefix {
+			prefixes = append(prefixes, op)
+		} else {
+			others = append(others, op)
+		}
+	}
+	gen.primary(pr, prefixes, depth)
+	for n := gen.rnd.Intn(gen.repeat + 1); n > 0 && len(others) > 0; n-- {
+		op := others[gen.rnd.Intn(len(others))]
+		if op.kind == opTernary && !gen.nest(depth) {
+			continue
+		}
+		gen.generate(gen.a.resolve(op.op), depth)
+		switch op.kind {
+		case opInfix:
+			gen.primary(pr, prefixes, depth)
+		case opTernary:
+			gen.expression(pr, depth+1)
+			gen.generate(gen.a.resolve(op.op2), depth)
+			gen.primary(pr, prefixes, depth)
+		}
+		if op.kind == opInfix && op.assoc == AssocNone {
+			break // non associative operators don't chain.
+		}
+	}
+}
+
+// primary emit an operand, optionally prefixed by an operator, or a
+// parenthesised sub-expression.
+func (gen *Generator) primary(pr *Pratt, prefixes []*prattop, depth int) {
+	if len(prefixes) > 0 && gen.rnd.Intn(4) == 0 {
+		op := prefixes[gen.rnd.Intn(len(prefixes))]
+		gen.generate(gen.a.resolve(op.op), depth)
+	}
+	if pr.open != nil && gen.nest(depth) {
+		gen.generate(gen.a.resolve(pr.open), depth)
+		gen.expression(pr, depth+1)
+		gen.generate(gen.a.resolve(pr.close), depth)
+		return
+	}
+	gen.generate(gen.a.resolve(pr.operand), depth)
+}
+
+// nest return true to nest a sub-expression, less likely with depth,
+// since every expression can nest several sub-expressions.
+func (gen *Generator) nest(depth int) bool {
+	return depth < gen.depth && gen.rnd.Intn(2*depth+4) == 0
+}
+
+func (gen *Generator) terminal(st *structure, text string) {
+	if len(gen.out) > 0 && !st.exact {
+		gen.out = append(gen.out, ' ')
+	}
+	start := len(gen.out)
+	gen.out = append(gen.out, text...)
+	gen.spans = append(gen.spans, [2]int{start, len(gen.out)})
+}
+
+// mutate text by a single edit on one of its terminals.
+func (gen *Generator) mutate(text []byte, spans [][2]int) []byte {
+	stray := strays[gen.rnd.Intn(len(strays))]
+	if len(spans) == 0 {
+		return append([]byte{stray}, text...)
+	}
+	i := gen.rnd.Intn(len(spans))
+	x, y := spans[i], spans[gen.rnd.Intn(len(spans))]
+	out := make([]byte, 0, len(text)+x[1]-x[0]+1)
+	switch gen.rnd.Intn(6) {
+	case 0: // drop.
+		out = append(append(out, text[:x[0]]...), text[x[1]:]...)
+	case 1: // duplicate.
+		out = append(append(out, text[:x[1]]...), ' ')
+		out = append(append(out, text[x[0]:x[1]]...), text[x[1]:]...)
+	case 2: // replace.
+		out = append(append(out, text[:x[0]]...), text[y[0]:y[1]]...)
+		out = append(out, text[x[1]:]...)
+	case 3: // swap with next.
+		if i+1 == len(spans) {
+			return text
+		}
+		y = spans[i+1]
+		out = append(append(out, text[:x[0]]...), text[y[0]:y[1]]...)
+		out = append(append(out, text[x[1]:y[0]]...), text[x[0]:x[1]]...)
+		out = append(out, text[y[1]:]...)
+	case 4: // stray character.
+		out = append(append(out, text[:x[0]]...), stray)
+		out = append(out, text[x[0]:]...)
+	case 5: // truncate after terminal.
+		out = append(out, text[:x[1]]...)
+	}
+	return out
+}
+
+// measure heights of structures, that is, minimum nesting of rules to
+// generate a sentence, iterated until there is no change, since rules
+// can be recursive.
+func (gen *Generator) measure() {
+	structures := append(append([]*structure(nil), gen.a.order...), gen.a.rules...)
+	for _, st := range structures {
+		gen.heights[st] = infinite
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, st := range structures {
+			if h := gen.height(st); h < gen.heights[st] {
+				gen.heights[st], changed = h, true
+			}
+		}
+	}
+}
+
+func (gen *Generator) height(st *structure) int {
+	args := gen.a.args[st]
+	switch st.kind {
+	case "Rule":
+		if h := gen.heightof(args[0]); h < infinite {
+			return h + 1
+		}
+		return infinite
+	case "And", "Between", "Permutation":
+		return gen.highest(args)
+	case "OrdChoice", "LongestChoice":
+		if len(args) == 0 {
+			return 0
+		}
+		h := infinite
+		for _, arg := range args {
+			if x := gen.heightof(arg); x < h {
+				h = x
+			}
+		}
+		return h
+	case "Repeat":
+		if st.min == 0 {
+			return 0
+		} else if st.min == 1 {
+			return gen.heightof(args[0])
+		}
+		return gen.highest(args)
+	case "Many", "Chainl1", "Chainr1", "Skip", "Recover", "Ref", "Pratt":
+		return gen.heightof(args[0])
+	case "ManyUntil": // until, op, sep
+		return gen.heightof(args[1])
+	}
+	// terminals, zero width parsers, and parsers that can emit nothing.
+	return 0
+}
+
+func (gen *Generator) highest(args []*structure) int {
+	h := 0
+	for _, arg := range args {
+		if x := gen.heightof(arg); x > h {
+			h = x
+		}
+	}
+	return h
+}
+
+func (gen *Generator) heightof(st *structure) int {
+	if h, ok := gen.heights[st]; ok {
+		return h
+	}
+	return 0
+}
+
+// fits return true if st can be generated within the depth limit.
+func (gen *Generator) fits(st *structure, depth int) bool {
+	h := gen.heightof(st)
+	return h < infinite && depth+h <= gen.depth
+}
+
+// sample a string matching regular expression pattern.
+func (gen *Generator) sample(pattern string) string {
+	re, ok := gen.regexps[pattern]
+	if !ok {
+		var err error
+		if re, err = syntax.Parse(pattern, syntax.Perl); err != nil {
+			return ""
+		}
+		gen.regexps[pattern] = re
+	}
+	return string(gen.runes(re, nil))
+}
+
+func (gen *Generator) runes(re *syntax.Regexp, buf []rune) []rune {
+	switch re.Op {
+	case syntax.OpLiteral:
+		for _, r := range re.Rune {
+			if re.Flags&syntax.FoldCase != 0 && gen.rnd.Intn(2) == 0 {
+				r = unicode.SimpleFold(r)
+			}
+			buf = append(buf, r)
+		}
+	case syntax.OpCharClass:
+		buf = append(buf, gen.class(re.Rune))
+	case syntax.OpAnyCharNotNL, syntax.OpAnyChar:
+		buf = append(buf, rune(' '+gen.rnd.Intn('~'-' '+1)))
+	case syntax.OpCapture:
+		buf = gen.runes(re.Sub[0], buf)
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			buf = gen.runes(sub, buf)
+		}
+	case syntax.OpAlternate:
+		buf = gen.runes(re.Sub[gen.rnd.Intn(len(re.Sub))], buf)
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		min, max := re.Min, re.Max
+		switch re.Op {
+		case syntax.OpStar:
+			min, max = 0, -1
+		case syntax.OpPlus:
+			min, max = 1, -1
+		case syntax.OpQuest:
+			min, max = 0, 1
+		}
+		n := min + gen.rnd.Intn(gen.repeat+1)
+		if max >= 0 && n > max {
+			n = max
+		}
+		for i := 0; i < n; i++ {
+			buf = gen.runes(re.Sub[0], buf)
+		}
+	}
+	// empty matches, anchors and word boundaries emit nothing.
+	return buf
+}
+
+// class return a random rune from character class ranges, preferring
+// printable ASCII characters.
+func (gen *Generator) class(ranges []rune) rune {
+	var printables []rune
+	for i := 0; i < len(ranges); i += 2 {
+		for r := ranges[i]; r <= ranges[i+1] && r <= '~'; r++ {
+			if r >= ' ' {
+				printables = append(printables, r)
+			}
+		}
+	}
+	if len(printables) > 0 {
+		return printables[gen.rnd.Intn(len(printables))]
+	} else if len(ranges) == 0 {
+		return 0
+	}
+	i := 2 * gen.rnd.Intn(len(ranges)/2)
+	return ranges[i] + rune(gen.rnd.Intn(int(ranges[i+1]-ranges[i])+1))
+}
@@ -0,0 +1,123 @@
+package parsec
+
+import "bytes"
+import "reflect"
+import "regexp"
+import "testing"
+
+func TestGeneratorSentence(t *testing.T) {
+	var value Parser
+	comma := Atom(",", "COMMA")
+	property := And(nil, String(), Atom(":", "COLON"), &value)
+	array := And(nil, Atom("[", "OPEN"), Kleene(nil, &value, comma), Atom("]", "CLOSE"))
+	object := And(nil, Atom("{", "LBRACE"), SepBy(nil, property, comma), Atom("}", "RBRACE"))
+	value = OrdChoice(nil,
+		Atom("null", "NULL"), Atom("true", "TRUE"), Float(), Int(), String(),
+		array, object)
+	y := And(nil, &value, End())
+
+	gen := NewGenerator(y, 1)
+	for i := 0; i < 200; i++ {
+		text := gen.Sentence()
+		if node, _ := y(NewScanner(text)); node == nil {
+			t.Errorf("%v unexpected failure for %q", i, text)
+		}
+	}
+
+	// reproducible for same seed.
+	gen1, gen2, gen3 := NewGenerator(y, 10), NewGenerator(y, 10), NewGenerator(y, 11)
+	var texts1, texts2, texts3 []string
+	for i := 0; i < 20; i++ {
+		texts1 = append(texts1, string(gen1.Sentence()))
+		texts2 = append(texts2, string(gen2.Sentence()))
+		texts3 = append(texts3, string(gen3.Sentence()))
+	}
+	if !reflect.DeepEqual(texts1, texts2) {
+		t.Errorf("expected %v, got %v", texts1, texts2)
+	}
+	if reflect.DeepEqual(texts1, texts3) {
+		t.Errorf("expected different sentences for different seed")
+	}
+
+	// depth limit.
+	gen = NewGenerator(y, 1).SetDepth(3).SetRepeat(5)
+	for i := 0; i < 200; i++ {
+		text := gen.Sentence()
+		nesting, maxnesting := 0, 0
+		for _, ch := range text {
+			if ch == '[' || ch == '{' {
+				nesting++
+			} else if ch == ']' || ch == '}' {
+				nesting--
+			}
+			if nesting > maxnesting {
+				maxnesting = nesting
+			}
+		}
+		if maxnesting > 3 {
+			t.Errorf("%v expected nesting <= 3, got %v for %q", i, maxnesting, text)
+		}
+	}
+}
+
+func TestGeneratorNearMiss(t *testing.T) {
+	var value Parser
+	comma := Atom(",", "COMMA")
+	array := Between(nil, Atom("[", "OPEN"), Atom("]", "CLOSE"), SepBy(nil, &value, comma))
+	value = OrdChoice(nil, Atom("null", "NULL"), Int(), array)
+	y := And(nil, array, End())
+
+	gen, ref := NewGenerator(y, 1), NewGenerator(y, 1)
+	if text, miss := ref.Sentence(), gen.NearMiss(); bytes.Equal(text, miss) {
+		t.Errorf("expected mutation of %q", text)
+	}
+	rejected := 0
+	for i := 0; i < 100; i++ {
+		if node, _ := y(NewScanner(gen.NearMiss())); node == nil {
+			rejected++
+		}
+	}
+	if rejected < 60 {
+		t.Errorf("expected most near misses to be rejected, got %v", rejected)
+	}
+}
+
+func TestGeneratorPratt(t *testing.T) {
+	var expr Parser
+	call := And(nil, Ident(), Atom("(", "LPAREN"), SepBy(nil, &expr, Atom(",", "COMMA")),
+		Atom(")", "RPAREN"))
+	expr = NewPratt(OrdChoice(nil, Int(), call, Ident())).
+		Parens(Atom("(", "OPEN"), Atom(")", "CLOSE")).
+		Infix(Atom("+", "ADD"), 10, AssocLeft, nil).
+		Infix(Atom("*", "MUL"), 20, AssocLeft, nil).
+		Infix(Atom("<", "LT"), 5, AssocNone, nil).
+		Prefix(Atom("-", "NEG"), 30, nil).
+		Postfix(Atom("!", "FACT"), 40, nil).
+		Ternary(Atom("?", "COND"), Atom(":", "ELSE"), 2, nil).
+		Parser()
+	y := And(nil, &expr, End())
+
+	gen := NewGenerator(y, 1).SetDepth(4)
+	for i := 0; i < 200; i++ {
+		text := gen.Sentence()
+		if node, _ := y(NewScanner(text)); node == nil {
+			t.Errorf("%v unexpected failure for %q", i, text)
+		}
+	}
+}
+
+func TestGeneratorSample(t *testing.T) {
+	patterns := []string{
+		`[0-9a-f]{4}`, `(?i:select)`, `"[^"\\]*"`, `a|b+c`, `[+-]?\d+(\.\d+)?([eE][+-]?\d+)?`,
+		`\w+\s\w+`, `[\x{4e00}-\x{4e10}]{2,}`, `.x*.`, `\bword\b`,
+	}
+	gen := NewGenerator(Int(), 1)
+	for _, pattern := range patterns {
+		re := regexp.MustCompile("^(?:" + pattern + ")$")
+		for i := 0; i < 20; i++ {
+			if text := gen.sample(pattern); !re.MatchString(text) {
+				t.Errorf("%q does not match %q", text, pattern)
+			}
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "fmt"
+import "sort"
+import "strings"
+
+// Grammar is a registry of named rules. Rules can be referenced by name
+// before they are defined, hence recursive grammars can be composed
+// without package level variables:
+//
+//	g := NewGrammar("list")
+//	g.Define("list", And(nil, Atom("[", "OPEN"), g.Ref("values"), Atom("]", "CLOSE")))
+//	g.Define("values", Kleene(nil, g.Ref("value"), Atom(",", "COMMA")))
+//	g.Define("value", OrdChoice(nil, Int(), g.Ref("list")))
+//	y, err := g.Build("list")
+//
+// Every call to a function composing the grammar, like above, creates a
+// new instance of the grammar.
+type Grammar struct {
+	name   string
+	rules  map[string]*Parser
+	order  []string        // rule names in the order of definition.
+	refs   map[string]bool // rules referenced via Ref.
+	defs   map[string]bool // rules defined via Define.
+	starts map[string]bool // rules marked as entry points via Start.
+}
+
+// NewGrammar return a new instance of Grammar, `name` is used in error
+// messages.
+func NewGrammar(name string) *Grammar {
+	return &Grammar{
+		name:   name,
+		rules:  make(map[string]*Parser),
+		refs:   make(map[string]bool),
+		defs:   make(map[string]bool),
+		starts: make(map[string]bool),
+	}
+}
+
+// Define rule `name` as parser, or reference to a parser. Panics if
+// rule is already defined.
+func (g *Grammar) Define(name string, parser interface{}) *Grammar {
+	if g.defs[name] {
+		panic(fmt.Errorf("grammar %q: rule %q already defined", g.name, name))
+	}
+	var y Parser
+	switch p := parser.(type) {
+	case Parser:
+		y = p
+	case *Parser:
+		y = ref(p)
+	default:
+		fmsg := "grammar %q: type of parser `%T` for rule %q not supported"
+		panic(fmt.Errorf(fmsg, g.name, parser, name))
+	}
+	*g.rule(name) = y
+	g.defs[name] = true
+	g.order = append(g.order, name)
+	return g
+}
+
+// Ref return a reference to rule `name`, that can be used as parser in
+// combinators, even before the rule is defined. Rules referenced via Ref
+// can be left recursive.
+func (g *Grammar) Ref(name string) *Parser {
+	g.refs[name] = true
+	return g.rule(name)
+}
+
+// Start mark rule `name` as an entry point of the grammar, that need
+// not be referenced from any other rule. Useful for grammars whose
+// rules are parsed on their own, say when the root is chosen later.
+func (g *Grammar) Start(name string) *Grammar {
+	g.starts[name] = true
+	return g
+}
+
+// Rules return the names of all rules, in the order of definition.
+func (g *Grammar) Rules() []string {
+	return append([]string(nil), g.order...)
+}
+
+// Build validate the grammar and return the parser for `root` rule.
+// It is an error to reference undefined rules, or to define rules
+// that are not referenced from any other rule, except root and rules
+// marked via Start.
+func (g *Grammar) Build(root string) (Parser, error) {
+	var undefined, unused []string
+	if !g.defs[root] {
+		undefined = append(undefined, root)
+	}
+	for name := range g.refs {
+		if !g.defs[name] && name != root {
+			undefined = append(undefined, name)
+		}
+	}
+	for _, name := range g.order {
+		if !g.refs[name] && !g.starts[name] && name != root {
+			unused = append(unused, name)
+		}
+	}
+	sort.Strings(undefined)
+
+	var errs []string
+	if len(undefined) > 0 {
+		errs = append(errs, "undefined rules "+strings.Join(undefined, ", "))
+	}
+	if len(unused) > 0 {
+		errs = append(errs, "unused rules "+strings.Join(unused, ", "))
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("grammar %q: %v", g.name, strings.Join(errs, "; "))
+	}
+
+	return ref(g.rules[root]), nil
+}
+
+// ref return a parser for rule, supporting left recursion.
+func ref(rule *Parser) Parser {
+	st := &structure{kind: "Ref", parsers: []interface{}{rule}}
+	return record(st, func(s Scanner) (ParsecNode, Scanner) {
+		return parserule(rule, s)
+	})
+}
+
+func (g *Grammar) rule(name string) *Parser {
+	rule, ok := g.rules[name]
+	if !ok {
+		rule = new(Parser)
+		*rule = func(s Scanner) (ParsecNode, Scanner) {
+			panic(fmt.Errorf("grammar %q: rule %q not defined", g.name, name))
+		}
+		g.rules[name] = rule
+	}
+	return rule
+}
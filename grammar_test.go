@@ -0,0 +1,85 @@
+package parsec
+
+import "fmt"
+import "testing"
+
+func TestGrammar(t *testing.T) {
+	newlist := func() *Grammar {
+		g := NewGrammar("list")
+		g.Define("list", And(nil, Atom("[", "OPEN"), g.Ref("values"), Atom("]", "CLOSE")))
+		g.Define("values", Kleene(nil, g.Ref("value"), Atom(",", "COMMA")))
+		g.Define("value", OrdChoice(one2one, Int(), g.Ref("list")))
+		return g
+	}
+
+	y1, err := newlist().Build("list")
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+	y2, _ := newlist().Build("list")
+	for _, y := range []Parser{y1, y2} {
+		node, s := y(NewScanner([]byte("[1, [2, []], 3]")))
+		if node == nil {
+			t.Errorf("expected node")
+		} else if !s.Endof() {
+			t.Errorf("expected end of text")
+		}
+	}
+	if ref := []string{"list", "values", "value"}; fmt.Sprint(newlist().Rules()) != fmt.Sprint(ref) {
+		t.Errorf("expected %v, got %v", ref, newlist().Rules())
+	}
+
+	// left recursion
+	g := NewGrammar("sum")
+	fold := func(ns []ParsecNode) ParsecNode {
+		return fmt.Sprintf("(%v-%v)", ns[0], ns[2].(*Terminal).Value)
+	}
+	g.Define("sum", OrdChoice(one2one, And(fold, g.Ref("sum"), Atom("-", "SUB"), Int()), g.Ref("int")))
+	g.Define("int", And(func(ns []ParsecNode) ParsecNode { return ns[0].(*Terminal).Value }, Int()))
+	y, err := g.Build("sum")
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+	if node, _ := y(NewScanner([]byte("1 - 2 - 3"))); node != "((1-2)-3)" {
+		t.Errorf("expected %v, got %v", "((1-2)-3)", node)
+	}
+}
+
+func TestGrammarErrors(t *testing.T) {
+	g := NewGrammar("test")
+	g.Define("root", And(nil, g.Ref("b"), g.Ref("a")))
+	g.Define("c", Int())
+	g.Define("d", Int())
+	_, err := g.Build("root")
+	ref := `grammar "test": undefined rules a, b; unused rules c, d`
+	if err == nil || err.Error() != ref {
+		t.Errorf("expected %v, got %v", ref, err)
+	}
+	if _, err = NewGrammar("test").Build("root"); err == nil {
+		t.Errorf("expected error")
+	}
+	// rules marked as entry points are not unused.
+	start := NewGrammar("test")
+	start.Define("root", start.Ref("a")).Define("a", Int()).Define("b", Int())
+	if _, err = start.Start("b").Build("root"); err != nil {
+		t.Errorf("unexpected %v", err)
+	}
+
+	// undefined rule invoked
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("expected panic")
+			}
+		}()
+		(*g.Ref("x"))(NewScanner([]byte("10")))
+	}()
+
+	// redefined rule
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("expected panic")
+		}
+	}()
+	g.Define("c", Int())
+}
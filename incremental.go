@@ -0,0 +1,334 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "fmt"
+import "io"
+import "bytes"
+import "unicode/utf8"
+
+// Incremental parsing reuses the results of a previous parse after
+// editing its input text. Along with the packrat cache, extent of the
+// input text examined by every combinator is remembered, including the
+// text examined by regular expressions beyond their match. When the
+// text is edited, results of combinators that examined text only before
+// the edit are reused as is, and results of combinators that examined
+// text only after the edit are reused with their positions shifted.
+// Rest of the combinators are parsed again, for example,
+//
+//	ast := parsec.NewAST("program", 100).SetIncremental(100000)
+//	root, _ := ast.Parsewith(y, parsec.NewScanner(text))
+//	root, _ = ast.Reparse(parsec.Edit{Offset: 10, Deleted: 2, Inserted: []byte("x")})
+//
+// Results can be reused only if their nodes are Terminal, NonTerminal,
+// list of ParsecNode, or nodes that don't carry position, like strings.
+// Other nodes, from custom Nodify and ASTNodify callbacks, are parsed
+// again when their position changes.
+
+// Edit describes a change to the input text, Deleted number of bytes
+// starting from Offset are replaced with Inserted bytes.
+type Edit struct {
+	Offset   int
+	Deleted  int
+	Inserted []byte
+}
+
+// Incremental enables packrat parsing, refer to Packrat, for the parse
+// using scanner s, and additionally tracks the input text examined by
+// every combinator so that its results can be reused after editing the
+// input text, refer to AST.Reparse. Return s.
+func Incremental(s Scanner, size int) Scanner {
+	if sess := sessionof(s); sess != nil {
+		sess.memo = newmemotable(size)
+		sess.tracker = &tracker{examined: []extent{{}}}
+	}
+	return s
+}
+
+// tracker track the input text examined by the parse, as a stack of
+// extents for parsers being invoked.
+type tracker struct {
+	examined []extent
+}
+
+// examine record that input text in [lo, hi) was examined by the
+// parse, if tracked for incremental parsing. hi beyond the end of text
+// means end of text was examined.
+func (sess *session) examine(lo, hi int) {
+	if sess.tracker == nil {
+		return
+	}
+	examined := sess.tracker.examined
+	if n := len(examined); n > 0 {
+		examined[n-1] = examined[n-1].merge(extent{lo: lo, hi: hi})
+	}
+}
+
+// track the text examined by a parser invoked at cursor, return the
+// depth to be passed to untrack.
+func (sess *session) track(cursor int) int {
+	if sess.tracker == nil {
+		return 0
+	}
+	tr := sess.tracker
+	depth := len(tr.examined)
+	if depth > 0 {
+		tr.examined = append(tr.examined, extent{lo: cursor, hi: cursor})
+	}
+	return depth
+}
+
+// untrack return the text examined since track, including parsers that
+// did not untrack due to a panic, and merge it with the caller's.
+func (sess *session) untrack(depth int) extent {
+	if depth == 0 || sess.tracker == nil || len(sess.tracker.examined) <= depth {
+		return extent{}
+	}
+	tr := sess.tracker
+	examined := tr.examined[depth]
+	for _, ex := range tr.examined[depth+1:] {
+		examined = examined.merge(ex)
+	}
+	tr.examined = tr.examined[:depth]
+	sess.examine(examined.lo, examined.hi)
+	return examined
+}
+
+// extent of input text, [lo, hi), examined by a parser.
+type extent struct {
+	lo, hi int
+}
+
+func (ex extent) merge(other extent) extent {
+	if other.lo < ex.lo {
+		ex.lo = other.lo
+	}
+	if other.hi > ex.hi {
+		ex.hi = other.hi
+	}
+	return ex
+}
+
+// trackreader implements io.RuneReader over input text, remembering how
+// far the text was read.
+type trackreader struct {
+	buf []byte
+	off int
+	eof bool
+}
+
+func (r *trackreader) ReadRune() (ch rune, size int, err error) {
+	if r.off >= len(r.buf) {
+		r.eof = true
+		return 0, 0, io.EOF
+	}
+	ch, size = utf8.DecodeRune(r.buf[r.off:])
+	r.off += size
+	return ch, size, nil
+}
+
+// examined return the offset upto which the text was read, beyond the
+// end of text if end of text was read.
+func (r *trackreader) examined() int {
+	if r.eof {
+		return len(r.buf) + 1
+	}
+	return r.off
+}
+
+// edited return a new scanner over the input text of s after applying
+// edit. If the parse using s was incremental, the packrat cache is
+// carried over, refer to Incremental.
+func edited(s Scanner, edit Edit) Scanner {
+	scanner, ok := s.(*SimpleScanner)
+	if !ok {
+		panic(fmt.Errorf("scanner %T does not support edits", s))
+	}
+	buf, off, end := scanner.buf, edit.Offset, edit.Offset+edit.Deleted
+	if off < 0 || edit.Deleted < 0 || end > len(buf) {
+		fmsg := "invalid edit %v+%v for text of length %v"
+		panic(fmt.Errorf(fmsg, edit.Offset, edit.Deleted, len(buf)))
+	}
+	text := make([]byte, 0, len(buf)-edit.Deleted+len(edit.Inserted))
+	text = append(text, buf[:off]...)
+	text = append(text, edit.Inserted...)
+	text = append(text, buf[end:]...)
+
+	sess := newsession()
+	if scanner.sess.layout != nil {
+		sess.layout = &layout{tabwidth: scanner.sess.layout.tabwidth}
+	}
+	// user state from the previous parse can be reused, hence versions
+	// shall not be repeated.
+	if scanner.sess.states != nil {
+		sess.states = &states{version: scanner.sess.states.version}
+	}
+	if lim := scanner.sess.limits; lim != nil {
+		sess.limits = &limiter{Limits: lim.Limits}
+	}
+	sh := &shifter{
+		text: text, sess: sess, off: off, end: end,
+		delta: len(edit.Inserted) - edit.Deleted,
+		lines: bytes.Count(edit.Inserted, []byte{'\n'}) -
+			bytes.Count(buf[off:end], []byte{'\n'}),
+		terms: make(map[*Terminal]*Terminal),
+		nts:   make(map[*NonTerminal]*NonTerminal),
+	}
+	if memo := scanner.sess.memo; memo != nil && scanner.sess.tracker != nil {
+		sess.memo = sh.memotable(memo)
+		sess.tracker = &tracker{examined: []extent{{}}}
+	}
+
+	news := *scanner
+	news.buf, news.sess = text, sess
+	switch {
+	case news.cursor <= off: // parse shall include the inserted text.
+	case news.cursor >= end:
+		news.cursor, news.lineno = news.cursor+sh.delta, news.lineno+sh.lines
+	default:
+		news.lineno -= bytes.Count(buf[off:news.cursor], []byte{'\n'})
+		news.cursor = off
+	}
+	return &news
+}
+
+// shifter carry over the results of a previous parse to the edited
+// text, shifting the positions after the edit.
+type shifter struct {
+	text  []byte
+	sess  *session
+	off   int // offset of the edit.
+	end   int // end of the deleted text, before edit.
+	delta int // number of bytes added by the edit, can be negative.
+	lines int // number of lines added by the edit, can be negative.
+	// shifted nodes, nodes are shared by results.
+	terms map[*Terminal]*Terminal
+	nts   map[*NonTerminal]*NonTerminal
+}
+
+func (sh *shifter) memotable(mt *memotable) *memotable {
+	newmt := newmemotable(mt.size)
+	newmt.carried = true
+	for elem := mt.lru.Back(); elem != nil; elem = elem.Prev() {
+		if entry, ok := sh.entry(elem.Value.(*memoentry)); ok {
+			newmt.push(entry)
+		}
+	}
+	return newmt
+}
+
+func (sh *shifter) entry(entry *memoentry) (*memoentry, bool) {
+	var delta, lines int
+
+	key, examined := entry.key, entry.examined
+	switch {
+	case examined.hi <= sh.off && key.cursor < sh.off:
+	case examined.lo >= sh.end && key.cursor >= sh.end:
+		delta, lines = sh.delta, sh.lines
+	default:
+		return nil, false
+	}
+	key.cursor += delta
+	examined.lo, examined.hi = examined.lo+delta, examined.hi+delta
+	newentry := &memoentry{key: key, examined: examined}
+	if entry.node == nil {
+		return newentry, true
+	}
+
+	node, ok := entry.node, true
+	if delta != 0 {
+		if node, ok = sh.node(node); !ok {
+			return nil, false
+		}
+	}
+	news, ok := entry.news.(*SimpleScanner)
+	if !ok {
+		return nil, false
+	}
+	newscanner := *news
+	newscanner.buf, newscanner.sess = sh.text, sh.sess
+	newscanner.cursor, newscanner.lineno = news.cursor+delta, news.lineno+lines
+	newentry.node, newentry.news = node, &newscanner
+	for _, diag := range entry.diags {
+		newdiag := *diag
+		if newdiag.Cursor += delta; newdiag.Lineno > 0 {
+			newdiag.Lineno, newdiag.Column = newscanner.linecol(newdiag.Cursor)
+		}
+		newentry.diags = append(newentry.diags, &newdiag)
+	}
+	return newentry, true
+}
+
+// node return a copy of node with its position shifted, return false
+// if node cannot be shifted.
+func (sh *shifter) node(node ParsecNode) (ParsecNode, bool) {
+	switch n := node.(type) {
+	case *Terminal:
+		return sh.terminal(n), true
+	case *NonTerminal:
+		if nt, ok := sh.nonterminal(n); ok {
+			return nt, true
+		}
+	case []ParsecNode:
+		ns := make([]ParsecNode, 0, len(n))
+		for _, item := range n {
+			item, ok := sh.node(item)
+			if !ok {
+				return nil, false
+			}
+			ns = append(ns, item)
+		}
+		return ns, true
+	case MaybeNone, voidnode, cutnode, string, bool:
+		return n, true
+	}
+	return nil, false
+}
+
+func (sh *shifter) terminal(t *Terminal) *Terminal {
+	newt, ok := sh.terms[t]
+	if !ok {
+		newt = &Terminal{
+			Name: t.Name, Value: t.Value, Position: t.Position + sh.delta,
+			Attributes: copyattrs(t.Attributes),
+		}
+		sh.terms[t] = newt
+	}
+	return newt
+}
+
+func (sh *shifter) nonterminal(nt *NonTerminal) (*NonTerminal, bool) {
+	if newnt, ok := sh.nts[nt]; ok {
+		return newnt, true
+	}
+	newnt := &NonTerminal{
+		Name:       nt.Name,
+		Children:   make([]Queryable, 0, len(nt.Children)),
+		Attributes: copyattrs(nt.Attributes),
+	}
+	for _, child := range nt.Children {
+		node, ok := sh.node(child)
+		if !ok {
+			return nil, false
+		}
+		q, ok := node.(Queryable)
+		if !ok {
+			return nil, false
+		}
+		newnt.Children = append(newnt.Children, q)
+	}
+	sh.nts[nt] = newnt
+	return newnt, true
+}
+
+func copyattrs(attrs map[string][]string) map[string][]string {
+	if attrs == nil {
+		return nil
+	}
+	newattrs := make(map[string][]string, len(attrs))
+	for name, values := range attrs {
+		newattrs[name] = append([]string(nil), values...)
+	}
+	return newattrs
+}
@@ -0,0 +1,133 @@
+package parsec
+
+import "fmt"
+import "math/rand"
+import "strings"
+import "testing"
+
+func TestReparse(t *testing.T) {
+	ast, y := makeincrementaly()
+	text := "a = 10; bc = 20; d = \"x\";"
+	root, _ := ast.Parsewith(y, NewScanner([]byte(text)))
+	if ast.Error() != nil {
+		t.Fatalf("unexpected %v", ast.Error())
+	}
+	first := root.GetChildren()[0]
+	last := root.GetChildren()[2]
+
+	// edit second statement.
+	root, s := ast.Reparse(Edit{Offset: 13, Deleted: 2, Inserted: []byte("300")})
+	text = "a = 10; bc = 300; d = \"x\";"
+	if ast.Error() != nil {
+		t.Fatalf("unexpected %v", ast.Error())
+	} else if ref, out := parseafresh(text), dumpquery(root); out != ref {
+		t.Errorf("expected %v, got %v", ref, out)
+	} else if !s.Endof() {
+		t.Errorf("expected end of text")
+	}
+	// unaffected subtrees are reused.
+	if root.GetChildren()[0] != first {
+		t.Errorf("expected first statement to be reused")
+	} else if stats := PackratStats(s); stats.Hits == 0 {
+		t.Errorf("expected cache hits")
+	}
+	if q := root.GetChildren()[2]; q.GetPosition() != last.GetPosition()+1 {
+		t.Errorf("expected %v, got %v", last.GetPosition()+1, q.GetPosition())
+	}
+
+	// edit joining two tokens, ";" before "d" is deleted.
+	root, s = ast.Reparse(Edit{Offset: 16, Deleted: 2})
+	if n := len(root.GetChildren()); n != 1 {
+		t.Errorf("expected %v, got %v", 1, n)
+	} else if s.GetCursor() != 7 {
+		t.Errorf("expected %v, got %v", 7, s.GetCursor())
+	}
+	// insert it back.
+	root, _ = ast.Reparse(Edit{Offset: 16, Inserted: []byte("; ")})
+	if ref, out := parseafresh(text), dumpquery(root); out != ref {
+		t.Errorf("expected %v, got %v", ref, out)
+	}
+
+	// append to the end of text.
+	root, _ = ast.Reparse(Edit{Offset: len(text), Inserted: []byte(" e = 1;")})
+	text += " e = 1;"
+	if ref, out := parseafresh(text), dumpquery(root); out != ref {
+		t.Errorf("expected %v, got %v", ref, out)
+	}
+}
+
+func TestReparseLookahead(t *testing.T) {
+	// regular expression examines text beyond its match.
+	ast := NewAST("lookahead", 100).SetIncremental(1000)
+	y := ast.Many("tokens", nil, ast.OrdChoice("token", nil,
+		Token(`a(bc)?`, "ABC"), Token(`[b-z]`, "LETTER")))
+	root, _ := ast.Parsewith(y, NewScanner([]byte("x abd")))
+	if ref, out := "tokens(LETTER:x@0 ABC:a@2 LETTER:b@3 LETTER:d@4)", dumpquery(root); out != ref {
+		t.Errorf("expected %v, got %v", ref, out)
+	}
+	root, _ = ast.Reparse(Edit{Offset: 4, Deleted: 1, Inserted: []byte("c")})
+	if ref, out := "tokens(LETTER:x@0 ABC:abc@2)", dumpquery(root); out != ref {
+		t.Errorf("expected %v, got %v", ref, out)
+	}
+}
+
+func TestReparseRandom(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	ast, y := makeincrementaly()
+	stmts := make([]string, 0)
+	for i := 0; i < 50; i++ {
+		stmts = append(stmts, fmt.Sprintf("x%v = %v;", i, i*i))
+	}
+	text := strings.Join(stmts, "\n")
+	ast.Parsewith(y, NewScanner([]byte(text)))
+
+	alphabet := "ab1 ;=\n\""
+	for i := 0; i < 500; i++ {
+		edit := Edit{Offset: rnd.Intn(len(text) + 1)}
+		edit.Deleted = rnd.Intn(len(text)-edit.Offset+1) % 4
+		for j := rnd.Intn(4); j > 0; j-- {
+			edit.Inserted = append(edit.Inserted, alphabet[rnd.Intn(len(alphabet))])
+		}
+		text = text[:edit.Offset] + string(edit.Inserted) +
+			text[edit.Offset+edit.Deleted:]
+
+		root, s := ast.Reparse(edit)
+		refast, refy := makeincrementaly()
+		refroot, refs := refast.Parsewith(refy, NewScanner([]byte(text)))
+		if (ast.Error() == nil) != (refast.Error() == nil) {
+			t.Fatalf("%q expected %v, got %v", text, refast.Error(), ast.Error())
+		} else if refroot == nil {
+			continue
+		} else if ref, out := dumpquery(refroot), dumpquery(root); out != ref {
+			t.Fatalf("%q expected %v, got %v", text, ref, out)
+		} else if s.GetCursor() != refs.GetCursor() {
+			t.Fatalf("expected %v, got %v", refs.GetCursor(), s.GetCursor())
+		}
+	}
+}
+
+func makeincrementaly() (*AST, Parser) {
+	ast := NewAST("incremental", 100).SetIncremental(100000)
+	value := ast.OrdChoice("value", nil, Int(), Token(`"[^"]*"`, "STRING"), Ident())
+	stmt := ast.And("stmt", nil, Ident(), Atom("=", "EQUAL"), value, Atom(";", "SEMI"))
+	return ast, ast.Kleene("program", nil, stmt)
+}
+
+func parseafresh(text string) string {
+	ast, y := makeincrementaly()
+	root, _ := ast.Parsewith(y, NewScanner([]byte(text)))
+	return dumpquery(root)
+}
+
+func dumpquery(q Queryable) string {
+	if q == nil {
+		return "<nil>"
+	} else if q.IsTerminal() {
+		return fmt.Sprintf("%v:%v@%v", q.GetName(), q.GetValue(), q.GetPosition())
+	}
+	children := make([]string, 0)
+	for _, child := range q.GetChildren() {
+		children = append(children, dumpquery(child))
+	}
+	return fmt.Sprintf("%v(%v)", q.GetName(), strings.Join(children, " "))
+}
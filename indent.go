@@ -0,0 +1,187 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "fmt"
+
+// Indentation sensitive grammars, like Python or YAML, can express
+// blocks using Indent, SameIndent and Dedent combinators. Scanner
+// tracks a stack of indentation levels, starting with level 0, which is
+// copied to its clones, hence restored when combinators backtrack. For
+// example,
+//
+//	block := And(nil,
+//		Atom(":", "COLON"), Indent(),
+//		Many(nil, And(nil, SameIndent(), stmt)),
+//		Dedent())
+//
+// Indentation is the width of leading whitespace on the line of the next
+// token, where a tab advances to the next multiple of tab-width. These
+// combinators don't consume input and And combinator shall not include
+// their nodes in its list of ParsecNode. They are supported only by
+// SimpleScanner, with other scanners the parse is aborted with a
+// *ParseError.
+
+// SetTabWidth for computing indentation of lines, default is 8, and
+// return s. Scanners that do not support indentation are returned as
+// is.
+func SetTabWidth(s Scanner, width int) Scanner {
+	if width <= 0 {
+		panic(fmt.Errorf("invalid tab width %v", width))
+	}
+	if scanner, ok := s.(*SimpleScanner); ok {
+		scanner.sess.layout = &layout{tabwidth: width}
+	}
+	return s
+}
+
+// Indent combinator succeeds if the next token is the first token on
+// its line, and its indentation is greater than the current level. The
+// indentation is pushed as the new current level.
+func Indent() Parser {
+	return record(&structure{kind: "Indent"}, func(s Scanner) (ParsecNode, Scanner) {
+		scanner := indentscanner(s)
+		level, pos, newline := scanner.indentation()
+		if !newline || level <= scanner.indentlevel() {
+			expect(s, pos, "INDENT")
+			return nil, s
+		}
+		news := scanner.Clone().(*SimpleScanner)
+		news.setindents(append(scanner.indents(), level))
+		return voidnode{}, news
+	})
+}
+
+// SameIndent combinator succeeds if the next token is the first token
+// on its line, and its indentation is same as the current level.
+func SameIndent() Parser {
+	return record(&structure{kind: "SameIndent"}, func(s Scanner) (ParsecNode, Scanner) {
+		scanner := indentscanner(s)
+		level, pos, newline := scanner.indentation()
+		if !newline || level != scanner.indentlevel() {
+			expect(s, pos, "NEWLINE")
+			return nil, s
+		}
+		return voidnode{}, s
+	})
+}
+
+// Dedent combinator succeeds if the next token is the first token on
+// its line, and its indentation is less than the current level, or if
+// there are no more tokens. The current level is popped. Every Dedent
+// pops one level, hence to close several blocks at once use as many
+// Dedent. If the indentation does not match any of the outer levels,
+// the parse is aborted with a *ParseError, refer to Parse.
+func Dedent() Parser {
+	return record(&structure{kind: "Dedent"}, func(s Scanner) (ParsecNode, Scanner) {
+		scanner := indentscanner(s)
+		level, pos, newline := scanner.indentation()
+		indents := scanner.indents()
+		n := len(indents)
+		if pos == len(scanner.buf) && n > 0 {
+			level, newline = 0, true
+		}
+		if n == 0 || !newline || level >= scanner.indentlevel() {
+			expect(s, pos, "DEDENT")
+			return nil, s
+		}
+		news := scanner.Clone().(*SimpleScanner)
+		news.setindents(indents[:n-1])
+		if outer := news.indentlevel(); level > outer {
+			err := newParseError(s)
+			err.Cursor, err.Expected = pos, nil
+			err.Lineno, err.Column = scanner.linecol(pos)
+			err.Message = "inconsistent indentation"
+			panic(err)
+		}
+		return voidnode{}, news
+	})
+}
+
+// indentscanner return s as SimpleScanner, the only scanner supporting
+// indentation, else abort the parse with a *ParseError.
+func indentscanner(s Scanner) *SimpleScanner {
+	if scanner, ok := s.(*SimpleScanner); ok {
+		return scanner
+	}
+	err := &ParseError{Cursor: s.GetCursor()}
+	err.Message = fmt.Sprintf("indentation not supported by scanner %T", s)
+	panic(err)
+}
+
+// indentation return the indentation of the line of the next token,
+// position of the next token, and whether it is the first token on its
+// line.
+func (s *SimpleScanner) indentation() (level, pos int, newline bool) {
+	pos = s.cursor
+	for pos < len(s.buf) && isspace(s.buf[pos]) {
+		pos++
+	}
+	start := pos
+	for start > 0 && s.buf[start-1] != '\n' {
+		start--
+	}
+	s.sess.examine(start, pos+1)
+	tabwidth := s.sess.tabwidth()
+	newline = true
+	for _, ch := range s.buf[start:pos] {
+		switch {
+		case ch == '\t':
+			level = (level/tabwidth + 1) * tabwidth
+		case ch == '\r':
+		case !isspace(ch):
+			newline = false
+			fallthrough
+		default:
+			level++
+		}
+	}
+	return level, pos, newline
+}
+
+func (s *SimpleScanner) indentlevel() int {
+	indents := s.indents()
+	if n := len(indents); n > 0 {
+		return indents[n-1]
+	}
+	return 0
+}
+
+// indents return the stack of indentation levels.
+func (s *SimpleScanner) indents() []int {
+	if s.state != nil {
+		return s.state.indents
+	}
+	return nil
+}
+
+// setindents with a new stack of indentation levels. Stacks are shared
+// by clones, hence they are never modified in place.
+func (s *SimpleScanner) setindents(indents []int) {
+	state := &userstate{
+		indents: indents[:len(indents):len(indents)],
+		version: s.sess.nextversion(),
+	}
+	if s.state != nil {
+		state.value = s.state.value
+	}
+	s.state = state
+}
+
+// layout settings for computing indentation.
+type layout struct {
+	tabwidth int
+}
+
+// tabwidth for the parse session, default is 8.
+func (sess *session) tabwidth() int {
+	if sess.layout == nil {
+		return 8
+	}
+	return sess.layout.tabwidth
+}
+
+func isspace(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\r' || ch == '\n'
+}
@@ -0,0 +1,122 @@
+package parsec
+
+import "strings"
+import "testing"
+
+func makeblocky() Parser {
+	var stmt Parser
+
+	// stmt  -> IDENT | IDENT ":" block
+	// block -> INDENT (SAMEINDENT stmt)+ DEDENT
+	block := And(nil,
+		Indent(), Many(nil, And(one2one, SameIndent(), &stmt)), Dedent())
+	compound := And(
+		func(ns []ParsecNode) ParsecNode {
+			return []ParsecNode{ns[0].(*Terminal).Value, ns[2]}
+		},
+		Ident(), Atom(":", "COLON"), block,
+	)
+	simple := And(
+		func(ns []ParsecNode) ParsecNode { return ns[0].(*Terminal).Value },
+		Ident())
+	stmt = OrdChoice(one2one, compound, simple)
+	return And(one2one, Many(nil, And(one2one, SameIndent(), &stmt)), End())
+}
+
+func TestIndent(t *testing.T) {
+	y := makeblocky()
+	testcases := []struct {
+		text string
+		ref  string
+	}{
+		{"a\nb", "[a b]"},
+		{"a:\n  b\n  c\nd", "[[a [[b c]]] d]"},
+		{"a:\n  b:\n    c\n  d\ne", "[[a [[[b [[c]]] d]]] e]"},
+		{"a:\n  b:\n    c\ne", "[[a [[[b [[c]]]]]] e]"},
+		{"a:\n\tb:\n\t\tc", "[[a [[[b [[c]]]]]]]"},
+		{"a:\n  b:\n\n    c", "[[a [[[b [[c]]]]]]]"},
+	}
+	for _, tcase := range testcases {
+		for _, s := range []Scanner{
+			NewScanner([]byte(tcase.text)),
+			Packrat(NewScanner([]byte(tcase.text)), 1000),
+		} {
+			node, _, err := Parse(y, s)
+			if err != nil {
+				t.Errorf("%q unexpected %v", tcase.text, err)
+			} else if out := sprint(node); out != tcase.ref {
+				t.Errorf("%q expected %v, got %v", tcase.text, tcase.ref, out)
+			}
+		}
+	}
+
+	// errors
+	testcases = []struct {
+		text string
+		ref  string
+	}{
+		{"a\n  b", "parse error at line 2 col 3, expected one of COLON, NEWLINE"},
+		{"a:\nb", "parse error at line 2 col 1, expected INDENT"},
+		{"a: b", "parse error at line 1 col 4, expected INDENT"},
+		{"a:\n    b\n  c", "parse error at line 3 col 3, inconsistent indentation"},
+	}
+	for _, tcase := range testcases {
+		_, _, err := Parse(y, NewScanner([]byte(tcase.text)))
+		if err == nil {
+			t.Errorf("%q expected error", tcase.text)
+		} else if err.Error() != tcase.ref {
+			t.Errorf("%q expected %v, got %v", tcase.text, tcase.ref, err)
+		}
+	}
+
+	// tab width
+	text := "a:\n    b\n\tc"
+	if _, _, err := Parse(y, NewScanner([]byte(text))); err == nil {
+		t.Errorf("expected error")
+	}
+	s := SetTabWidth(NewScanner([]byte(text)), 4)
+	if _, _, err := Parse(y, s); err != nil {
+		t.Errorf("unexpected %v", err)
+	}
+
+	// input scanner is not modified.
+	s = NewScanner([]byte("a:\n  b"))
+	_, s = Atom("a:", "A")(s)
+	if node, news := Indent()(s); node == nil {
+		t.Errorf("expected node")
+	} else if s.(*SimpleScanner).indentlevel() != 0 {
+		t.Errorf("expected %v, got %v", 0, s.(*SimpleScanner).indentlevel())
+	} else if news.(*SimpleScanner).indentlevel() != 2 {
+		t.Errorf("expected %v, got %v", 2, news.(*SimpleScanner).indentlevel())
+	} else if _, s = Ident()(news); s.(*SimpleScanner).indentlevel() != 2 {
+		t.Errorf("expected %v, got %v", 2, s.(*SimpleScanner).indentlevel())
+	} else if node, news = Dedent()(s); node == nil {
+		t.Errorf("expected node")
+	} else if s.(*SimpleScanner).indentlevel() != 2 {
+		t.Errorf("expected %v, got %v", 2, s.(*SimpleScanner).indentlevel())
+	} else if news.(*SimpleScanner).indentlevel() != 0 {
+		t.Errorf("expected %v, got %v", 0, news.(*SimpleScanner).indentlevel())
+	}
+
+	// scanners not supporting indentation.
+	s = SetTabWidth(NewStreamScanner(strings.NewReader(text)), 4)
+	if _, _, err := Parse(y, s); err == nil {
+		t.Errorf("expected error")
+	} else if ref := "parse error at offset 0, indentation not supported by scanner *parsec.StreamScanner"; err.Error() != ref {
+		t.Errorf("expected %q, got %q", ref, err)
+	}
+}
+
+func sprint(node ParsecNode) string {
+	if ns, ok := node.([]ParsecNode); ok {
+		out := "["
+		for i, n := range ns {
+			if i > 0 {
+				out += " "
+			}
+			out += sprint(n)
+		}
+		return out + "]"
+	}
+	return node.(string)
+}
@@ -7,6 +7,7 @@ import "encoding/json"
 import "io/ioutil"
 import "fmt"
 import "reflect"
+import "sync"
 import "testing"
 
 import "github.com/prataprc/goparsec"
@@ -82,6 +83,32 @@ func TestJson(t *testing.T) {
 	}
 }
 
+func TestParallel(t *testing.T) {
+	var mediumVal []interface{}
+
+	mediumText, err := ioutil.ReadFile("./../testdata/medium.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	json.Unmarshal(mediumText, &mediumVal)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				v, _ := Y(NewJSONScanner(mediumText))
+				if !reflect.DeepEqual(nativeValue(v), mediumVal) {
+					t.Errorf("Mismatch `%v`: %v vs %v", string(mediumText), mediumVal, v)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
 func BenchmarkJSONInt(b *testing.B) {
 	text := []byte(`10000`)
 	for i := 0; i < b.N; i++ {
@@ -0,0 +1,135 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+// Rules referenced via *Parser can be left recursive, directly like,
+//
+//	sum -> sum addop prod | prod
+//
+// or indirectly through other rules referenced via *Parser. When a
+// rule is invoked again at the same cursor position, before its first
+// invocation returns, the inner invocation fails. If the outer
+// invocation succeeds nevertheless, its result is used as seed and
+// the rule is parsed again, this time the inner invocation returns the
+// seed. This is repeated for as long as the rule matches more input
+// than the previous attempt, constructing left associative nodes.
+
+// rulekey identify a rule's invocation at a cursor position.
+type rulekey struct {
+	rule   *Parser
+	cursor int
+	state  int64
+}
+
+// ruleentry track a rule's invocation while it is being parsed.
+type ruleentry struct {
+	rulekey
+	detected bool // rule was invoked again at the same cursor.
+	growing  bool // seed is being grown.
+	node     ParsecNode
+	news     Scanner
+}
+
+// leftrec track the rules being parsed in a parse session, as a stack
+// of invocations. Rules are invoked at or after the cursor of rules
+// being parsed, hence invocations at the same cursor are at the top of
+// the stack, and looking them up is cheap.
+type leftrec struct {
+	stack []ruleentry
+}
+
+// find the invocation for key, return -1 if key is not being parsed.
+func (lr *leftrec) find(key rulekey) int {
+	for i := len(lr.stack) - 1; i >= 0 && lr.stack[i].cursor >= key.cursor; i-- {
+		if lr.stack[i].rulekey == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// push invocation for key, return its index in the stack.
+func (lr *leftrec) push(key rulekey) int {
+	lr.stack = append(lr.stack, ruleentry{rulekey: key})
+	return len(lr.stack) - 1
+}
+
+// pop invocations from index i, including invocations that did not pop
+// due to a panic.
+func (lr *leftrec) pop(i int) {
+	for j := range lr.stack[i:] {
+		lr.stack[i+j] = ruleentry{}
+	}
+	lr.stack = lr.stack[:i]
+}
+
+// parserule invoke the parser referenced by rule, supporting left
+// recursion if scanner s can track a parse session.
+func parserule(rule *Parser, s Scanner) (ParsecNode, Scanner) {
+	sess := sessionof(s)
+	if sess == nil {
+		return (*rule)(s)
+	}
+
+	key := rulekey{rule: rule, cursor: s.GetCursor(), state: stateof(s)}
+	if sess.leftrec == nil {
+		sess.leftrec = &leftrec{}
+	}
+	lr := sess.leftrec
+	if i := lr.find(key); i >= 0 {
+		if entry := &lr.stack[i]; entry.growing {
+			return entry.node, entry.news.Clone()
+		}
+		lr.stack[i].detected = true
+		return nil, s
+	}
+	mkey := memokey{rule: rule, cursor: key.cursor, state: key.state}
+	if sess.memo != nil {
+		if entry, ok := sess.memo.peek(mkey); ok {
+			sess.examine(entry.examined.lo, entry.examined.hi)
+			sess.diagnostics = append(sess.diagnostics, entry.diags...)
+			return entry.node, entry.news.Clone()
+		}
+	}
+
+	i := lr.push(key)
+	defer lr.pop(i)
+
+	// combinators don't modify their input scanner, hence s is the
+	// start for growing the seed.
+	mark := len(sess.diagnostics)
+	saved := sess.track(key.cursor)
+	node, news := (*rule)(s)
+	if !lr.stack[i].detected || node == nil {
+		sess.untrack(saved)
+		return node, news
+	}
+
+	// grow the seed, only diagnostics from the longest match are kept.
+	// stack can be reallocated by nested invocations, hence entry is
+	// accessed by its index.
+	lr.stack[i].growing = true
+	lr.stack[i].node, lr.stack[i].news = node, news
+	for {
+		if sess.memo != nil { // results at cursor may depend on the seed.
+			sess.memo.purge(key.cursor)
+		}
+		diags := append(Diagnostics(nil), sess.diagnostics[mark:]...)
+		sess.discard(mark)
+		node, news = (*rule)(s.Clone())
+		if node == nil || news.GetCursor() <= lr.stack[i].news.GetCursor() {
+			sess.diagnostics = append(sess.diagnostics[:mark], diags...)
+			break
+		}
+		lr.stack[i].node, lr.stack[i].news = node, news
+	}
+	node, news = lr.stack[i].node, lr.stack[i].news
+	examined := sess.untrack(saved)
+	if sess.memo != nil {
+		diags := append(Diagnostics(nil), sess.diagnostics[mark:]...)
+		sess.memo.purge(key.cursor)
+		sess.memo.put(mkey, node, news.Clone(), diags, examined)
+	}
+	return node, news
+}
@@ -0,0 +1,133 @@
+package parsec
+
+import "fmt"
+import "testing"
+
+func TestLeftRecursion(t *testing.T) {
+	var sum Parser
+
+	// sum  -> sum addop prod | prod
+	// prod -> INT
+	addop := OrdChoice(one2one, Atom("+", "ADD"), Atom("-", "SUB"))
+	value := And(
+		func(ns []ParsecNode) ParsecNode { return ns[0].(*Terminal).Value },
+		Int(),
+	)
+	binary := And(
+		func(ns []ParsecNode) ParsecNode {
+			op := ns[1].(*Terminal).Value
+			return fmt.Sprintf("(%v%v%v)", ns[0], op, ns[2])
+		},
+		&sum, addop, value,
+	)
+	sum = OrdChoice(one2one, binary, value)
+	// left recursive rules are to be invoked via reference.
+	y := OrdChoice(one2one, &sum)
+
+	testcases := [][2]string{
+		{"1", "1"},
+		{"1 - 2", "(1-2)"},
+		{"1 - 2 + 3 - 4", "(((1-2)+3)-4)"},
+	}
+	for _, tcase := range testcases {
+		for _, s := range []Scanner{
+			NewScanner([]byte(tcase[0])),
+			Packrat(NewScanner([]byte(tcase[0])), 1000),
+		} {
+			node, news := y(s)
+			if node == nil {
+				t.Errorf("%q failed", tcase[0])
+			} else if node.(string) != tcase[1] {
+				t.Errorf("expected %v, got %v", tcase[1], node)
+			} else if !news.Endof() {
+				t.Errorf("expected end of text for %q", tcase[0])
+			}
+		}
+	}
+
+	// partial match
+	node, s := And(nil, &sum)(NewScanner([]byte("1 - 2 +")))
+	if node == nil {
+		t.Errorf("expected node")
+	} else if ref := "(1-2)"; node.([]ParsecNode)[0].(string) != ref {
+		t.Errorf("expected %v, got %v", ref, node)
+	} else if s.GetCursor() != 5 {
+		t.Errorf("expected %v, got %v", 5, s.GetCursor())
+	}
+}
+
+func TestIndirectLeftRecursion(t *testing.T) {
+	var a, b Parser
+
+	// a -> b "x" | "y"
+	// b -> a "z" | "w"
+	a = OrdChoice(one2one,
+		And(func(ns []ParsecNode) ParsecNode {
+			return fmt.Sprintf("(%vx)", ns[0])
+		}, &b, Atom("x", "X")),
+		And(func(_ []ParsecNode) ParsecNode { return "y" }, Atom("y", "Y")),
+	)
+	b = OrdChoice(one2one,
+		And(func(ns []ParsecNode) ParsecNode {
+			return fmt.Sprintf("(%vz)", ns[0])
+		}, &a, Atom("z", "Z")),
+		And(func(_ []ParsecNode) ParsecNode { return "w" }, Atom("w", "W")),
+	)
+	y := And(nil, &a, End())
+
+	testcases := [][2]string{
+		{"y", "y"},
+		{"wx", "(wx)"},
+		{"yzx", "((yz)x)"},
+		{"yzxzx", "((((yz)x)z)x)"},
+	}
+	for _, tcase := range testcases {
+		for _, s := range []Scanner{
+			NewScanner([]byte(tcase[0])),
+			Packrat(NewScanner([]byte(tcase[0])), 1000),
+		} {
+			node, _ := y(s)
+			if node == nil {
+				t.Errorf("%q failed", tcase[0])
+				continue
+			}
+			ns := node.([]ParsecNode)
+			if ns[0].(string) != tcase[1] {
+				t.Errorf("expected %v, got %v", tcase[1], ns[0])
+			}
+		}
+	}
+}
+
+func TestASTLeftRecursion(t *testing.T) {
+	var sum Parser
+
+	ast := NewAST("testleftrec", 100)
+	addop := ast.OrdChoice("addop", nil, Atom("+", "ADD"), Atom("-", "SUB"))
+	sum = ast.OrdChoice("sum", nil, ast.And("binary", nil, &sum, addop, Int()), Int())
+
+	y := ast.OrdChoice("expr", nil, &sum)
+	node, s := ast.Parsewith(y, NewScanner([]byte("1 - 2 + 3")))
+	if node == nil {
+		t.Fatalf("expected node")
+	} else if !s.Endof() {
+		t.Errorf("expected end of text")
+	} else if node.GetValue() != "1-2+3" {
+		t.Errorf("unexpected %v", node.GetValue())
+	}
+	// left associative: ((1 - 2) + 3)
+	children := node.GetChildren()
+	if len(children) != 3 {
+		t.Fatalf("unexpected %v", len(children))
+	} else if x := children[0].GetValue(); x != "1-2" {
+		t.Errorf("expected %v, got %v", "1-2", x)
+	} else if x := children[1].GetValue(); x != "+" {
+		t.Errorf("expected %v, got %v", "+", x)
+	} else if x := children[0].GetChildren()[0].GetName(); x != "INT" {
+		t.Errorf("expected %v, got %v", "INT", x)
+	}
+}
+
+func one2one(ns []ParsecNode) ParsecNode {
+	return ns[0]
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "context"
+import "errors"
+
+// ErrMaxSteps is returned by a parse that exceeds Limits.MaxSteps.
+var ErrMaxSteps = errors.New("parsec: maximum number of parse steps exceeded")
+
+// ErrMaxDepth is returned by a parse that exceeds Limits.MaxDepth.
+var ErrMaxDepth = errors.New("parsec: maximum parse depth exceeded")
+
+// Limits to guard a parse against pathological input, say from
+// untrusted sources. Zero value for a field means no limit.
+type Limits struct {
+	// Context aborts the parse when it is done, parse shall return
+	// Context.Err().
+	Context context.Context
+	// MaxSteps is the maximum number of combinator invocations, parse
+	// shall return ErrMaxSteps when exceeded.
+	MaxSteps int64
+	// MaxDepth is the maximum nesting of combinator invocations, parse
+	// shall return ErrMaxDepth when exceeded.
+	MaxDepth int
+}
+
+// Limit the parse using scanner s, and return s. Limits are checked
+// every time a combinator is invoked, and the parse is
+// aborted when any of the limit is hit. Use Parse function, or
+// AST.Parsewith method, to learn the reason. Scanners that do not
+// support parse session, like custom scanners, are returned as is.
+//
+//	s := parsec.Limit(parsec.NewScanner(text), parsec.Limits{
+//		Context: ctx, MaxSteps: 1000000, MaxDepth: 1000,
+//	})
+//	node, s, err := parsec.Parse(Y, s)
+func Limit(s Scanner, limits Limits) Scanner {
+	if sess := sessionof(s); sess != nil {
+		sess.limits = &limiter{Limits: limits}
+	}
+	return s
+}
+
+// limited wraps combinators that are not memoized, to check the
+// limits for the parse, if any.
+func limited(parser Parser) Parser {
+	return func(s Scanner) (ParsecNode, Scanner) {
+		if lim := enterlimits(s); lim != nil {
+			defer lim.exit()
+		}
+		return parser(s)
+	}
+}
+
+// enterlimits check the limits for the parse using s, if any, before
+// invoking a parser. Return the limiter to exit after the parser
+// returns, nil if there are no limits.
+func enterlimits(s Scanner) *limiter {
+	if sess := sessionof(s); sess != nil && sess.limits != nil {
+		sess.limits.enter()
+		return sess.limits
+	}
+	return nil
+}
+
+// abort is raised when a parse hits a limit.
+type abort struct {
+	err error
+}
+
+// limiter tracks the limits for a parse.
+type limiter struct {
+	Limits
+	steps int64
+	depth int
+}
+
+func (lim *limiter) reset() {
+	lim.steps, lim.depth = 0, 0
+}
+
+// enter is called before invoking a parser.
+func (lim *limiter) enter() {
+	lim.steps++
+	lim.depth++
+	if lim.MaxSteps > 0 && lim.steps > lim.MaxSteps {
+		panic(abort{ErrMaxSteps})
+	} else if lim.MaxDepth > 0 && lim.depth > lim.MaxDepth {
+		panic(abort{ErrMaxDepth})
+	} else if lim.Context != nil && lim.steps%64 == 1 {
+		if err := lim.Context.Err(); err != nil {
+			panic(abort{err})
+		}
+	}
+}
+
+// exit is called after the parser returns.
+func (lim *limiter) exit() {
+	lim.depth--
+}
@@ -0,0 +1,130 @@
+package parsec
+
+import "context"
+import "strings"
+import "testing"
+
+func TestLimits(t *testing.T) {
+	var count int
+	y, _ := makenestedy(&count)
+	text := []byte(strings.Repeat("(", 4) + "x" + strings.Repeat(")", 4))
+
+	// within limits
+	s := Limit(NewScanner(text), Limits{
+		Context: context.Background(), MaxSteps: 100000, MaxDepth: 100,
+	})
+	if node, news, err := Parse(y, s); err != nil {
+		t.Errorf("unexpected %v", err)
+	} else if node == nil || !news.Endof() {
+		t.Errorf("expected end of text")
+	}
+
+	// steps
+	s = Limit(NewScanner(text), Limits{MaxSteps: 100})
+	if node, news, err := Parse(y, s); err != ErrMaxSteps {
+		t.Errorf("expected %v, got %v", ErrMaxSteps, err)
+	} else if node != nil {
+		t.Errorf("unexpected %v", node)
+	} else if news.GetCursor() != 0 {
+		t.Errorf("expected %v, got %v", 0, news.GetCursor())
+	}
+	// limits are reset for every parse.
+	s = Limit(NewScanner([]byte("x")), Limits{MaxSteps: 20})
+	for i := 0; i < 2; i++ {
+		if _, _, err := Parse(y, s); err != nil {
+			t.Errorf("unexpected %v", err)
+		}
+	}
+
+	// depth
+	s = Limit(NewScanner(text), Limits{MaxDepth: 10})
+	if _, _, err := Parse(y, s); err != ErrMaxDepth {
+		t.Errorf("expected %v, got %v", ErrMaxDepth, err)
+	}
+
+	// context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s = Limit(NewScanner(text), Limits{Context: ctx})
+	if _, _, err := Parse(y, s); err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+
+	// limits are not recovered by Recover.
+	y = Kleene(nil, Recover(y, Atom(";", "SEMI")))
+	s = Limit(NewScanner(text), Limits{MaxDepth: 10})
+	if _, _, err := Parse(y, s); err != ErrMaxDepth {
+		t.Errorf("expected %v, got %v", ErrMaxDepth, err)
+	}
+}
+
+func TestASTLimits(t *testing.T) {
+	var expr Parser
+
+	ast := NewAST("testlimits", 100)
+	group := ast.And("group", nil, Atom("(", "OPEN"), &expr, Atom(")", "CLOSE"))
+	expr = ast.OrdChoice("expr", nil, group, Atom("x", "X"))
+
+	text := []byte(strings.Repeat("(", 100) + "x" + strings.Repeat(")", 100))
+	s := Limit(NewScanner(text), Limits{MaxDepth: 50})
+	if node, _ := ast.Parsewith(expr, s); node != nil {
+		t.Errorf("unexpected %v", node)
+	} else if ast.Error() != ErrMaxDepth {
+		t.Errorf("expected %v, got %v", ErrMaxDepth, ast.Error())
+	}
+
+	s = Limit(NewScanner(text), Limits{MaxDepth: 1000})
+	if node, _ := ast.Parsewith(expr, s); node == nil {
+		t.Errorf("unexpected %v", ast.Error())
+	}
+}
+
+func TestPrattLimits(t *testing.T) {
+	y := NewPratt(Int()).
+		Parens(Atom("(", "OPEN"), Atom(")", "CLOSE")).
+		Infix(Atom("+", "ADD"), 10, AssocLeft, nil).
+		Prefix(Atom("-", "NEG"), 30, nil).
+		Parser()
+
+	texts := []string{
+		strings.Repeat("(", 100000) + "1" + strings.Repeat(")", 100000),
+		strings.Repeat("-", 100000) + "1",
+		strings.Repeat("1 + (", 100000) + "1" + strings.Repeat(")", 100000),
+	}
+	for _, text := range texts {
+		s := Limit(NewScanner([]byte(text)), Limits{MaxDepth: 1000})
+		if node, _, err := Parse(y, s); err != ErrMaxDepth {
+			t.Errorf("expected %v, got %v", ErrMaxDepth, err)
+		} else if node != nil {
+			t.Errorf("unexpected %v", node)
+		}
+	}
+
+	text := strings.Repeat("(", 10) + "-1" + strings.Repeat(")", 10)
+	s := Limit(NewScanner([]byte(text)), Limits{MaxDepth: 1000})
+	if node, _, err := Parse(y, s); err != nil {
+		t.Errorf("unexpected %v", err)
+	} else if node == nil {
+		t.Errorf("expected node")
+	}
+}
+
+func TestPredicateLimits(t *testing.T) {
+	var y Parser
+
+	// every level of nesting is through Lookahead and Skip.
+	y = OrdChoice(nil,
+		And(nil, Lookahead(Atom("(", "OPEN")), Skip(Atom("(", "OPEN")),
+			Skip(&y), Atom(")", "CLOSE")),
+		Atom("x", "X"),
+	)
+	text := []byte(strings.Repeat("(", 100) + "x" + strings.Repeat(")", 100))
+	s := Limit(NewScanner(text), Limits{MaxDepth: 1000})
+	if _, _, err := Parse(y, s); err != nil {
+		t.Errorf("unexpected %v", err)
+	}
+	s = Limit(NewScanner(text), Limits{MaxDepth: 250})
+	if _, _, err := Parse(y, s); err != ErrMaxDepth {
+		t.Errorf("expected %v, got %v", ErrMaxDepth, err)
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "container/list"
+import "sync/atomic"
+
+// MemoStats is a snapshot of packrat cache statistics for a parse.
+type MemoStats struct {
+	Size      int   // maximum number of entries in the cache.
+	Entries   int   // number of entries currently in the cache.
+	Hits      int64 // lookups served from the cache.
+	Misses    int64 // lookups that missed the cache.
+	Evictions int64 // least recently used entries evicted from cache.
+}
+
+// HitRate return the fraction of lookups served from the cache.
+func (stats MemoStats) HitRate() float64 {
+	if total := stats.Hits + stats.Misses; total > 0 {
+		return float64(stats.Hits) / float64(total)
+	}
+	return 0
+}
+
+// Packrat enables memoization of combinator results for the parse using
+// scanner s, and return s. Result of every combinator, both package
+// level and AST, along with the resulting scanner is remembered for
+// each cursor position, so that backtracking does not parse the same
+// input with the same combinator again. Atmost `size` results are
+// remembered, least recently used results are evicted. Results are
+// forgotten when a new parse is started with s, or its clones.
+//
+// Memoized nodes are shared, hence callbacks should not modify the
+// nodes passed to them. Scanners that do not support parse session,
+// like custom scanners, are returned as is.
+func Packrat(s Scanner, size int) Scanner {
+	if sess := sessionof(s); sess != nil {
+		sess.memo = newmemotable(size)
+	}
+	return s
+}
+
+// PackratStats return the cache statistics for the parse using
+// scanner s, or any of its clones.
+func PackratStats(s Scanner) MemoStats {
+	if sess := sessionof(s); sess != nil && sess.memo != nil {
+		stats := sess.memo.stats
+		stats.Size, stats.Entries = sess.memo.size, sess.memo.lru.Len()
+		return stats
+	}
+	return MemoStats{}
+}
+
+// parserids generate a unique id for every combinator.
+var parserids int64
+
+// memoize wraps every combinator to check the limits and, look up and
+// populate the packrat cache, if enabled for the parse. Records the
+// combinator's structure, if not nil. Diagnostics recovered by a failed
+// combinator are discarded.
+func memoize(st *structure, parser Parser) Parser {
+	id := atomic.AddInt64(&parserids, 1)
+	return func(s Scanner) (ParsecNode, Scanner) {
+		sess := sessionof(s)
+		if sess == nil {
+			if _, ok := s.(probe); ok && st != nil {
+				return st, s
+			}
+			return parser(s)
+		} else if sess.limits != nil {
+			sess.limits.enter()
+			defer sess.limits.exit()
+		}
+		if sess.memo == nil {
+			mark := len(sess.diagnostics)
+			node, news := parser(s)
+			if node == nil && len(sess.diagnostics) > mark {
+				sess.discard(mark)
+			}
+			return node, news
+		}
+		mark := len(sess.diagnostics)
+		key := memokey{id: id, cursor: s.GetCursor(), state: stateof(s)}
+		if entry, ok := sess.memo.get(key); ok {
+			sess.examine(entry.examined.lo, entry.examined.hi)
+			if entry.node == nil {
+				return nil, s
+			}
+			sess.diagnostics = append(sess.diagnostics, entry.diags...)
+			return entry.node, entry.news.Clone()
+		}
+		saved := sess.track(key.cursor)
+		node, news := parser(s)
+		examined := sess.untrack(saved)
+		if node == nil {
+			sess.discard(mark)
+			sess.memo.put(key, nil, nil, nil, examined)
+			return nil, news
+		}
+		diags := append(Diagnostics(nil), sess.diagnostics[mark:]...)
+		sess.memo.put(key, node, news.Clone(), diags, examined)
+		return node, news
+	}
+}
+
+func ismemoized(s Scanner) bool {
+	sess := sessionof(s)
+	return sess != nil && sess.memo != nil
+}
+
+type memokey struct {
+	id     int64   // combinator id, or
+	rule   *Parser // left recursive rule.
+	cursor int
+	state  int64 // version of user state and indentation levels.
+}
+
+type memoentry struct {
+	key      memokey
+	node     ParsecNode
+	news     Scanner
+	diags    Diagnostics // recovered while parsing node.
+	examined extent      // text examined while parsing node.
+}
+
+// memotable is a bounded LRU cache of parser results, indexed by cursor.
+type memotable struct {
+	size    int
+	entries map[int]map[memokey]*list.Element
+	lru     *list.List
+	stats   MemoStats
+	carried bool // entries carried over from a previous parse.
+}
+
+func newmemotable(size int) *memotable {
+	return &memotable{
+		size:    size,
+		entries: make(map[int]map[memokey]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+func (mt *memotable) get(key memokey) (*memoentry, bool) {
+	if elem, ok := mt.entries[key.cursor][key]; ok {
+		mt.lru.MoveToFront(elem)
+		mt.stats.Hits++
+		return elem.Value.(*memoentry), true
+	}
+	mt.stats.Misses++
+	return nil, false
+}
+
+// peek is same as get, but a miss is not accounted for.
+func (mt *memotable) peek(key memokey) (*memoentry, bool) {
+	if elem, ok := mt.entries[key.cursor][key]; ok {
+		mt.lru.MoveToFront(elem)
+		mt.stats.Hits++
+		return elem.Value.(*memoentry), true
+	}
+	return nil, false
+}
+
+func (mt *memotable) put(
+	key memokey, node ParsecNode, news Scanner, diags Diagnostics,
+	examined extent) {
+
+	if mt.size <= 0 {
+		return
+	}
+	if elem, ok := mt.entries[key.cursor][key]; ok {
+		mt.lru.Remove(elem)
+	}
+	entry := &memoentry{
+		key: key, node: node, news: news, diags: diags, examined: examined,
+	}
+	mt.push(entry)
+}
+
+// push entry to the front of lru list, evicting the least recently
+// used entries if the cache is full.
+func (mt *memotable) push(entry *memoentry) {
+	entries, ok := mt.entries[entry.key.cursor]
+	if !ok {
+		entries = make(map[memokey]*list.Element)
+		mt.entries[entry.key.cursor] = entries
+	}
+	entries[entry.key] = mt.lru.PushFront(entry)
+	for mt.lru.Len() > mt.size {
+		mt.remove(mt.lru.Back())
+		mt.stats.Evictions++
+	}
+}
+
+// purge all entries at cursor.
+func (mt *memotable) purge(cursor int) {
+	for _, elem := range mt.entries[cursor] {
+		mt.lru.Remove(elem)
+	}
+	delete(mt.entries, cursor)
+}
+
+func (mt *memotable) remove(elem *list.Element) {
+	key := elem.Value.(*memoentry).key
+	mt.lru.Remove(elem)
+	if entries := mt.entries[key.cursor]; len(entries) > 1 {
+		delete(entries, key)
+	} else {
+		delete(mt.entries, key.cursor)
+	}
+}
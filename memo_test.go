@@ -0,0 +1,151 @@
+package parsec
+
+import "strings"
+import "testing"
+
+func TestPackrat(t *testing.T) {
+	var count int
+	y, x := makenestedy(&count)
+	text := []byte(strings.Repeat("(", 8) + "x" + strings.Repeat(")", 8))
+
+	// without packrat
+	node, s := y(NewScanner(text))
+	if node == nil {
+		t.Fatalf("expected node")
+	} else if !s.Endof() {
+		t.Errorf("expected end of text")
+	} else if stats := PackratStats(s); stats != (MemoStats{}) {
+		t.Errorf("unexpected %v", stats)
+	}
+	nomemo := *x
+
+	// with packrat
+	*x = 0
+	node, s = y(Packrat(NewScanner(text), 1000))
+	if node == nil {
+		t.Fatalf("expected node")
+	} else if !s.Endof() {
+		t.Errorf("expected end of text")
+	} else if *x >= nomemo {
+		t.Errorf("expected less than %v, got %v", nomemo, *x)
+	} else if *x != 1 {
+		t.Errorf("expected %v, got %v", 1, *x)
+	}
+	stats := PackratStats(s)
+	if stats.Hits == 0 {
+		t.Errorf("expected hits")
+	} else if stats.Size != 1000 {
+		t.Errorf("expected %v, got %v", 1000, stats.Size)
+	} else if stats.Entries == 0 || stats.Evictions != 0 {
+		t.Errorf("unexpected %+v", stats)
+	} else if rate := stats.HitRate(); rate <= 0 || rate >= 1 {
+		t.Errorf("unexpected %v", rate)
+	}
+
+	// bounded cache
+	node, s = y(Packrat(NewScanner(text), 4))
+	if node == nil {
+		t.Fatalf("expected node")
+	}
+	stats = PackratStats(s)
+	if stats.Entries != 4 {
+		t.Errorf("expected %v, got %v", 4, stats.Entries)
+	} else if stats.Evictions == 0 {
+		t.Errorf("expected evictions")
+	}
+
+	// failure is memoized as well.
+	*x = 0
+	node, s = y(Packrat(NewScanner([]byte("((y))")), 1000))
+	if node != nil {
+		t.Errorf("expected nil")
+	} else if s.GetCursor() != 0 {
+		t.Errorf("expected %v, got %v", 0, s.GetCursor())
+	} else if *x != 3 { // once for each cursor position
+		t.Errorf("expected %v, got %v", 3, *x)
+	}
+}
+
+func TestPackratParseAgain(t *testing.T) {
+	var count int
+	y, x := makenestedy(&count)
+	text := []byte(strings.Repeat("(", 4) + "x" + strings.Repeat(")", 4))
+
+	// every parse start with an empty cache.
+	s := Packrat(NewScanner(text), 1000)
+	for i := 1; i <= 2; i++ {
+		node, news, err := Parse(y, s)
+		if err != nil {
+			t.Fatalf("unexpected %v", err)
+		} else if node == nil || !news.Endof() {
+			t.Fatalf("expected node till end of text")
+		} else if *x != i {
+			t.Errorf("expected %v, got %v", i, *x)
+		}
+	}
+
+	// results of previous parse are not reused.
+	token := "x"
+	y = func(s Scanner) (ParsecNode, Scanner) {
+		return Atom(token, "TOKEN")(s)
+	}
+	y = OrdChoice(nil, y)
+	s = Packrat(NewScanner([]byte("y")), 1000)
+	if node, _, _ := Parse(y, s); node != nil {
+		t.Errorf("unexpected %v", node)
+	}
+	token = "y"
+	if node, _, err := Parse(y, s); err != nil {
+		t.Errorf("unexpected %v", err)
+	} else if node == nil {
+		t.Errorf("expected node")
+	}
+}
+
+func TestASTPackrat(t *testing.T) {
+	var expr Parser
+	var count int
+
+	ast := NewAST("testpackrat", 100).SetPackrat(1000)
+	x := countparser(Atom("x", "X"), &count)
+	group := ast.And("group", nil, Atom("(", "OPEN"), &expr, Atom(")", "CLOSE"))
+	term := ast.OrdChoice("term", nil, group, x)
+	expr = ast.OrdChoice("expr", nil,
+		ast.And("add", nil, term, Atom("+", "PLUS"), &expr),
+		ast.And("sub", nil, term, Atom("-", "MINUS"), &expr),
+		term,
+	)
+	text := "((x+x)-(x))"
+	node, s := ast.Parsewith(expr, NewScanner([]byte(text)))
+	if node == nil {
+		t.Fatalf("expected node")
+	} else if node.GetValue() != text {
+		t.Errorf("expected %v, got %v", text, node.GetValue())
+	} else if !s.Endof() {
+		t.Errorf("expected end of text")
+	} else if count != 3 {
+		t.Errorf("expected %v, got %v", 3, count)
+	} else if PackratStats(s).Hits == 0 {
+		t.Errorf("expected hits")
+	}
+}
+
+func makenestedy(count *int) (Parser, *int) {
+	var expr Parser
+	x := countparser(Atom("x", "X"), count)
+	group := And(nil, Atom("(", "OPEN"), &expr, Atom(")", "CLOSE"))
+	term := OrdChoice(nil, group, x)
+	expr = OrdChoice(nil,
+		And(nil, term, Atom("+", "PLUS"), &expr),
+		And(nil, term, Atom("-", "MINUS"), &expr),
+		term,
+	)
+	return expr, count
+}
+
+func countparser(parser Parser, count *int) Parser {
+	return func(s Scanner) (ParsecNode, Scanner) {
+		*count++
+		return parser(s)
+	}
+}
@@ -16,13 +16,47 @@ type Parser func(Scanner) (ParsecNode, Scanner)
 // combinators like And, OrdChoice, Many etc.. can match input string,
 // it is still possible to fail them via nodify callback function, by
 // returning nil. This very useful in cases when,
-//  * lookahead matching is required.
 //  * an exceptional cases for regex pattern.
 //
+// For lookahead matching use Lookahead and NotFollowedBy combinators.
+//
 // Note that some combinators like KLEENE shall not interpret the return
 // value from Nodify callback.
 type Nodify func([]ParsecNode) ParsecNode
 
+// First is a Nodify callback returning the first ParsecNode, or nil if
+// there are none. Useful with OrdChoice, Maybe and Between to pass the
+// matched node as is.
+func First(ns []ParsecNode) ParsecNode {
+	if len(ns) == 0 {
+		return nil
+	}
+	return ns[0]
+}
+
+// Parse execute the root parser, y, with scanner s. Return the root
+// node, if success, and scanner with remaining input. If y fails to
+// match the input text, return a *ParseError pointing at the furthest
+// position reached in the input text.
+//
+// If a parser fails after passing a Cut, the *ParseError raised by the
+// enclosing And combinator is returned, with scanner s. If y succeeds
+// after recovering from errors, via Recover combinator, the root node
+// is returned along with Diagnostics as error. If the parse is aborted
+// on hitting Limits, the corresponding error is returned.
+func Parse(y Parser, s Scanner) (node ParsecNode, news Scanner, err error) {
+	defer resetsession(s)()
+	defer func() {
+		if r := recover(); r != nil {
+			node, news, err = nil, s, recoverparse(r)
+		}
+	}()
+	if node, news = y(s); node == nil {
+		return nil, news, newParseError(news)
+	}
+	return node, news, diagnosticsof(news)
+}
+
 // And combinator accepts a list of `Parser`, or reference to a
 // parser, that must match the input string, atleast until the
 // last Parser argument. Return a parser function that can further be
@@ -32,15 +66,25 @@ type Nodify func([]ParsecNode) ParsecNode
 // ParsecNode is constructed by matching parser, will be passed
 // as argument to Nodify callback. Even if one of the input
 // parser function fails, And will fail without consuming the input.
+// Unless the failing parser is preceded by a Cut, refer to Cut for
+// details.
 func And(callb Nodify, parsers ...interface{}) Parser {
-	return func(s Scanner) (ParsecNode, Scanner) {
+	st := &structure{kind: "And", parsers: parsers}
+	return memoize(st, func(s Scanner) (ParsecNode, Scanner) {
 		var ns = make([]ParsecNode, 0, len(parsers))
 		var n ParsecNode
+		var cut bool
 		news := s.Clone()
 		for _, parser := range parsers {
 			n, news = doParse(parser, news)
-			if n == nil {
+			if n == nil && cut && abortable(news) {
+				panic(newParseError(news))
+			} else if n == nil {
 				return nil, s
+			} else if isvoid(n) {
+				_, ok := n.(cutnode)
+				cut = cut || ok
+				continue
 			}
 			ns = append(ns, n)
 		}
@@ -48,7 +92,7 @@ func And(callb Nodify, parsers ...interface{}) Parser {
 			return node, news
 		}
 		return nil, s
-	}
+	})
 }
 
 // OrdChoice combinator accepts a list of `Parser`, or
@@ -62,7 +106,8 @@ func And(callb Nodify, parsers ...interface{}) Parser {
 // match the input, then OrdChoice will fail without consuming
 // any input.
 func OrdChoice(callb Nodify, parsers ...interface{}) Parser {
-	return func(s Scanner) (ParsecNode, Scanner) {
+	st := &structure{kind: "OrdChoice", parsers: parsers}
+	return memoize(st, func(s Scanner) (ParsecNode, Scanner) {
 		for _, parser := range parsers {
 			if n, news := doParse(parser, s.Clone()); n != nil {
 				if node := docallback(callb, []ParsecNode{n}); node != nil {
@@ -71,7 +116,54 @@ func OrdChoice(callb Nodify, parsers ...interface{}) Parser {
 			}
 		}
 		return nil, s
-	}
+	})
+}
+
+// LongestChoice combinator accepts a list of `Parser`, or reference to
+// a parser, and tries all of them on the input string. Return a parser
+// function that picks the parser that matched the longest input, hence
+// the order of parsers does not matter, like Float and Int.
+//
+// Nodes from all parsers that matched the longest input, in the order
+// of parsers, are passed as argument to Nodify callback, that is more
+// than one element in []ParsecNode argument indicates an ambiguity,
+// which the callback can resolve or report. Return value of callback
+// is used as the matched node. If none of the parsers match the input,
+// then LongestChoice will fail without consuming any input.
+func LongestChoice(callb Nodify, parsers ...interface{}) Parser {
+	st := &structure{kind: "LongestChoice", parsers: parsers}
+	return memoize(st, func(s Scanner) (ParsecNode, Scanner) {
+		if ns, news := longest(doParse, parsers, s); ns != nil {
+			if node := docallback(callb, ns); node != nil {
+				return node, news
+			}
+		}
+		return nil, s
+	})
+}
+
+// Permutation combinator accepts a list of `Parser`, or reference to a
+// parser, where each parser must match the input string once, in any
+// order. Wrap a parser with Maybe combinator to make it optional, like,
+//
+//	Permutation(nil, where, Maybe(nil, orderby), Maybe(nil, limit))
+//
+// ParsecNode from each parser is passed as argument to Nodify callback,
+// in the order of parsers regardless of the order in the input string.
+// Parsers are tried in order and the first one to consume input is
+// picked, parsers that did not match are tried once more at the end.
+// If any of the parser fails to match, Permutation will fail without
+// consuming the input.
+func Permutation(callb Nodify, parsers ...interface{}) Parser {
+	st := &structure{kind: "Permutation", parsers: parsers}
+	return memoize(st, func(s Scanner) (ParsecNode, Scanner) {
+		if ns, news := permute(doParse, parsers, s); ns != nil {
+			if node := docallback(callb, ns); node != nil {
+				return node, news
+			}
+		}
+		return nil, s
+	})
 }
 
 // Kleene combinator accepts two parsers, or reference to
@@ -92,6 +184,10 @@ func OrdChoice(callb Nodify, parsers ...interface{}) Parser {
 // single match for opScan, then []ParsecNode of ZERO length
 // will be passed as argument to Nodify callback. Kleene
 // combinator will never fail.
+//
+// If an iteration matches without consuming any input, which would
+// otherwise loop forever, the parse is aborted with a *ParseError, use
+// Analyze to find such repetitions in a grammar.
 func Kleene(callb Nodify, parsers ...interface{}) Parser {
 	var opScan, sepScan interface{}
 	switch l := len(parsers); l {
@@ -102,11 +198,13 @@ func Kleene(callb Nodify, parsers ...interface{}) Parser {
 	default:
 		panic(fmt.Errorf("kleene parser doesn't accept %v parsers", l))
 	}
-	return func(s Scanner) (ParsecNode, Scanner) {
+	st := &structure{kind: "Kleene", parsers: nonnil(opScan, sepScan)}
+	return memoize(st, func(s Scanner) (ParsecNode, Scanner) {
 		var n ParsecNode
 		ns := make([]ParsecNode, 0)
 		news := s.Clone()
 		for {
+			from := news.GetCursor()
 			if n, news = doParse(opScan, news); n == nil {
 				break
 			}
@@ -116,9 +214,12 @@ func Kleene(callb Nodify, parsers ...interface{}) Parser {
 					break
 				}
 			}
+			if news.GetCursor() == from {
+				NoProgress(news)
+			}
 		}
 		return docallback(callb, ns), news
-	}
+	})
 }
 
 // Many combinator accepts two parsers, or reference to
@@ -141,6 +242,10 @@ func Kleene(callb Nodify, parsers ...interface{}) Parser {
 // passed as argument to Nodify callback. If there is not a
 // single match for opScan, then Many will fail without
 // consuming the input.
+//
+// If an iteration matches without consuming any input, which would
+// otherwise loop forever, the parse is aborted with a *ParseError, use
+// Analyze to find such repetitions in a grammar.
 func Many(callb Nodify, parsers ...interface{}) Parser {
 	var opScan, sepScan interface{}
 	switch l := len(parsers); l {
@@ -151,11 +256,13 @@ func Many(callb Nodify, parsers ...interface{}) Parser {
 	default:
 		panic(fmt.Errorf("many parser doesn't accept %v parsers", l))
 	}
-	return func(s Scanner) (ParsecNode, Scanner) {
+	st := &structure{kind: "Many", parsers: nonnil(opScan, sepScan)}
+	return memoize(st, func(s Scanner) (ParsecNode, Scanner) {
 		var n ParsecNode
 		ns := make([]ParsecNode, 0)
 		news := s.Clone()
 		for {
+			from := news.GetCursor()
 			if n, news = doParse(opScan, news); n == nil {
 				break
 			}
@@ -165,6 +272,9 @@ func Many(callb Nodify, parsers ...interface{}) Parser {
 					break
 				}
 			}
+			if news.GetCursor() == from {
+				NoProgress(news)
+			}
 		}
 		if len(ns) > 0 {
 			if node := docallback(callb, ns); node != nil {
@@ -172,7 +282,7 @@ func Many(callb Nodify, parsers ...interface{}) Parser {
 			}
 		}
 		return nil, s
-	}
+	})
 }
 
 // ManyUntil combinator accepts three parsers, or references to
@@ -192,6 +302,10 @@ func Many(callb Nodify, parsers ...interface{}) Parser {
 // passed as argument to Nodify callback. If there is not a
 // single match for opScan, then ManyUntil will fail without
 // consuming the input.
+//
+// If an iteration matches without consuming any input, which would
+// otherwise loop forever, the parse is aborted with a *ParseError, use
+// Analyze to find such repetitions in a grammar.
 func ManyUntil(callb Nodify, parsers ...interface{}) Parser {
 	var opScan, sepScan, untilScan interface{}
 	switch l := len(parsers); l {
@@ -202,7 +316,8 @@ func ManyUntil(callb Nodify, parsers ...interface{}) Parser {
 	default:
 		panic(fmt.Errorf("ManyUntil parser doesn't accept %v parsers", l))
 	}
-	return func(s Scanner) (ParsecNode, Scanner) {
+	st := &structure{kind: "ManyUntil", parsers: nonnil(untilScan, opScan, sepScan)}
+	return memoize(st, func(s Scanner) (ParsecNode, Scanner) {
 		var n ParsecNode
 		var e ParsecNode
 		ns := make([]ParsecNode, 0)
@@ -211,6 +326,7 @@ func ManyUntil(callb Nodify, parsers ...interface{}) Parser {
 			if e, _ = doParse(untilScan, news.Clone()); e != nil {
 				break
 			}
+			from := news.GetCursor()
 			if n, news = doParse(opScan, news); n == nil {
 				break
 			}
@@ -220,6 +336,9 @@ func ManyUntil(callb Nodify, parsers ...interface{}) Parser {
 					break
 				}
 			}
+			if news.GetCursor() == from {
+				NoProgress(news)
+			}
 		}
 		if len(ns) > 0 {
 			if node := docallback(callb, ns); node != nil {
@@ -227,14 +346,15 @@ func ManyUntil(callb Nodify, parsers ...interface{}) Parser {
 			}
 		}
 		return nil, s
-	}
+	})
 }
 
 // Maybe combinator accepts a single parser, or reference to
 // a parser, and tries to match the input stream with it. If
 // parser fails to match the input, returns MaybeNone.
 func Maybe(callb Nodify, parser interface{}) Parser {
-	return func(s Scanner) (ParsecNode, Scanner) {
+	st := &structure{kind: "Maybe", parsers: []interface{}{parser}}
+	return memoize(st, func(s Scanner) (ParsecNode, Scanner) {
 		n, news := doParse(parser, s.Clone())
 		if n == nil {
 			return MaybeNone("missing"), s
@@ -243,7 +363,231 @@ func Maybe(callb Nodify, parser interface{}) Parser {
 			return node, news
 		}
 		return MaybeNone("missing"), s
+	})
+}
+
+// Repeat combinator accepts one or two parsers, or reference to
+// parsers, namely opScan and sepScan, to match atleast min and atmost
+// max opScan, separated by sepScan if supplied. A negative max means
+// there is no upper bound. Unlike Kleene combinator, a trailing sepScan
+// is not consumed. Same as `{min,max}` quantifier in regular
+// expressions, for example four hex digits:
+//
+//	Repeat(nil, 4, 4, Token(`[0-9a-fA-F]`, "HEXDIGIT"))
+//
+// For every successful match of opScan, the returned ParsecNode from
+// matching parser will be accumulated and passed as argument to Nodify
+// callback. If opScan matches less than min times, Repeat will fail
+// without consuming the input. If there is no upper bound, like Kleene
+// combinator, an iteration that does not consume any input aborts the
+// parse.
+func Repeat(callb Nodify, min, max int, parsers ...interface{}) Parser {
+	opScan, sepScan := repeatargs(min, max, parsers)
+	st := &structure{
+		kind: "Repeat", parsers: nonnil(opScan, sepScan), min: min, max: max,
+	}
+	return memoize(st, func(s Scanner) (ParsecNode, Scanner) {
+		ns, news := repeat(doParse, min, max, opScan, sepScan, s)
+		if ns != nil {
+			if node := docallback(callb, ns); node != nil {
+				return node, news
+			}
+		}
+		return nil, s
+	})
+}
+
+// Times combinator is same as Repeat combinator to match exactly n
+// times.
+func Times(callb Nodify, n int, parsers ...interface{}) Parser {
+	return Repeat(callb, n, n, parsers...)
+}
+
+// AtLeast combinator is same as Repeat combinator to match n or more
+// times.
+func AtLeast(callb Nodify, n int, parsers ...interface{}) Parser {
+	return Repeat(callb, n, -1, parsers...)
+}
+
+// AtMost combinator is same as Repeat combinator to match zero to n
+// times.
+func AtMost(callb Nodify, n int, parsers ...interface{}) Parser {
+	return Repeat(callb, 0, n, parsers...)
+}
+
+// SepBy combinator accepts two parsers, or reference to parsers, namely
+// parser and sep, to match zero or more parser separated by sep. Unlike
+// Kleene combinator, a trailing sep is not consumed. ParsecNode from
+// every match of parser is accumulated and passed as argument to Nodify
+// callback, nodes matched by sep are ignored. SepBy combinator will
+// never fail, but like Kleene combinator, an iteration that does not
+// consume any input aborts the parse.
+func SepBy(callb Nodify, parser, sep interface{}) Parser {
+	st := &structure{kind: "SepBy", parsers: []interface{}{parser, sep}}
+	return memoize(st, func(s Scanner) (ParsecNode, Scanner) {
+		ns, news := separated(doParse, parser, sep, trailNone, s)
+		return docallback(callb, ns), news
+	})
+}
+
+// SepEndBy combinator is same as SepBy combinator, but an optional
+// trailing sep is consumed, like in JSON5 arrays or Go composite
+// literals.
+func SepEndBy(callb Nodify, parser, sep interface{}) Parser {
+	st := &structure{kind: "SepEndBy", parsers: []interface{}{parser, sep}}
+	return memoize(st, func(s Scanner) (ParsecNode, Scanner) {
+		ns, news := separated(doParse, parser, sep, trailOptional, s)
+		return docallback(callb, ns), news
+	})
+}
+
+// EndBy combinator is same as SepBy combinator, but every match of
+// parser shall be followed by sep, like statements terminated by `;`.
+func EndBy(callb Nodify, parser, sep interface{}) Parser {
+	st := &structure{kind: "EndBy", parsers: []interface{}{parser, sep}}
+	return memoize(st, func(s Scanner) (ParsecNode, Scanner) {
+		ns, news := separated(doParse, parser, sep, trailMandatory, s)
+		return docallback(callb, ns), news
+	})
+}
+
+// Between combinator accepts three parsers, or reference to parsers,
+// to match open, parser and close in sequence. Only the ParsecNode
+// matched by parser is passed as argument to Nodify callback, nodes
+// matched by open and close delimiters are ignored.
+func Between(callb Nodify, open, close, parser interface{}) Parser {
+	st := &structure{kind: "Between", parsers: []interface{}{open, parser, close}}
+	return memoize(st, func(s Scanner) (ParsecNode, Scanner) {
+		n, news := between(doParse, open, close, parser, s)
+		if n != nil {
+			if node := docallback(callb, []ParsecNode{n}); node != nil {
+				return node, news
+			}
+		}
+		return nil, s
+	})
+}
+
+// Chainl1 combinator accepts two parsers, or reference to parsers,
+// namely operand and op, to match one or more operand separated by op,
+// like `a - b - c`. Matches are folded from left, that is, Nodify
+// callback is called with left node, op node and right node, and its
+// return value is the left node for the next op. If operand does not
+// match even once, or if callback return nil, Chainl1 will fail
+// without consuming the input.
+func Chainl1(callb Nodify, operand, op interface{}) Parser {
+	build := func(_ Scanner, ns []ParsecNode) ParsecNode {
+		return docallback(callb, ns)
+	}
+	st := &structure{kind: "Chainl1", parsers: []interface{}{operand, op}}
+	return memoize(st, func(s Scanner) (ParsecNode, Scanner) {
+		return chain(doParse, build, operand, op, false, s)
+	})
+}
+
+// Chainr1 combinator is same as Chainl1 combinator, but matches are
+// folded from right, like `a ^ b ^ c`.
+func Chainr1(callb Nodify, operand, op interface{}) Parser {
+	build := func(_ Scanner, ns []ParsecNode) ParsecNode {
+		return docallback(callb, ns)
 	}
+	st := &structure{kind: "Chainr1", parsers: []interface{}{operand, op}}
+	return memoize(st, func(s Scanner) (ParsecNode, Scanner) {
+		return chain(doParse, build, operand, op, true, s)
+	})
+}
+
+// Skip combinator accepts a single parser, or reference to a parser,
+// and matches the input stream, but discards the matched node. And
+// combinator shall not include the node returned by Skip in its list
+// of ParsecNode.
+func Skip(parser interface{}) Parser {
+	st := &structure{kind: "Skip", parsers: []interface{}{parser}}
+	return record(st, limited(func(s Scanner) (ParsecNode, Scanner) {
+		if n, news := doParse(parser, s.Clone()); n != nil {
+			return voidnode{}, news
+		}
+		return nil, s
+	}))
+}
+
+// Lookahead combinator accepts a single parser, or reference to a
+// parser, and succeeds if the parser matches the input stream, without
+// consuming the input. Same as `&` predicate in PEG. And combinator
+// shall not include the node returned by Lookahead in its list of
+// ParsecNode.
+func Lookahead(parser interface{}) Parser {
+	st := &structure{kind: "Lookahead", parsers: []interface{}{parser}}
+	return record(st, limited(func(s Scanner) (ParsecNode, Scanner) {
+		if predicate(s, func(s Scanner) ParsecNode {
+			n, _ := doParse(parser, s)
+			return n
+		}) != nil {
+			return voidnode{}, s
+		}
+		return nil, s
+	}))
+}
+
+// NotFollowedBy combinator accepts a single parser, or reference to a
+// parser, and succeeds if the parser fails to match the input stream,
+// without consuming the input. Same as `!` predicate in PEG, useful for
+// keyword boundaries and reserved word exclusion. And combinator shall
+// not include the node returned by NotFollowedBy in its list of
+// ParsecNode.
+func NotFollowedBy(parser interface{}) Parser {
+	st := &structure{kind: "NotFollowedBy", parsers: []interface{}{parser}}
+	return record(st, limited(func(s Scanner) (ParsecNode, Scanner) {
+		if notfollowedby(s, func(s Scanner) ParsecNode {
+			n, _ := doParse(parser, s)
+			return n
+		}) {
+			return voidnode{}, s
+		}
+		return nil, s
+	}))
+}
+
+// Cut combinator always succeed without consuming the input. Once
+// passed, any subsequent failure within the enclosing And combinator
+// shall not backtrack to try other alternatives, instead the parse is
+// aborted with a *ParseError pointing at the failure. For example,
+//
+//	ifstmt := And(nil, Atom("if", "IF"), Cut(), expr, block)
+//
+// once `if` keyword is matched, failure to match expr or block is a
+// hard error. Parsers using Cut shall be invoked via Parse function or
+// AST.Parsewith method, that return the error. When invoked directly,
+// failure after Cut fails the enclosing And like any other failure.
+// Within Lookahead and NotFollowedBy, failure after Cut fails the
+// predicated parser.
+func Cut() Parser {
+	st := &structure{kind: "Cut"}
+	return record(st, limited(func(s Scanner) (ParsecNode, Scanner) {
+		return cutnode{}, s
+	}))
+}
+
+// Recover combinator accepts a parser, or reference to a parser, and a
+// sync parser, or reference to a parser, to recover from syntax errors.
+// If parser fails to match the input, the failure is recorded as a
+// *ParseError and input is skipped until, and including, a match for
+// sync parser or until end of text. The skipped text is returned as a
+// Terminal named "ERROR". For example,
+//
+//	stmts := Kleene(nil, Recover(stmt, Atom(";", "SEMI")))
+//
+// shall continue with the next statement after a malformed statement.
+// Use Parse function, or AST.Parsewith method, to obtain the recorded
+// errors as Diagnostics. Recover fails only at the end of text.
+func Recover(parser, sync interface{}) Parser {
+	st := &structure{kind: "Recover", parsers: []interface{}{parser, sync}}
+	return memoize(st, func(s Scanner) (ParsecNode, Scanner) {
+		return recoverwith(s, "ERROR",
+			func(s Scanner) (ParsecNode, Scanner) { return doParse(parser, s) },
+			func(s Scanner) (ParsecNode, Scanner) { return doParse(sync, s) },
+		)
+	})
 }
 
 //----------------
@@ -255,7 +599,7 @@ func doParse(parser interface{}, s Scanner) (ParsecNode, Scanner) {
 	case Parser:
 		return p(s)
 	case *Parser:
-		return (*p)(s)
+		return parserule(p, s)
 	default:
 		panic(fmt.Errorf("type of parser `%T` not supported", parser))
 	}
@@ -267,3 +611,309 @@ func docallback(callb Nodify, ns []ParsecNode) ParsecNode {
 	}
 	return ns
 }
+
+const (
+	trailNone      = iota // separator after the last item is not consumed.
+	trailOptional         // separator after the last item is consumed.
+	trailMandatory        // every item must be followed by separator.
+)
+
+// longest return nodes from all parsers that matched the longest
+// input, in the order of parsers.
+func longest(
+	doparse func(interface{}, Scanner) (ParsecNode, Scanner),
+	parsers []interface{}, s Scanner) ([]ParsecNode, Scanner) {
+
+	var ns []ParsecNode
+	var news Scanner
+	for _, parser := range parsers {
+		n, next := doparse(parser, s.Clone())
+		if n == nil {
+			continue
+		} else if news == nil || next.GetCursor() > news.GetCursor() {
+			ns, news = []ParsecNode{n}, next
+		} else if next.GetCursor() == news.GetCursor() {
+			ns = append(ns, n)
+		}
+	}
+	return ns, news
+}
+
+// permute match each of the parsers once, in any order, and return
+// their nodes in the order of parsers. Return nil if any of the parser
+// fails.
+func permute(
+	doparse func(interface{}, Scanner) (ParsecNode, Scanner),
+	parsers []interface{}, s Scanner) ([]ParsecNode, Scanner) {
+
+	ns, news := make([]ParsecNode, len(parsers)), s.Clone()
+	for matched := 0; matched < len(parsers); matched++ {
+		i, n, next := -1, ParsecNode(nil), news
+		for j, parser := range parsers {
+			if ns[j] != nil {
+				continue
+			}
+			n, next = doparse(parser, news.Clone())
+			if n != nil && next.GetCursor() > news.GetCursor() {
+				i = j
+				break
+			}
+		}
+		if i < 0 {
+			break
+		}
+		ns[i], news = n, next
+	}
+	// parsers that can match without consuming input, like Maybe.
+	for j, parser := range parsers {
+		if ns[j] != nil {
+			continue
+		} else if ns[j], news = doparse(parser, news); ns[j] == nil {
+			return nil, s
+		}
+	}
+	return ns, news
+}
+
+func repeatargs(min, max int, parsers []interface{}) (op, sep interface{}) {
+	if min < 0 || (max >= 0 && max < min) {
+		panic(fmt.Errorf("repeat parser doesn't accept range {%v,%v}", min, max))
+	}
+	switch l := len(parsers); l {
+	case 1:
+		return parsers[0], nil
+	case 2:
+		return parsers[0], parsers[1]
+	default:
+		panic(fmt.Errorf("repeat parser doesn't accept %v parsers", l))
+	}
+}
+
+// repeat match atleast min and atmost max parser, separated by sep if
+// not nil, return nil if parser matches less than min times.
+func repeat(
+	doparse func(interface{}, Scanner) (ParsecNode, Scanner),
+	min, max int, parser, sep interface{}, s Scanner) ([]ParsecNode, Scanner) {
+
+	ns, news := make([]ParsecNode, 0), s.Clone()
+	for max < 0 || len(ns) < max {
+		from := news
+		if sep != nil && len(ns) > 0 {
+			var m ParsecNode
+			if m, from = doparse(sep, news.Clone()); m == nil {
+				break
+			}
+		}
+		n, next := doparse(parser, from.Clone())
+		if n == nil {
+			break
+		}
+		ns = append(ns, n)
+		if max < 0 && next.GetCursor() == news.GetCursor() {
+			NoProgress(next)
+		}
+		news = next
+	}
+	if len(ns) < min {
+		return nil, s
+	}
+	return ns, news
+}
+
+// separated match zero or more parser separated by sep.
+func separated(
+	doparse func(interface{}, Scanner) (ParsecNode, Scanner),
+	parser, sep interface{}, trail int, s Scanner) ([]ParsecNode, Scanner) {
+
+	ns, news, from := make([]ParsecNode, 0), s.Clone(), s.Clone()
+	for {
+		n, next := doparse(parser, from.Clone())
+		if n == nil {
+			break
+		}
+		m, after := doparse(sep, next.Clone())
+		if m == nil && trail == trailMandatory {
+			break
+		}
+		ns = append(ns, n)
+		if news = next; m == nil {
+			break
+		} else if trail != trailNone {
+			news = after
+		}
+		if after.GetCursor() == from.GetCursor() {
+			NoProgress(after)
+		}
+		from = after
+	}
+	return ns, news
+}
+
+func between(
+	doparse func(interface{}, Scanner) (ParsecNode, Scanner),
+	open, close, parser interface{}, s Scanner) (ParsecNode, Scanner) {
+
+	n, news := doparse(open, s.Clone())
+	if n == nil {
+		return nil, s
+	} else if n, news = doparse(parser, news); n == nil {
+		return nil, s
+	} else if m, news := doparse(close, news); m != nil {
+		return n, news
+	}
+	return nil, s
+}
+
+// chain match one or more operand separated by op, folding from left,
+// or from right if `right` is true.
+func chain(
+	doparse func(interface{}, Scanner) (ParsecNode, Scanner),
+	build func(Scanner, []ParsecNode) ParsecNode,
+	operand, op interface{}, right bool, s Scanner) (ParsecNode, Scanner) {
+
+	n, news := doparse(operand, s.Clone())
+	if n == nil {
+		return nil, s
+	}
+	operands, ops := []ParsecNode{n}, []ParsecNode{}
+	for {
+		o, next := doparse(op, news.Clone())
+		if o == nil {
+			break
+		} else if n, next = doparse(operand, next); n == nil {
+			break
+		}
+		operands, ops, news = append(operands, n), append(ops, o), next
+	}
+
+	if right {
+		node := operands[len(operands)-1]
+		for i := len(ops) - 1; i >= 0 && node != nil; i-- {
+			node = build(news, []ParsecNode{operands[i], ops[i], node})
+		}
+		if node == nil {
+			return nil, s
+		}
+		return node, news
+	}
+	node := operands[0]
+	for i := 0; i < len(ops) && node != nil; i++ {
+		node = build(news, []ParsecNode{node, ops[i], operands[i+1]})
+	}
+	if node == nil {
+		return nil, s
+	}
+	return node, news
+}
+
+// recoverparse return the error for a parse that was aborted, either by
+// a failure after Cut or by hitting Limits.
+func recoverparse(r interface{}) error {
+	if x, ok := r.(abort); ok {
+		return x.err
+	}
+	return recovercut(r)
+}
+
+// recovercut return the *ParseError raised by a failure after Cut,
+// other panics are propagated as is.
+func recovercut(r interface{}) *ParseError {
+	if r == nil {
+		return nil
+	} else if perr, ok := r.(*ParseError); ok {
+		return perr
+	}
+	panic(r)
+}
+
+// recoverwith attempt parse on s, on failure skip input until sync
+// matches and return the skipped text as Terminal `name`.
+func recoverwith(
+	s Scanner, name string,
+	parse, sync func(Scanner) (ParsecNode, Scanner)) (ParsecNode, Scanner) {
+
+	node, news, perr := tryparse(s, parse)
+	if node != nil {
+		return node, news
+	} else if s.Endof() {
+		return nil, s
+	}
+
+	var skipped []byte
+	news = s.Clone()
+	for !news.Endof() {
+		if n, next := sync(news.Clone()); n != nil {
+			news = next
+			break
+		}
+		var token []byte
+		token, news = news.Match(`^(?s).`)
+		skipped = append(skipped, token...)
+	}
+	if sess := sessionof(s); sess != nil {
+		sess.diagnostics = append(sess.diagnostics, perr)
+	}
+	return NewTerminal(name, string(skipped), s.GetCursor()), news
+}
+
+// tryparse attempt parse on s, on failure return the *ParseError, also
+// for failures after Cut. Failure is not accounted as furthest failure
+// for the rest of the parse.
+func tryparse(
+	s Scanner, parse func(Scanner) (ParsecNode, Scanner)) (
+	node ParsecNode, news Scanner, perr *ParseError) {
+
+	if sess := sessionof(s); sess != nil {
+		failcursor, expected := sess.snapshot()
+		sess.restore(-1, make(map[string]bool))
+		depth := sess.track(s.GetCursor())
+		defer func() {
+			sess.untrack(depth)
+			if perr == nil {
+				sess.merge(failcursor, expected)
+			} else {
+				sess.restore(failcursor, expected)
+			}
+		}()
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			node, news, perr = nil, nil, recovercut(r)
+		}
+	}()
+	if node, news = parse(s.Clone()); node == nil {
+		perr = newParseError(s)
+	}
+	return node, news, perr
+}
+
+// notfollowedby return true if parse fails on s. Terminals expected by
+// parse are not the reason for a parse error, hence forgotten.
+func notfollowedby(s Scanner, parse func(Scanner) ParsecNode) bool {
+	if sess := sessionof(s); sess != nil {
+		failcursor, expected := sess.snapshot()
+		defer sess.restore(failcursor, expected)
+	}
+	return predicate(s, parse) == nil
+}
+
+// predicate attempt parse on s without consuming the input, return nil
+// on failure, also for failures after Cut. Diagnostics recovered by
+// parse are discarded, they shall be reported by the parser consuming
+// the input.
+func predicate(s Scanner, parse func(Scanner) ParsecNode) (node ParsecNode) {
+	if sess := sessionof(s); sess != nil {
+		mark, depth := len(sess.diagnostics), sess.track(s.GetCursor())
+		defer func() {
+			sess.untrack(depth)
+			sess.discard(mark)
+		}()
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			recovercut(r)
+			node = nil
+		}
+	}()
+	return parse(s.Clone())
+}
@@ -2,6 +2,7 @@ package parsec
 
 import "fmt"
 import "reflect"
+import "strings"
 import "testing"
 
 var _ = fmt.Sprintf("dummy")
@@ -258,3 +259,434 @@ func TestForwardReference(t *testing.T) {
 func allTokens(ns []ParsecNode) ParsecNode {
 	return ns
 }
+
+func TestLookahead(t *testing.T) {
+	// match identifier only if followed by `(`
+	y := And(nil, Ident(), Lookahead(Atom("(", "OPEN")))
+	node, s := y(NewScanner([]byte("fn (")))
+	if node == nil {
+		t.Errorf("expected node")
+	} else if nodes := node.([]ParsecNode); len(nodes) != 1 {
+		t.Errorf("unexpected %v", nodes)
+	} else if s.GetCursor() != 2 {
+		t.Errorf("expected %v, got %v", 2, s.GetCursor())
+	}
+	node, s = y(NewScanner([]byte("fn )")))
+	if node != nil {
+		t.Errorf("unexpected %v", node)
+	} else if s.GetCursor() != 0 {
+		t.Errorf("expected %v, got %v", 0, s.GetCursor())
+	}
+	_, _, err := Parse(y, NewScanner([]byte("fn )")))
+	if x := err.(*ParseError).Expected; !reflect.DeepEqual(x, []string{"OPEN"}) {
+		t.Errorf("unexpected %v", x)
+	}
+
+}
+
+func TestNotFollowedBy(t *testing.T) {
+	// keyword boundary
+	keyword := And(nil, Atom("if", "IF"), NotFollowedBy(TokenExact(`\w`, "W")))
+	y := OrdChoice(nil, keyword, Ident())
+	node, s := y(NewScanner([]byte("if x")))
+	if t1 := node.([]ParsecNode)[0].([]ParsecNode)[0].(*Terminal); t1.Name != "IF" {
+		t.Errorf("expected %v, got %v", "IF", t1.Name)
+	} else if s.GetCursor() != 2 {
+		t.Errorf("expected %v, got %v", 2, s.GetCursor())
+	}
+	node, s = y(NewScanner([]byte("iffy")))
+	if t1 := node.([]ParsecNode)[0].(*Terminal); t1.Name != "IDENT" {
+		t.Errorf("expected %v, got %v", "IDENT", t1.Name)
+	} else if s.GetCursor() != 4 {
+		t.Errorf("expected %v, got %v", 4, s.GetCursor())
+	}
+
+	// reserved word exclusion, errors are not about excluded words.
+	ident := And(nil, NotFollowedBy(keyword), Ident())
+	_, _, err := Parse(And(nil, ident, End()), NewScanner([]byte("if")))
+	perr := err.(*ParseError)
+	if perr.Cursor != 0 {
+		t.Errorf("expected %v, got %v", 0, perr.Cursor)
+	} else if perr.Expected != nil {
+		t.Errorf("unexpected %v", perr.Expected)
+	}
+	node, _ = ident(NewScanner([]byte("iffy")))
+	if nodes := node.([]ParsecNode); len(nodes) != 1 {
+		t.Errorf("unexpected %v", nodes)
+	}
+}
+
+func TestCut(t *testing.T) {
+	expr := OrdChoice(nil, Int(), Ident())
+	ifstmt := And(nil, Atom("if", "IF"), Cut(), expr, Atom(";", "SEMI"))
+	var count int
+	alt := countparser(And(nil, Ident(), Atom(";", "SEMI")), &count)
+	y := OrdChoice(nil, ifstmt, alt)
+
+	// committed sequence
+	node, s, err := Parse(y, NewScanner([]byte("if 10;")))
+	if err != nil {
+		t.Errorf("unexpected %v", err)
+	} else if nodes := node.([]ParsecNode)[0].([]ParsecNode); len(nodes) != 3 {
+		t.Errorf("unexpected %v", nodes)
+	} else if !s.Endof() {
+		t.Errorf("expected end of text")
+	}
+
+	// failure after cut shall not try the next alternative.
+	_, _, err = Parse(y, NewScanner([]byte("if 10")))
+	perr, ok := err.(*ParseError)
+	if !ok {
+		t.Fatalf("unexpected %v", err)
+	} else if count != 0 {
+		t.Errorf("expected %v, got %v", 0, count)
+	} else if perr.Cursor != 5 {
+		t.Errorf("expected %v, got %v", 5, perr.Cursor)
+	} else if ref := []string{"SEMI"}; !reflect.DeepEqual(perr.Expected, ref) {
+		t.Errorf("expected %v, got %v", ref, perr.Expected)
+	}
+
+	// failure before cut shall backtrack.
+	_, s, err = Parse(y, NewScanner([]byte("x;")))
+	if err != nil {
+		t.Errorf("unexpected %v", err)
+	} else if count != 1 {
+		t.Errorf("expected %v, got %v", 1, count)
+	} else if !s.Endof() {
+		t.Errorf("expected end of text")
+	}
+
+	// failure within nested combinators.
+	y = Kleene(nil, Maybe(nil, ifstmt))
+	_, s, err = Parse(y, NewScanner([]byte("if 1; if ;")))
+	if perr, ok := err.(*ParseError); !ok {
+		t.Errorf("unexpected %v", err)
+	} else if perr.Cursor != 9 {
+		t.Errorf("expected %v, got %v", 9, perr.Cursor)
+	} else if s.GetCursor() != 0 {
+		t.Errorf("expected %v, got %v", 0, s.GetCursor())
+	}
+
+	// invoked directly, failure after cut fails the sequence.
+	node, s = Kleene(nil, ifstmt)(NewScanner([]byte("if 1; if ;")))
+	if nodes := node.([]ParsecNode); len(nodes) != 1 {
+		t.Errorf("unexpected %v", nodes)
+	} else if s.GetCursor() != 5 {
+		t.Errorf("expected %v, got %v", 5, s.GetCursor())
+	}
+
+	// failure after cut within predicates.
+	ab := And(nil, Atom("a", "A"), Cut(), Atom("b", "B"))
+	y = And(nil, NotFollowedBy(ab), Ident())
+	if node, _, err := Parse(y, NewScanner([]byte("ac"))); err != nil {
+		t.Errorf("unexpected %v", err)
+	} else if node == nil {
+		t.Errorf("expected node")
+	}
+	y = OrdChoice(nil, And(nil, Lookahead(ab), Ident()), Ident())
+	if node, _, err := Parse(y, NewScanner([]byte("ac"))); err != nil {
+		t.Errorf("unexpected %v", err)
+	} else if node == nil {
+		t.Errorf("expected node")
+	}
+
+	// other panics are propagated.
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("expected panic")
+		}
+	}()
+	Parse(And(nil, Cut(), 10), NewScanner([]byte("x")))
+}
+
+func TestRecover(t *testing.T) {
+	semi := Atom(";", "SEMI")
+	stmt := And(nil, Ident(), Atom("=", "EQUAL"), Int(), semi)
+	y := And(nil, Kleene(nil, Recover(stmt, semi)), End())
+
+	text := "a = 1;\nb = ;\nc = 3;\nd 4;"
+	for _, s := range []Scanner{
+		NewScanner([]byte(text)),
+		Packrat(NewScanner([]byte(text)), 1000),
+	} {
+		node, news, err := Parse(y, s)
+		if node == nil {
+			t.Fatalf("unexpected %v", err)
+		} else if !news.Endof() {
+			t.Errorf("expected end of text")
+		}
+		stmts := node.([]ParsecNode)[0].([]ParsecNode)
+		if len(stmts) != 4 {
+			t.Fatalf("expected %v, got %v", 4, len(stmts))
+		} else if x := stmts[1].(*Terminal); x.Name != "ERROR" {
+			t.Errorf("expected %v, got %v", "ERROR", x.Name)
+		} else if x.Value != "\nb =" || x.Position != 6 {
+			t.Errorf("unexpected %q at %v", x.Value, x.Position)
+		}
+		diags, ok := err.(Diagnostics)
+		if !ok || len(diags) != 2 {
+			t.Fatalf("unexpected %v", err)
+		} else if diags[0].Lineno != 2 || diags[0].Column != 5 {
+			t.Errorf("unexpected %v", diags[0])
+		} else if ref := []string{"INT"}; !reflect.DeepEqual(diags[0].Expected, ref) {
+			t.Errorf("expected %v, got %v", ref, diags[0].Expected)
+		} else if diags[1].Lineno != 4 || diags[1].Column != 3 {
+			t.Errorf("unexpected %v", diags[1])
+		}
+		ref := "parse error at line 2 col 5, expected INT\n" +
+			"parse error at line 4 col 3, expected EQUAL"
+		if err.Error() != ref {
+			t.Errorf("expected %q, got %q", ref, err.Error())
+		}
+	}
+
+	// diagnostics from alternatives that failed are discarded.
+	y = OrdChoice(nil, And(nil, Recover(stmt, semi), Atom("x", "X")), stmt)
+	node, _, err := Parse(y, NewScanner([]byte("a = 1;")))
+	if node == nil {
+		t.Errorf("expected node")
+	} else if err != nil {
+		t.Errorf("unexpected %v", err)
+	}
+
+	// diagnostics from predicates are discarded.
+	stmts := Kleene(nil, Recover(stmt, semi))
+	y = And(nil, Lookahead(stmts), NotFollowedBy(End()), stmts)
+	_, _, err = Parse(y, NewScanner([]byte("a = 1; b = ; c = 3;")))
+	if diags, ok := err.(Diagnostics); !ok || len(diags) != 1 {
+		t.Errorf("unexpected %v", err)
+	}
+
+	// recover from failure after cut.
+	stmt = And(nil, Ident(), Cut(), Atom("=", "EQUAL"), Int(), semi)
+	y = Kleene(nil, Recover(stmt, semi))
+	node, _, err = Parse(y, NewScanner([]byte("a 1; b = 2;")))
+	if nodes := node.([]ParsecNode); len(nodes) != 2 {
+		t.Errorf("unexpected %v", nodes)
+	} else if diags := err.(Diagnostics); diags[0].Cursor != 2 {
+		t.Errorf("expected %v, got %v", 2, diags[0].Cursor)
+	}
+}
+
+func TestSepBy(t *testing.T) {
+	comma := Atom(",", "COMMA")
+	testcases := []struct {
+		y      Parser
+		text   string
+		count  int
+		cursor int
+	}{
+		{SepBy(nil, Int(), comma), "", 0, 0},
+		{SepBy(nil, Int(), comma), "1, 2, 3", 3, 7},
+		{SepBy(nil, Int(), comma), "1, 2,", 2, 4},
+		{SepEndBy(nil, Int(), comma), "1, 2, 3", 3, 7},
+		{SepEndBy(nil, Int(), comma), "1, 2,", 2, 5},
+		{SepEndBy(nil, Int(), comma), "1, 2, x", 2, 5},
+		{EndBy(nil, Int(), comma), "1, 2,", 2, 5},
+		{EndBy(nil, Int(), comma), "1, 2, 3", 2, 5},
+		{EndBy(nil, Int(), comma), "1", 0, 0},
+	}
+	for _, tcase := range testcases {
+		node, s := tcase.y(NewScanner([]byte(tcase.text)))
+		if nodes := node.([]ParsecNode); len(nodes) != tcase.count {
+			t.Errorf("%q expected %v, got %v", tcase.text, tcase.count, len(nodes))
+		} else if s.GetCursor() != tcase.cursor {
+			t.Errorf("%q expected %v, got %v", tcase.text, tcase.cursor, s.GetCursor())
+		}
+	}
+}
+
+func TestBetween(t *testing.T) {
+	comma := Atom(",", "COMMA")
+	y := Between(nil, Atom("[", "OPEN"), Atom("]", "CLOSE"), SepEndBy(nil, Int(), comma))
+	node, s := y(NewScanner([]byte("[1, 2,]")))
+	if node == nil {
+		t.Fatalf("expected node")
+	} else if nodes := node.([]ParsecNode); len(nodes) != 1 {
+		t.Errorf("unexpected %v", nodes)
+	} else if len(nodes[0].([]ParsecNode)) != 2 {
+		t.Errorf("unexpected %v", nodes[0])
+	} else if !s.Endof() {
+		t.Errorf("expected end of text")
+	}
+	if node, s = y(NewScanner([]byte("[1, 2"))); node != nil {
+		t.Errorf("unexpected %v", node)
+	} else if s.GetCursor() != 0 {
+		t.Errorf("expected %v, got %v", 0, s.GetCursor())
+	}
+}
+
+func TestChain(t *testing.T) {
+	fold := func(ns []ParsecNode) ParsecNode {
+		return fmt.Sprintf("(%v%v%v)", ns[0], ns[1].(*Terminal).Value, ns[2])
+	}
+	operand := And(
+		func(ns []ParsecNode) ParsecNode { return ns[0].(*Terminal).Value },
+		Int(),
+	)
+	sub := Atom("-", "SUB")
+	testcases := []struct {
+		y      Parser
+		text   string
+		ref    string
+		cursor int
+	}{
+		{Chainl1(fold, operand, sub), "1", "1", 1},
+		{Chainl1(fold, operand, sub), "1 - 2 - 3", "((1-2)-3)", 9},
+		{Chainl1(fold, operand, sub), "1 - 2 -", "(1-2)", 5},
+		{Chainr1(fold, operand, sub), "1 - 2 - 3", "(1-(2-3))", 9},
+	}
+	for _, tcase := range testcases {
+		node, s := tcase.y(NewScanner([]byte(tcase.text)))
+		if node.(string) != tcase.ref {
+			t.Errorf("expected %v, got %v", tcase.ref, node)
+		} else if s.GetCursor() != tcase.cursor {
+			t.Errorf("expected %v, got %v", tcase.cursor, s.GetCursor())
+		}
+	}
+
+	// nodify callback fails the chain.
+	y := Chainr1(func(_ []ParsecNode) ParsecNode { return nil }, operand, sub)
+	if node, s := y(NewScanner([]byte("1 - 2"))); node != nil {
+		t.Errorf("unexpected %v", node)
+	} else if s.GetCursor() != 0 {
+		t.Errorf("expected %v, got %v", 0, s.GetCursor())
+	}
+	if node, _ := y(NewScanner([]byte("x"))); node != nil {
+		t.Errorf("unexpected %v", node)
+	}
+}
+
+func TestSkip(t *testing.T) {
+	y := And(nil, Skip(Atom("let", "LET")), Ident(), Skip(Atom("=", "EQUAL")), Int())
+	node, s := y(NewScanner([]byte("let x = 10")))
+	if nodes := node.([]ParsecNode); len(nodes) != 2 {
+		t.Errorf("unexpected %v", nodes)
+	} else if !s.Endof() {
+		t.Errorf("expected end of text")
+	}
+	if node, _ = y(NewScanner([]byte("x = 10"))); node != nil {
+		t.Errorf("unexpected %v", node)
+	}
+}
+
+func TestRepeat(t *testing.T) {
+	hex := Token(`[0-9a-fA-F]`, "HEX")
+	octet := Token(`[0-9]{1,3}`, "OCTET")
+	dot := Atom(".", "DOT")
+	testcases := []struct {
+		y      Parser
+		text   string
+		count  int
+		cursor int
+	}{
+		{Times(nil, 4, hex), "00fF", 4, 4},
+		{Times(nil, 4, hex), "00fF1", 4, 4},
+		{Times(nil, 4, hex), "00f", -1, 0},
+		{Repeat(nil, 4, 4, octet, dot), "10.0.0.1", 4, 8},
+		{Repeat(nil, 4, 4, octet, dot), "10.0.0.1.2", 4, 8},
+		{Repeat(nil, 4, 4, octet, dot), "10.0.0.", -1, 0},
+		{Repeat(nil, 1, 2, octet, dot), "10.0.", 2, 4},
+		{AtLeast(nil, 2, hex), "abcx", 3, 3},
+		{AtLeast(nil, 2, hex), "ax", -1, 0},
+		{AtMost(nil, 2, hex), "abc", 2, 2},
+		{AtMost(nil, 2, hex), "x", 0, 0},
+		{AtMost(nil, 2, Maybe(nil, hex)), "x", 2, 0},
+	}
+	for _, tcase := range testcases {
+		node, s := tcase.y(NewScanner([]byte(tcase.text)))
+		if tcase.count < 0 && node != nil {
+			t.Errorf("%q unexpected %v", tcase.text, node)
+		} else if tcase.count >= 0 && len(node.([]ParsecNode)) != tcase.count {
+			t.Errorf("%q expected %v, got %v", tcase.text, tcase.count, node)
+		} else if s.GetCursor() != tcase.cursor {
+			t.Errorf("%q expected %v, got %v", tcase.text, tcase.cursor, s.GetCursor())
+		}
+	}
+
+	// invalid range
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("expected panic")
+		}
+	}()
+	Repeat(nil, 2, 1, hex)
+}
+
+func TestLongestChoice(t *testing.T) {
+	y := LongestChoice(nil, Atom(">", "GT"), Atom(">=", "GE"), Atom(">>", "SHR"))
+	testcases := [][2]string{{">", "GT"}, {">=", "GE"}, {">>=", "SHR"}}
+	for _, tcase := range testcases {
+		node, _ := y(NewScanner([]byte(tcase[0])))
+		if ns := node.([]ParsecNode); len(ns) != 1 {
+			t.Errorf("unexpected %v", ns)
+		} else if name := ns[0].(*Terminal).Name; name != tcase[1] {
+			t.Errorf("expected %v, got %v", tcase[1], name)
+		}
+	}
+	if node, s := y(NewScanner([]byte("<"))); node != nil {
+		t.Errorf("unexpected %v", node)
+	} else if s.GetCursor() != 0 {
+		t.Errorf("expected %v, got %v", 0, s.GetCursor())
+	}
+
+	// ambiguity
+	var ambiguous int
+	pick := func(ns []ParsecNode) ParsecNode {
+		if len(ns) > 1 {
+			ambiguous++
+		}
+		return ns[0]
+	}
+	y = LongestChoice(pick, Int(), Float(), Token(`[0-9]+`, "DIGITS"))
+	node, s := y(NewScanner([]byte("10.5")))
+	if name := node.(*Terminal).Name; name != "FLOAT" {
+		t.Errorf("expected %v, got %v", "FLOAT", name)
+	} else if !s.Endof() || ambiguous != 0 {
+		t.Errorf("unexpected %v %v", s.GetCursor(), ambiguous)
+	}
+	node, _ = y(NewScanner([]byte("10")))
+	if name := node.(*Terminal).Name; name != "INT" {
+		t.Errorf("expected %v, got %v", "INT", name)
+	} else if ambiguous != 1 {
+		t.Errorf("expected %v, got %v", 1, ambiguous)
+	}
+}
+
+func TestPermutation(t *testing.T) {
+	option := func(name string) Parser {
+		return And(
+			func(ns []ParsecNode) ParsecNode { return name },
+			Atom("--"+name, strings.ToUpper(name)), Int(),
+		)
+	}
+	y := Permutation(nil, option("port"), Maybe(one2one, option("timeout")), option("retry"))
+	testcases := []struct {
+		text string
+		ref  string
+	}{
+		{"--port 80 --timeout 10 --retry 3", "[port timeout retry]"},
+		{"--retry 3 --port 80", "[port missing retry]"},
+		{"--timeout 10 --retry 3 --port 80", "[port timeout retry]"},
+	}
+	for _, tcase := range testcases {
+		node, s := y(NewScanner([]byte(tcase.text)))
+		if node == nil {
+			t.Errorf("%q failed", tcase.text)
+		} else if out := fmt.Sprintf("%v", node); out != tcase.ref {
+			t.Errorf("expected %v, got %v", tcase.ref, out)
+		} else if !s.Endof() {
+			t.Errorf("expected end of text")
+		}
+	}
+
+	// missing required, or repeated items.
+	for _, text := range []string{"--port 80", "--port 80 --port 80 --retry 3"} {
+		node, s := And(nil, y, End())(NewScanner([]byte(text)))
+		if node != nil {
+			t.Errorf("%q unexpected %v", text, node)
+		} else if s.GetCursor() != 0 {
+			t.Errorf("expected %v, got %v", 0, s.GetCursor())
+		}
+	}
+}
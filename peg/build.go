@@ -0,0 +1,129 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package peg
+
+import "fmt"
+import "regexp"
+
+import "github.com/prataprc/goparsec"
+
+// Compile grammar text into parsers composed with combinators from
+// ast, and return the parser for the first rule.
+func Compile(ast *parsec.AST, text []byte) (parsec.Parser, error) {
+	g, err := Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return g.Build(ast, "")
+}
+
+// Build parsers for grammar rules, composed with combinators from ast,
+// and return the parser for `root` rule. If root is empty, first rule
+// is the root. Rules not reachable from root are allowed.
+func (g *Grammar) Build(ast *parsec.AST, root string) (parsec.Parser, error) {
+	if err := g.Validate(); err != nil {
+		return nil, err
+	}
+	if root == "" {
+		root = g.Rules[0].Name
+	}
+	return g.grammar(ast, root).Build(root)
+}
+
+// grammar compose parsers for grammar rules, with combinators from ast,
+// into parsec.Grammar named `name`.
+func (g *Grammar) grammar(ast *parsec.AST, name string) *parsec.Grammar {
+	b := &builder{ast: ast, rules: parsec.NewGrammar(name)}
+	for _, rule := range g.Rules {
+		b.rules.Define(rule.Name, b.compile(rule.Name, rule.Expr, true))
+		b.rules.Start(rule.Name)
+	}
+	return b.rules
+}
+
+type builder struct {
+	ast   *parsec.AST
+	rules *parsec.Grammar
+}
+
+// compile expr into a parser, or reference to a parser. Nodes are
+// named `name`, terminals are named `name` only if `top` is true.
+func (b *builder) compile(name string, expr *Expr, top bool) interface{} {
+	if expr.Label != "" {
+		name, top = expr.Label, true
+	}
+	switch expr.Op {
+	case Sequence:
+		return b.ast.And(name, nil, b.compileall(name, expr.Args)...)
+	case Choice:
+		var callb parsec.ASTNodify
+		if expr.Label != "" {
+			callb = rename
+		}
+		return b.ast.OrdChoice(name, callb, b.compileall(name, expr.Args)...)
+	case Repetition:
+		p := b.compile(name, expr.Args[0], false)
+		switch {
+		case expr.Min == 0 && expr.Max < 0:
+			return b.ast.Kleene(name, nil, p)
+		case expr.Min == 1 && expr.Max < 0:
+			return b.ast.Many(name, nil, p)
+		case expr.Min == 0 && expr.Max == 1:
+			return b.ast.Maybe(name, nil, p)
+		}
+		return b.ast.Repeat(name, nil, expr.Min, expr.Max, p)
+	case Lookahead:
+		return b.ast.Lookahead(name, b.compile(name, expr.Args[0], false))
+	case Not:
+		return b.ast.Not(name, b.compile(name, expr.Args[0], false))
+	case Literal, Regexp:
+		if !top {
+			name = expr.Text
+		}
+		return terminal(name, expr)
+	case Ref:
+		ref := b.rules.Ref(expr.Text)
+		if expr.Label != "" {
+			return b.ast.OrdChoice(name, rename, ref)
+		}
+		return ref
+	}
+	panic(fmt.Errorf("invalid operator %v", expr.Op))
+}
+
+func (b *builder) compileall(name string, exprs []*Expr) []interface{} {
+	parsers := make([]interface{}, 0, len(exprs))
+	for _, expr := range exprs {
+		parsers = append(parsers, b.compile(name, expr, false))
+	}
+	return parsers
+}
+
+// terminal parser for Literal or Regexp expression.
+func terminal(name string, expr *Expr) parsec.Parser {
+	if expr.Op == Literal && !expr.Fold {
+		if expr.Exact {
+			return parsec.AtomExact(expr.Text, name)
+		}
+		return parsec.Atom(expr.Text, name)
+	}
+	if expr.Exact {
+		return parsec.TokenExact(pattern(expr), name)
+	}
+	return parsec.Token(pattern(expr), name)
+}
+
+// pattern for Regexp expression, or Literal expression matched ignoring
+// case.
+func pattern(expr *Expr) string {
+	if expr.Op == Literal {
+		return "(?:(?i:" + regexp.QuoteMeta(expr.Text) + "))"
+	}
+	return "(?:" + expr.Text + ")"
+}
+
+// rename node matched by a named capture.
+func rename(name string, _ parsec.Scanner, node parsec.Queryable) parsec.Queryable {
+	return parsec.Rename(name, node)
+}
@@ -0,0 +1,809 @@
+// Code generated by parsec gen; DO NOT EDIT.
+
+package calc
+
+import "bytes"
+import "regexp"
+import "sort"
+import "unicode/utf8"
+
+import "github.com/prataprc/goparsec"
+
+// Parse text using rule Program, return the root node and the number of
+// bytes consumed. If rule Program fails to match the text, return
+// *parsec.ParseError.
+func Parse(text []byte) (root parsec.Queryable, n int, err error) {
+	p := &parser{text: text, failcursor: -1}
+	defer func() {
+		if r := recover(); r != nil {
+			perr, ok := r.(*parsec.ParseError)
+			if !ok {
+				panic(r)
+			}
+			root, n, err = nil, 0, perr
+		}
+	}()
+	if node, end := p.parseProgram(0); node != nil {
+		return node, end, nil
+	}
+	return nil, 0, p.error()
+}
+
+var ws = regexp.MustCompile(`^[ \t\r\n]+`)
+var re0 = regexp.MustCompile(`^(?:(?s).)`)
+var re1 = regexp.MustCompile(`^(?:(?i:let))`)
+var re2 = regexp.MustCompile(`^(?:[a-z_][a-z0-9_]*)`)
+var re3 = regexp.MustCompile(`^(?:[a-z0-9_])`)
+var re4 = regexp.MustCompile(`^(?:[0-9]+(?:\.[0-9]+)?)`)
+
+// Program <- Statement+ !~"(?s)."
+func (p *parser) parseProgram(cursor int) (parsec.Queryable, int) {
+	var node parsec.Queryable
+	nt, end := parsec.NewNonTerminal("Program"), cursor
+	node, end = p.parseProgram1(end)
+	if node == nil {
+		return nil, cursor
+	} else if node != void {
+		nt.Children = append(nt.Children, node)
+	}
+	node, end = p.parseProgram2(end)
+	if node == nil {
+		return nil, cursor
+	} else if node != void {
+		nt.Children = append(nt.Children, node)
+	}
+	return nt, end
+}
+
+func (p *parser) parseProgram1(cursor int) (parsec.Queryable, int) {
+	var node parsec.Queryable
+	var next int
+	nt, end := parsec.NewNonTerminal("Program"), cursor
+	for {
+		node, next = p.parseStatement(end)
+		if node == nil {
+			break
+		}
+		nt.Children = append(nt.Children, node)
+		if next == end {
+			panic(p.noprogress(end))
+		}
+		end = next
+	}
+	if len(nt.Children) < 1 {
+		return nil, cursor
+	}
+	return nt, end
+}
+
+func (p *parser) parseProgram2(cursor int) (parsec.Queryable, int) {
+	var node parsec.Queryable
+	failcursor, expected := p.snapshot()
+	if at, n := p.match(re0, p.skipws(cursor)); n >= 0 {
+		node, _ = parsec.NewTerminal("(?s).", string(p.text[at:at+n]), at), at+n
+	} else {
+		node = nil
+		p.expect(at, "(?s).")
+	}
+	p.failcursor, p.expected = failcursor, expected
+	if node != nil {
+		return nil, cursor
+	}
+	return void, cursor
+}
+
+// Statement <- kw:"let"i Name "=" Expr ";" / Expr ";"
+func (p *parser) parseStatement(cursor int) (parsec.Queryable, int) {
+	var node parsec.Queryable
+	var end int
+	node, end = p.parseStatement1(cursor)
+	if node != nil {
+		return node, end
+	}
+	node, end = p.parseStatement2(cursor)
+	if node != nil {
+		return node, end
+	}
+	return nil, cursor
+}
+
+func (p *parser) parseStatement1(cursor int) (parsec.Queryable, int) {
+	var node parsec.Queryable
+	nt, end := parsec.NewNonTerminal("Statement"), cursor
+	if at, n := p.match(re1, p.skipws(end)); n >= 0 {
+		node, end = parsec.NewTerminal("kw", string(p.text[at:at+n]), at), at+n
+	} else {
+		node = nil
+		p.expect(at, "kw")
+	}
+	if node == nil {
+		return nil, cursor
+	} else if node != void {
+		nt.Children = append(nt.Children, node)
+	}
+	node, end = p.parseName(end)
+	if node == nil {
+		return nil, cursor
+	} else if node != void {
+		nt.Children = append(nt.Children, node)
+	}
+	if at := p.skipws(end); p.prefix(at, "=") {
+		node, end = parsec.NewTerminal("=", "=", at), at+1
+	} else {
+		node = nil
+		p.expect(at, "=")
+	}
+	if node == nil {
+		return nil, cursor
+	} else if node != void {
+		nt.Children = append(nt.Children, node)
+	}
+	node, end = p.parseExpr(end)
+	if node == nil {
+		return nil, cursor
+	} else if node != void {
+		nt.Children = append(nt.Children, node)
+	}
+	if at := p.skipws(end); p.prefix(at, ";") {
+		node, end = parsec.NewTerminal(";", ";", at), at+1
+	} else {
+		node = nil
+		p.expect(at, ";")
+	}
+	if node == nil {
+		return nil, cursor
+	} else if node != void {
+		nt.Children = append(nt.Children, node)
+	}
+	return nt, end
+}
+
+func (p *parser) parseStatement2(cursor int) (parsec.Queryable, int) {
+	var node parsec.Queryable
+	nt, end := parsec.NewNonTerminal("Statement"), cursor
+	node, end = p.parseExpr(end)
+	if node == nil {
+		return nil, cursor
+	} else if node != void {
+		nt.Children = append(nt.Children, node)
+	}
+	if at := p.skipws(end); p.prefix(at, ";") {
+		node, end = parsec.NewTerminal(";", ";", at), at+1
+	} else {
+		node = nil
+		p.expect(at, ";")
+	}
+	if node == nil {
+		return nil, cursor
+	} else if node != void {
+		nt.Children = append(nt.Children, node)
+	}
+	return nt, end
+}
+
+// Expr <- Expr op:("+" / "-") Term / Term
+func (p *parser) parseExpr(cursor int) (parsec.Queryable, int) {
+	return p.leftrec(0, cursor, (*parser).parseExprBody)
+}
+
+func (p *parser) parseExprBody(cursor int) (parsec.Queryable, int) {
+	var node parsec.Queryable
+	var end int
+	node, end = p.parseExprBody1(cursor)
+	if node != nil {
+		return node, end
+	}
+	node, end = p.parseTerm(cursor)
+	if node != nil {
+		return node, end
+	}
+	return nil, cursor
+}
+
+func (p *parser) parseExprBody1(cursor int) (parsec.Queryable, int) {
+	var node parsec.Queryable
+	nt, end := parsec.NewNonTerminal("Expr"), cursor
+	node, end = p.parseExpr(end)
+	if node == nil {
+		return nil, cursor
+	} else if node != void {
+		nt.Children = append(nt.Children, node)
+	}
+	node, end = p.parseExprBody11(end)
+	if node == nil {
+		return nil, cursor
+	} else if node != void {
+		nt.Children = append(nt.Children, node)
+	}
+	node, end = p.parseTerm(end)
+	if node == nil {
+		return nil, cursor
+	} else if node != void {
+		nt.Children = append(nt.Children, node)
+	}
+	return nt, end
+}
+
+func (p *parser) parseExprBody11(cursor int) (parsec.Queryable, int) {
+	var node parsec.Queryable
+	var end int
+	if at := p.skipws(cursor); p.prefix(at, "+") {
+		node, end = parsec.NewTerminal("+", "+", at), at+1
+	} else {
+		node = nil
+		p.expect(at, "+")
+	}
+	if node != nil {
+		return parsec.Rename("op", node), end
+	}
+	if at := p.skipws(cursor); p.prefix(at, "-") {
+		node, end = parsec.NewTerminal("-", "-", at), at+1
+	} else {
+		node = nil
+		p.expect(at, "-")
+	}
+	if node != nil {
+		return parsec.Rename("op", node), end
+	}
+	return nil, cursor
+}
+
+// Term <- Term op:("*" / "/") Factor / Factor
+func (p *parser) parseTerm(cursor int) (parsec.Queryable, int) {
+	return p.leftrec(1, cursor, (*parser).parseTermBody)
+}
+
+func (p *parser) parseTermBody(cursor int) (parsec.Queryable, int) {
+	var node parsec.Queryable
+	var end int
+	node, end = p.parseTermBody1(cursor)
+	if node != nil {
+		return node, end
+	}
+	node, end = p.parseFactor(cursor)
+	if node != nil {
+		return node, end
+	}
+	return nil, cursor
+}
+
+func (p *parser) parseTermBody1(cursor int) (parsec.Queryable, int) {
+	var node parsec.Queryable
+	nt, end := parsec.NewNonTerminal("Term"), cursor
+	node, end = p.parseTerm(end)
+	if node == nil {
+		return nil, cursor
+	} else if node != void {
+		nt.Children = append(nt.Children, node)
+	}
+	node, end = p.parseTermBody11(end)
+	if node == nil {
+		return nil, cursor
+	} else if node != void {
+		nt.Children = append(nt.Children, node)
+	}
+	node, end = p.parseFactor(end)
+	if node == nil {
+		return nil, cursor
+	} else if node != void {
+		nt.Children = append(nt.Children, node)
+	}
+	return nt, end
+}
+
+func (p *parser) parseTermBody11(cursor int) (parsec.Queryable, int) {
+	var node parsec.Queryable
+	var end int
+	if at := p.skipws(cursor); p.prefix(at, "*") {
+		node, end = parsec.NewTerminal("*", "*", at), at+1
+	} else {
+		node = nil
+		p.expect(at, "*")
+	}
+	if node != nil {
+		return parsec.Rename("op", node), end
+	}
+	if at := p.skipws(cursor); p.prefix(at, "/") {
+		node, end = parsec.NewTerminal("/", "/", at), at+1
+	} else {
+		node = nil
+		p.expect(at, "/")
+	}
+	if node != nil {
+		return parsec.Rename("op", node), end
+	}
+	return nil, cursor
+}
+
+// Factor <- sign:"-"? Value ("^" Value){0,8}
+func (p *parser) parseFactor(cursor int) (parsec.Queryable, int) {
+	var node parsec.Queryable
+	nt, end := parsec.NewNonTerminal("Factor"), cursor
+	node, end = p.parseFactor1(end)
+	if node == nil {
+		return nil, cursor
+	} else if node != void {
+		nt.Children = append(nt.Children, node)
+	}
+	node, end = p.parseValue(end)
+	if node == nil {
+		return nil, cursor
+	} else if node != void {
+		nt.Children = append(nt.Children, node)
+	}
+	node, end = p.parseFactor2(end)
+	if node == nil {
+		return nil, cursor
+	} else if node != void {
+		nt.Children = append(nt.Children, node)
+	}
+	return nt, end
+}
+
+func (p *parser) parseFactor1(cursor int) (parsec.Queryable, int) {
+	var node parsec.Queryable
+	var end int
+	if at := p.skipws(cursor); p.prefix(at, "-") {
+		node, end = parsec.NewTerminal("-", "-", at), at+1
+	} else {
+		node = nil
+		p.expect(at, "-")
+	}
+	if node == nil {
+		return parsec.MaybeNone("missing"), cursor
+	}
+	return node, end
+}
+
+func (p *parser) parseFactor2(cursor int) (parsec.Queryable, int) {
+	var node parsec.Queryable
+	var next int
+	nt, end := parsec.NewNonTerminal("Factor"), cursor
+	for len(nt.Children) < 8 {
+		node, next = p.parseFactor21(end)
+		if node == nil {
+			break
+		}
+		nt.Children = append(nt.Children, node)
+		end = next
+	}
+	return nt, end
+}
+
+func (p *parser) parseFactor21(cursor int) (parsec.Queryable, int) {
+	var node parsec.Queryable
+	nt, end := parsec.NewNonTerminal("Factor"), cursor
+	if at := p.skipws(end); p.prefix(at, "^") {
+		node, end = parsec.NewTerminal("^", "^", at), at+1
+	} else {
+		node = nil
+		p.expect(at, "^")
+	}
+	if node == nil {
+		return nil, cursor
+	} else if node != void {
+		nt.Children = append(nt.Children, node)
+	}
+	node, end = p.parseValue(end)
+	if node == nil {
+		return nil, cursor
+	} else if node != void {
+		nt.Children = append(nt.Children, node)
+	}
+	return nt, end
+}
+
+// Value <- call:(Name &"(" Args) / Number / Name / "(" Expr ")"
+func (p *parser) parseValue(cursor int) (parsec.Queryable, int) {
+	var node parsec.Queryable
+	var end int
+	node, end = p.parseValue1(cursor)
+	if node != nil {
+		return node, end
+	}
+	node, end = p.parseNumber(cursor)
+	if node != nil {
+		return node, end
+	}
+	node, end = p.parseName(cursor)
+	if node != nil {
+		return node, end
+	}
+	node, end = p.parseValue2(cursor)
+	if node != nil {
+		return node, end
+	}
+	return nil, cursor
+}
+
+func (p *parser) parseValue1(cursor int) (parsec.Queryable, int) {
+	var node parsec.Queryable
+	nt, end := parsec.NewNonTerminal("call"), cursor
+	node, end = p.parseName(end)
+	if node == nil {
+		return nil, cursor
+	} else if node != void {
+		nt.Children = append(nt.Children, node)
+	}
+	node, end = p.parseValue11(end)
+	if node == nil {
+		return nil, cursor
+	} else if node != void {
+		nt.Children = append(nt.Children, node)
+	}
+	node, end = p.parseArgs(end)
+	if node == nil {
+		return nil, cursor
+	} else if node != void {
+		nt.Children = append(nt.Children, node)
+	}
+	return nt, end
+}
+
+func (p *parser) parseValue2(cursor int) (parsec.Queryable, int) {
+	var node parsec.Queryable
+	nt, end := parsec.NewNonTerminal("Value"), cursor
+	if at := p.skipws(end); p.prefix(at, "(") {
+		node, end = parsec.NewTerminal("(", "(", at), at+1
+	} else {
+		node = nil
+		p.expect(at, "(")
+	}
+	if node == nil {
+		return nil, cursor
+	} else if node != void {
+		nt.Children = append(nt.Children, node)
+	}
+	node, end = p.parseExpr(end)
+	if node == nil {
+		return nil, cursor
+	} else if node != void {
+		nt.Children = append(nt.Children, node)
+	}
+	if at := p.skipws(end); p.prefix(at, ")") {
+		node, end = parsec.NewTerminal(")", ")", at), at+1
+	} else {
+		node = nil
+		p.expect(at, ")")
+	}
+	if node == nil {
+		return nil, cursor
+	} else if node != void {
+		nt.Children = append(nt.Children, node)
+	}
+	return nt, end
+}
+
+func (p *parser) parseValue11(cursor int) (parsec.Queryable, int) {
+	var node parsec.Queryable
+	if at := p.skipws(cursor); p.prefix(at, "(") {
+		node, _ = parsec.NewTerminal("(", "(", at), at+1
+	} else {
+		node = nil
+		p.expect(at, "(")
+	}
+	if node == nil {
+		return nil, cursor
+	}
+	return void, cursor
+}
+
+// Args <- "(" (Expr ("," Expr)*)? ")"
+func (p *parser) parseArgs(cursor int) (parsec.Queryable, int) {
+	var node parsec.Queryable
+	nt, end := parsec.NewNonTerminal("Args"), cursor
+	if at := p.skipws(end); p.prefix(at, "(") {
+		node, end = parsec.NewTerminal("(", "(", at), at+1
+	} else {
+		node = nil
+		p.expect(at, "(")
+	}
+	if node == nil {
+		return nil, cursor
+	} else if node != void {
+		nt.Children = append(nt.Children, node)
+	}
+	node, end = p.parseArgs1(end)
+	if node == nil {
+		return nil, cursor
+	} else if node != void {
+		nt.Children = append(nt.Children, node)
+	}
+	if at := p.skipws(end); p.prefix(at, ")") {
+		node, end = parsec.NewTerminal(")", ")", at), at+1
+	} else {
+		node = nil
+		p.expect(at, ")")
+	}
+	if node == nil {
+		return nil, cursor
+	} else if node != void {
+		nt.Children = append(nt.Children, node)
+	}
+	return nt, end
+}
+
+func (p *parser) parseArgs1(cursor int) (parsec.Queryable, int) {
+	var node parsec.Queryable
+	var end int
+	node, end = p.parseArgs11(cursor)
+	if node == nil {
+		return parsec.MaybeNone("missing"), cursor
+	}
+	return node, end
+}
+
+func (p *parser) parseArgs11(cursor int) (parsec.Queryable, int) {
+	var node parsec.Queryable
+	nt, end := parsec.NewNonTerminal("Args"), cursor
+	node, end = p.parseExpr(end)
+	if node == nil {
+		return nil, cursor
+	} else if node != void {
+		nt.Children = append(nt.Children, node)
+	}
+	node, end = p.parseArgs111(end)
+	if node == nil {
+		return nil, cursor
+	} else if node != void {
+		nt.Children = append(nt.Children, node)
+	}
+	return nt, end
+}
+
+func (p *parser) parseArgs111(cursor int) (parsec.Queryable, int) {
+	var node parsec.Queryable
+	var next int
+	nt, end := parsec.NewNonTerminal("Args"), cursor
+	for {
+		node, next = p.parseArgs1111(end)
+		if node == nil {
+			break
+		}
+		nt.Children = append(nt.Children, node)
+		if next == end {
+			panic(p.noprogress(end))
+		}
+		end = next
+	}
+	return nt, end
+}
+
+func (p *parser) parseArgs1111(cursor int) (parsec.Queryable, int) {
+	var node parsec.Queryable
+	nt, end := parsec.NewNonTerminal("Args"), cursor
+	if at := p.skipws(end); p.prefix(at, ",") {
+		node, end = parsec.NewTerminal(",", ",", at), at+1
+	} else {
+		node = nil
+		p.expect(at, ",")
+	}
+	if node == nil {
+		return nil, cursor
+	} else if node != void {
+		nt.Children = append(nt.Children, node)
+	}
+	node, end = p.parseExpr(end)
+	if node == nil {
+		return nil, cursor
+	} else if node != void {
+		nt.Children = append(nt.Children, node)
+	}
+	return nt, end
+}
+
+// Name <- !("let"i !~"[a-z0-9_]") ~"[a-z_][a-z0-9_]*"
+func (p *parser) parseName(cursor int) (parsec.Queryable, int) {
+	var node parsec.Queryable
+	nt, end := parsec.NewNonTerminal("Name"), cursor
+	node, end = p.parseName1(end)
+	if node == nil {
+		return nil, cursor
+	} else if node != void {
+		nt.Children = append(nt.Children, node)
+	}
+	if at, n := p.match(re2, p.skipws(end)); n >= 0 {
+		node, end = parsec.NewTerminal("[a-z_][a-z0-9_]*", string(p.text[at:at+n]), at), at+n
+	} else {
+		node = nil
+		p.expect(at, "[a-z_][a-z0-9_]*")
+	}
+	if node == nil {
+		return nil, cursor
+	} else if node != void {
+		nt.Children = append(nt.Children, node)
+	}
+	return nt, end
+}
+
+func (p *parser) parseName1(cursor int) (parsec.Queryable, int) {
+	var node parsec.Queryable
+	failcursor, expected := p.snapshot()
+	node, _ = p.parseName11(cursor)
+	p.failcursor, p.expected = failcursor, expected
+	if node != nil {
+		return nil, cursor
+	}
+	return void, cursor
+}
+
+func (p *parser) parseName11(cursor int) (parsec.Queryable, int) {
+	var node parsec.Queryable
+	nt, end := parsec.NewNonTerminal("Name"), cursor
+	if at, n := p.match(re1, p.skipws(end)); n >= 0 {
+		node, end = parsec.NewTerminal("let", string(p.text[at:at+n]), at), at+n
+	} else {
+		node = nil
+		p.expect(at, "let")
+	}
+	if node == nil {
+		return nil, cursor
+	} else if node != void {
+		nt.Children = append(nt.Children, node)
+	}
+	node, end = p.parseName111(end)
+	if node == nil {
+		return nil, cursor
+	} else if node != void {
+		nt.Children = append(nt.Children, node)
+	}
+	return nt, end
+}
+
+func (p *parser) parseName111(cursor int) (parsec.Queryable, int) {
+	var node parsec.Queryable
+	failcursor, expected := p.snapshot()
+	if at, n := p.match(re3, p.skipws(cursor)); n >= 0 {
+		node, _ = parsec.NewTerminal("[a-z0-9_]", string(p.text[at:at+n]), at), at+n
+	} else {
+		node = nil
+		p.expect(at, "[a-z0-9_]")
+	}
+	p.failcursor, p.expected = failcursor, expected
+	if node != nil {
+		return nil, cursor
+	}
+	return void, cursor
+}
+
+// Number <- ~"[0-9]+(?:\.[0-9]+)?"
+func (p *parser) parseNumber(cursor int) (parsec.Queryable, int) {
+	var node parsec.Queryable
+	var end int
+	if at, n := p.match(re4, p.skipws(cursor)); n >= 0 {
+		node, end = parsec.NewTerminal("Number", string(p.text[at:at+n]), at), at+n
+	} else {
+		node = nil
+		p.expect(at, "Number")
+	}
+	if node == nil {
+		return nil, cursor
+	}
+	return node, end
+}
+
+type parser struct {
+	text       []byte
+	failcursor int
+	expected   map[string]bool
+	rules      map[rulekey]*ruleentry
+}
+
+// voidnode is returned by predicates, and not included in the children
+// of a sequence.
+type voidnode struct {
+	parsec.MaybeNone
+}
+
+var void parsec.Queryable = voidnode{"void"}
+
+func (p *parser) skipws(cursor int) int {
+	if loc := ws.FindIndex(p.text[cursor:]); loc != nil {
+		return cursor + loc[1]
+	}
+	return cursor
+}
+
+func (p *parser) prefix(cursor int, s string) bool {
+	return len(p.text)-cursor >= len(s) && string(p.text[cursor:cursor+len(s)]) == s
+}
+
+// match return the length of match, -1 if re does not match at cursor.
+func (p *parser) match(re *regexp.Regexp, cursor int) (int, int) {
+	if loc := re.FindIndex(p.text[cursor:]); loc != nil {
+		return cursor, loc[1]
+	}
+	return cursor, -1
+}
+
+// expect remember the terminals that failed at the furthest cursor.
+func (p *parser) expect(cursor int, name string) {
+	if cursor > p.failcursor {
+		p.failcursor = cursor
+		p.expected = make(map[string]bool)
+	}
+	if cursor == p.failcursor && name != "" {
+		p.expected[name] = true
+	}
+}
+
+func (p *parser) snapshot() (int, map[string]bool) {
+	expected := make(map[string]bool, len(p.expected))
+	for name := range p.expected {
+		expected[name] = true
+	}
+	return p.failcursor, expected
+}
+
+func (p *parser) error() *parsec.ParseError {
+	err := &parsec.ParseError{}
+	if p.failcursor >= 0 {
+		err.Cursor = p.failcursor
+		for name := range p.expected {
+			err.Expected = append(err.Expected, name)
+		}
+		sort.Strings(err.Expected)
+	}
+	text := p.text[:err.Cursor]
+	err.Lineno = bytes.Count(text, []byte{'\n'}) + 1
+	if i := bytes.LastIndexByte(text, '\n'); i >= 0 {
+		text = text[i+1:]
+	}
+	err.Column = utf8.RuneCount(text) + 1
+	return err
+}
+
+// noprogress return the error for a repetition that matched without
+// consuming the text at cursor.
+func (p *parser) noprogress(cursor int) *parsec.ParseError {
+	p.failcursor, p.expected = cursor, nil
+	err := p.error()
+	err.Message = "repetition made no progress"
+	return err
+}
+
+type rulekey struct {
+	rule, cursor int
+}
+
+type ruleentry struct {
+	detected bool // rule was invoked again at the same cursor.
+	growing  bool // seed is being grown.
+	node     parsec.Queryable
+	end      int
+}
+
+// leftrec parse left recursive rule, by growing the seed from its
+// first match.
+func (p *parser) leftrec(
+	rule, cursor int,
+	parse func(*parser, int) (parsec.Queryable, int)) (parsec.Queryable, int) {
+
+	key := rulekey{rule: rule, cursor: cursor}
+	if entry, ok := p.rules[key]; ok {
+		if entry.growing {
+			return entry.node, entry.end
+		}
+		entry.detected = true
+		return nil, cursor
+	}
+	if p.rules == nil {
+		p.rules = make(map[rulekey]*ruleentry)
+	}
+	entry := &ruleentry{}
+	p.rules[key] = entry
+	defer delete(p.rules, key)
+
+	node, end := parse(p, cursor)
+	if !entry.detected || node == nil {
+		return node, end
+	}
+	entry.growing, entry.node, entry.end = true, node, end
+	for {
+		if node, end = parse(p, cursor); node == nil || end <= entry.end {
+			break
+		}
+		entry.node, entry.end = node, end
+	}
+	return entry.node, entry.end
+}
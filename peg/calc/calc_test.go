@@ -0,0 +1,111 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package calc
+
+import "fmt"
+import "io/ioutil"
+import "reflect"
+import "strings"
+import "testing"
+
+import "github.com/prataprc/goparsec"
+import "github.com/prataprc/goparsec/peg"
+
+var testcases = []string{
+	"1;",
+	"1 + 2 * 3 - 4 / 5;",
+	"let x = -2 ^ 3 ^ 1.5;\nLET y = f(x, (x + 1) * 2, g());\ny;",
+	"letter + let_ * lets;",
+	"a^1^2^3^4^5^6^7^8;",
+	// errors.
+	"",
+	"1 +;",
+	"let let = 1;",
+	"let x = 1;\n  f(x,;",
+	"a^1^2^3^4^5^6^7^8^9;",
+	"1; é",
+}
+
+func TestParse(t *testing.T) {
+	text, err := ioutil.ReadFile("calc.peg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tcase := range testcases {
+		ast := parsec.NewAST("calc", 100)
+		y, err := peg.Compile(ast, text)
+		if err != nil {
+			t.Fatalf("unexpected %v", err)
+		}
+		s := parsec.NewScanner([]byte(tcase))
+		ref, news := ast.Parsewith(y, s)
+		refend := news.GetCursor()
+
+		root, end, err := Parse([]byte(tcase))
+		if !reflect.DeepEqual(err, ast.Error()) {
+			t.Errorf("%q expected %v, got %v", tcase, ast.Error(), err)
+		} else if err != nil {
+			continue
+		}
+		if end != refend {
+			t.Errorf("%q expected %v, got %v", tcase, refend, end)
+		}
+		if out, ref := dump(root, ""), dump(ref, ""); out != ref {
+			t.Errorf("%q expected\n%v\ngot\n%v", tcase, ref, out)
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	root, _, err := Parse([]byte("let x = f(1, 2) + 3;"))
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+	ch := make(chan parsec.Queryable, 10)
+	go parsec.Query(root, "call Number", ch)
+	values := make([]string, 0)
+	for node := range ch {
+		values = append(values, node.GetValue())
+	}
+	if ref := []string{"1", "2"}; !reflect.DeepEqual(values, ref) {
+		t.Errorf("expected %v, got %v", ref, values)
+	}
+}
+
+func TestGenerator(t *testing.T) {
+	text, err := ioutil.ReadFile("calc.peg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	y, err := peg.Compile(parsec.NewAST("calc", 100), text)
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+	gen := parsec.NewGenerator(y, 1).SetDepth(6)
+	for i := 0; i < 100; i++ {
+		sentence := gen.Sentence()
+		if _, _, err := Parse(sentence); err != nil {
+			t.Errorf("%q unexpected %v", sentence, err)
+		}
+	}
+	rejected := 0
+	for i := 0; i < 100; i++ {
+		if _, _, err := Parse(gen.NearMiss()); err != nil {
+			rejected++
+		}
+	}
+	if rejected < 60 {
+		t.Errorf("expected most near misses to be rejected, got %v", rejected)
+	}
+}
+
+func dump(q parsec.Queryable, prefix string) string {
+	lines := []string{fmt.Sprintf(
+		"%v%v %q %v %v %v", prefix, q.GetName(), q.GetValue(),
+		q.GetPosition(), q.IsTerminal(), q.GetAttribute("class"))}
+	for _, child := range q.GetChildren() {
+		lines = append(lines, dump(child, prefix+"  "))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,449 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package peg
+
+import "bytes"
+import "fmt"
+import "go/format"
+import "io"
+import "strconv"
+import "strings"
+import "unicode"
+
+import "github.com/prataprc/goparsec"
+
+// Generate Go source for package `pkg`, implementing the parser for
+// `root` rule as plain functions, with terminals inlined. If root is
+// empty, first rule is the root. Generated package export a Parse
+// function that construct the same tree of parsec.Terminal and
+// parsec.NonTerminal nodes as the parser built by Build, so that
+// selectors for Query work the same. Terminals skip leading white
+// space, same as the default for parsec.NewScanner.
+func (g *Grammar) Generate(w io.Writer, pkg, root string) error {
+	if err := g.Validate(); err != nil {
+		return err
+	}
+	if root == "" {
+		root = g.Rules[0].Name
+	}
+	if g.Rule(root) == nil {
+		return fmt.Errorf("undefined root rule %q", root)
+	}
+
+	gen := &generator{
+		g:         g,
+		funcs:     make(map[string]bool),
+		rulefns:   make(map[string]string),
+		leftrec:   make(map[string]int),
+		regexpids: make(map[string]string),
+	}
+	for _, rule := range g.Rules {
+		gen.rulefns[rule.Name] = gen.funcname("parse" + camelcase(rule.Name))
+	}
+	leftrec := g.leftrecursive()
+	for _, rule := range g.Rules {
+		if leftrec[rule.Name] {
+			gen.leftrec[rule.Name] = len(gen.leftrec)
+		}
+	}
+	for _, rule := range g.Rules {
+		gen.rule(rule)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, genheader, pkg)
+	fmt.Fprintf(&buf, genparse, root, root, gen.rulefns[root])
+	fmt.Fprintf(&buf, "var ws = regexp.MustCompile(%v)\n", quote(`^[ \t\r\n]+`))
+	for _, patt := range gen.regexps {
+		fmt.Fprintf(&buf, "var %v = regexp.MustCompile(%v)\n", gen.regexpids[patt], quote(patt))
+	}
+	buf.WriteString("\n")
+	buf.Write(gen.code.Bytes())
+	buf.WriteString(genruntime)
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+type generator struct {
+	g         *Grammar
+	funcs     map[string]bool   // function names in use.
+	rulefns   map[string]string // rule name -> function name.
+	leftrec   map[string]int    // left recursive rule -> id.
+	regexps   []string          // patterns, in the order of use.
+	regexpids map[string]string // pattern -> variable name.
+	pending   []genfunc         // functions to be generated.
+	code      bytes.Buffer
+}
+
+// genfunc is a function generated for expr, constructing nodes named
+// `name`.
+type genfunc struct {
+	fn   string
+	name string
+	expr *Expr
+	top  bool
+}
+
+func (gen *generator) rule(rule *Rule) {
+	fn := gen.rulefns[rule.Name]
+	fmt.Fprintf(&gen.code, "// %v <- %v\n", rule.Name, rule.Expr)
+	if id, ok := gen.leftrec[rule.Name]; ok {
+		body := gen.funcname(fn + "Body")
+		fmt.Fprintf(&gen.code, "func (p *parser) %v(cursor int) (parsec.Queryable, int) {\n", fn)
+		fmt.Fprintf(&gen.code, "return p.leftrec(%v, cursor, (*parser).%v)\n}\n\n", id, body)
+		fn = body
+	}
+	gen.pending = append(gen.pending, genfunc{fn, rule.Name, rule.Expr, true})
+	for len(gen.pending) > 0 {
+		f := gen.pending[0]
+		gen.pending = gen.pending[1:]
+		gen.function(f.fn, f.name, f.expr, f.top)
+	}
+}
+
+// function for expr, same as builder.compile.
+func (gen *generator) function(fn, name string, expr *Expr, top bool) {
+	w := &gen.code
+	label := expr.Label
+	if label != "" {
+		name, top = label, true
+	}
+	fmt.Fprintf(w, "func (p *parser) %v(cursor int) (parsec.Queryable, int) {\n", fn)
+	fmt.Fprintf(w, "var node parsec.Queryable\n")
+	switch expr.Op {
+	case Sequence:
+		fmt.Fprintf(w, "nt, end := parsec.NewNonTerminal(%q), cursor\n", name)
+		for _, arg := range expr.Args {
+			w.WriteString(gen.block(fn, name, arg, false, "end", "end"))
+			fmt.Fprintf(w, "if node == nil {\nreturn nil, cursor\n")
+			fmt.Fprintf(w, "} else if node != void {\n")
+			fmt.Fprintf(w, "nt.Children = append(nt.Children, node)\n}\n")
+		}
+		fmt.Fprintf(w, "return nt, end\n")
+
+	case Choice:
+		result := "node"
+		if label != "" {
+			result = fmt.Sprintf("parsec.Rename(%q, node)", label)
+		}
+		fmt.Fprintf(w, "var end int\n")
+		for _, arg := range expr.Args {
+			w.WriteString(gen.block(fn, name, arg, false, "cursor", "end"))
+			fmt.Fprintf(w, "if node != nil {\nreturn %v, end\n}\n", result)
+		}
+		fmt.Fprintf(w, "return nil, cursor\n")
+
+	case Repetition:
+		if expr.Min == 0 && expr.Max == 1 {
+			fmt.Fprintf(w, "var end int\n")
+			w.WriteString(gen.block(fn, name, expr.Args[0], false, "cursor", "end"))
+			fmt.Fprintf(w, "if node == nil {\n")
+			fmt.Fprintf(w, "return parsec.MaybeNone(\"missing\"), cursor\n}\n")
+			fmt.Fprintf(w, "return node, end\n")
+			break
+		}
+		fmt.Fprintf(w, "var next int\n")
+		fmt.Fprintf(w, "nt, end := parsec.NewNonTerminal(%q), cursor\n", name)
+		if expr.Max < 0 {
+			fmt.Fprintf(w, "for {\n")
+		} else {
+			fmt.Fprintf(w, "for len(nt.Children) < %v {\n", expr.Max)
+		}
+		w.WriteString(gen.block(fn, name, expr.Args[0], false, "end", "next"))
+		fmt.Fprintf(w, "if node == nil {\nbreak\n}\n")
+		fmt.Fprintf(w, "nt.Children = append(nt.Children, node)\n")
+		if expr.Max < 0 {
+			fmt.Fprintf(w, "if next == end {\npanic(p.noprogress(end))\n}\n")
+		}
+		fmt.Fprintf(w, "end = next\n}\n")
+		if expr.Min > 0 {
+			fmt.Fprintf(w, "if len(nt.Children) < %v {\n", expr.Min)
+			fmt.Fprintf(w, "return nil, cursor\n}\n")
+		}
+		fmt.Fprintf(w, "return nt, end\n")
+
+	case Lookahead:
+		w.WriteString(gen.block(fn, name, expr.Args[0], false, "cursor", "_"))
+		fmt.Fprintf(w, "if node == nil {\nreturn nil, cursor\n}\n")
+		fmt.Fprintf(w, "return void, cursor\n")
+
+	case Not:
+		fmt.Fprintf(w, "failcursor, expected := p.snapshot()\n")
+		w.WriteString(gen.block(fn, name, expr.Args[0], false, "cursor", "_"))
+		fmt.Fprintf(w, "p.failcursor, p.expected = failcursor, expected\n")
+		fmt.Fprintf(w, "if node != nil {\nreturn nil, cursor\n}\n")
+		fmt.Fprintf(w, "return void, cursor\n")
+
+	default: // rule defined as a terminal or reference.
+		fmt.Fprintf(w, "var end int\n")
+		w.WriteString(gen.block(fn, name, expr, top, "cursor", "end"))
+		fmt.Fprintf(w, "if node == nil {\nreturn nil, cursor\n}\n")
+		fmt.Fprintf(w, "return node, end\n")
+	}
+	fmt.Fprintf(w, "}\n\n")
+}
+
+// block of code matching expr at cursor `in`, that set `node`, and the
+// cursor after the match to `out`.
+func (gen *generator) block(fn, name string, expr *Expr, top bool, in, out string) string {
+	label := expr.Label
+	switch expr.Op {
+	case Literal, Regexp:
+		if label != "" {
+			name = label
+		} else if !top {
+			name = expr.Text
+		}
+		return gen.terminal(name, expr, in, out)
+
+	case Ref:
+		call := fmt.Sprintf("p.%v(%v)", gen.rulefns[expr.Text], in)
+		if label == "" {
+			return fmt.Sprintf("node, %v = %v\n", out, call)
+		}
+		fmsg := "if node, %v = %v; node != nil {\nnode = parsec.Rename(%q, node)\n}\n"
+		return fmt.Sprintf(fmsg, out, call, label)
+	}
+	sub := gen.funcname(fn)
+	gen.pending = append(gen.pending, genfunc{sub, name, expr, top})
+	return fmt.Sprintf("node, %v = p.%v(%v)\n", out, sub, in)
+}
+
+// terminal inlined, same as parsec.Atom, parsec.Token and their exact
+// variants.
+func (gen *generator) terminal(name string, expr *Expr, in, out string) string {
+	at := in
+	if !expr.Exact {
+		at = fmt.Sprintf("p.skipws(%v)", in)
+	}
+	var code string
+	if expr.Op == Literal && !expr.Fold {
+		fmsg := "if at := %v; p.prefix(at, %q) {\n" +
+			"node, %v = parsec.NewTerminal(%q, %q, at), at+%v\n"
+		code = fmt.Sprintf(fmsg, at, expr.Text, out, name, expr.Text, len(expr.Text))
+	} else {
+		fmsg := "if at, n := p.match(%v, %v); n >= 0 {\n" +
+			"node, %v = parsec.NewTerminal(%q, string(p.text[at:at+n]), at), at+n\n"
+		code = fmt.Sprintf(fmsg, gen.regexp("^"+pattern(expr)), at, out, name)
+	}
+	return code + fmt.Sprintf("} else {\nnode = nil\np.expect(at, %q)\n}\n", name)
+}
+
+// regexp return the variable name for compiled pattern.
+func (gen *generator) regexp(patt string) string {
+	id, ok := gen.regexpids[patt]
+	if !ok {
+		id = fmt.Sprintf("re%v", len(gen.regexps))
+		gen.regexpids[patt] = id
+		gen.regexps = append(gen.regexps, patt)
+	}
+	return id
+}
+
+// funcname return a unique function name prefixed with `prefix`.
+func (gen *generator) funcname(prefix string) string {
+	name := prefix
+	for i := 1; gen.funcs[name]; i++ {
+		name = prefix + strconv.Itoa(i)
+	}
+	gen.funcs[name] = true
+	return name
+}
+
+// leftrecursive return rules that can invoke themselves, directly or
+// indirectly, at the same cursor position.
+func (g *Grammar) leftrecursive() map[string]bool {
+	rules := make(map[string]bool)
+	ast := parsec.NewAST("generate", 100)
+	for _, name := range g.grammar(ast, "generate").LeftRecursive() {
+		rules[name] = true
+	}
+	return rules
+}
+
+// camelcase rule name, for function names.
+func camelcase(name string) string {
+	parts := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, part := range parts {
+		parts[i] = strings.ToUpper(part[:1]) + part[1:]
+	}
+	return strings.Join(parts, "")
+}
+
+// quote pattern as raw string, if possible.
+func quote(patt string) string {
+	if strings.ContainsAny(patt, "`\r\n") {
+		return strconv.Quote(patt)
+	}
+	return "`" + patt + "`"
+}
+
+const genheader = `// Code generated by parsec gen; DO NOT EDIT.
+
+package %v
+
+import "bytes"
+import "regexp"
+import "sort"
+import "unicode/utf8"
+
+import "github.com/prataprc/goparsec"
+
+`
+
+const genparse = `// Parse text using rule %v, return the root node and the number of
+// bytes consumed. If rule %v fails to match the text, return
+// *parsec.ParseError.
+func Parse(text []byte) (root parsec.Queryable, n int, err error) {
+	p := &parser{text: text, failcursor: -1}
+	defer func() {
+		if r := recover(); r != nil {
+			perr, ok := r.(*parsec.ParseError)
+			if !ok {
+				panic(r)
+			}
+			root, n, err = nil, 0, perr
+		}
+	}()
+	if node, end := p.%v(0); node != nil {
+		return node, end, nil
+	}
+	return nil, 0, p.error()
+}
+
+`
+
+const genruntime = `type parser struct {
+	text       []byte
+	failcursor int
+	expected   map[string]bool
+	rules      map[rulekey]*ruleentry
+}
+
+// voidnode is returned by predicates, and not included in the children
+// of a sequence.
+type voidnode struct {
+	parsec.MaybeNone
+}
+
+var void parsec.Queryable = voidnode{"void"}
+
+func (p *parser) skipws(cursor int) int {
+	if loc := ws.FindIndex(p.text[cursor:]); loc != nil {
+		return cursor + loc[1]
+	}
+	return cursor
+}
+
+func (p *parser) prefix(cursor int, s string) bool {
+	return len(p.text)-cursor >= len(s) && string(p.text[cursor:cursor+len(s)]) == s
+}
+
+// match return the length of match, -1 if re does not match at cursor.
+func (p *parser) match(re *regexp.Regexp, cursor int) (int, int) {
+	if loc := re.FindIndex(p.text[cursor:]); loc != nil {
+		return cursor, loc[1]
+	}
+	return cursor, -1
+}
+
+// expect remember the terminals that failed at the furthest cursor.
+func (p *parser) expect(cursor int, name string) {
+	if cursor > p.failcursor {
+		p.failcursor = cursor
+		p.expected = make(map[string]bool)
+	}
+	if cursor == p.failcursor && name != "" {
+		p.expected[name] = true
+	}
+}
+
+func (p *parser) snapshot() (int, map[string]bool) {
+	expected := make(map[string]bool, len(p.expected))
+	for name := range p.expected {
+		expected[name] = true
+	}
+	return p.failcursor, expected
+}
+
+func (p *parser) error() *parsec.ParseError {
+	err := &parsec.ParseError{}
+	if p.failcursor >= 0 {
+		err.Cursor = p.failcursor
+		for name := range p.expected {
+			err.Expected = append(err.Expected, name)
+		}
+		sort.Strings(err.Expected)
+	}
+	text := p.text[:err.Cursor]
+	err.Lineno = bytes.Count(text, []byte{'\n'}) + 1
+	if i := bytes.LastIndexByte(text, '\n'); i >= 0 {
+		text = text[i+1:]
+	}
+	err.Column = utf8.RuneCount(text) + 1
+	return err
+}
+
+// noprogress return the error for a repetition that matched without
+// consuming the text at cursor.
+func (p *parser) noprogress(cursor int) *parsec.ParseError {
+	p.failcursor, p.expected = cursor, nil
+	err := p.error()
+	err.Message = "repetition made no progress"
+	return err
+}
+
+type rulekey struct {
+	rule, cursor int
+}
+
+type ruleentry struct {
+	detected bool // rule was invoked again at the same cursor.
+	growing  bool // seed is being grown.
+	node     parsec.Queryable
+	end      int
+}
+
+// leftrec parse left recursive rule, by growing the seed from its
+// first match.
+func (p *parser) leftrec(
+	rule, cursor int,
+	parse func(*parser, int) (parsec.Queryable, int)) (parsec.Queryable, int) {
+
+	key := rulekey{rule: rule, cursor: cursor}
+	if entry, ok := p.rules[key]; ok {
+		if entry.growing {
+			return entry.node, entry.end
+		}
+		entry.detected = true
+		return nil, cursor
+	}
+	if p.rules == nil {
+		p.rules = make(map[rulekey]*ruleentry)
+	}
+	entry := &ruleentry{}
+	p.rules[key] = entry
+	defer delete(p.rules, key)
+
+	node, end := parse(p, cursor)
+	if !entry.detected || node == nil {
+		return node, end
+	}
+	entry.growing, entry.node, entry.end = true, node, end
+	for {
+		if node, end = parse(p, cursor); node == nil || end <= entry.end {
+			break
+		}
+		entry.node, entry.end = node, end
+	}
+	return entry.node, entry.end
+}
+`
@@ -0,0 +1,53 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package peg
+
+import "bytes"
+import "io/ioutil"
+import "reflect"
+import "testing"
+
+// TestGenerate check that calc/calc.go is up to date with the
+// generator, calc/calc_test.go compare it with the AST parsers.
+func TestGenerate(t *testing.T) {
+	text, err := ioutil.ReadFile("calc/calc.peg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := ioutil.ReadFile("calc/calc.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := Parse(text)
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+	var buf bytes.Buffer
+	if err := g.Generate(&buf, "calc", ""); err != nil {
+		t.Fatalf("unexpected %v", err)
+	} else if !bytes.Equal(buf.Bytes(), ref) {
+		t.Errorf("calc/calc.go is stale, regenerate from calc/calc.peg")
+	}
+
+	if err := g.Generate(&buf, "calc", "Sum"); err == nil {
+		t.Errorf("expected error")
+	}
+}
+
+func TestLeftRecursive(t *testing.T) {
+	g, err := Parse([]byte(`
+	A <- B "a" / "a"
+	B <- C? A
+	C <- ~"[0-9]*"
+	D <- E "d" / "d"
+	E <- ~"[0-9]" D
+	F <- !F "f"`))
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+	ref := map[string]bool{"A": true, "B": true, "F": true}
+	if out := g.leftrecursive(); !reflect.DeepEqual(out, ref) {
+		t.Errorf("expected %v, got %v", ref, out)
+	}
+}
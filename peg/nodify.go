@@ -0,0 +1,205 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package peg
+
+import "strconv"
+import "strings"
+import "unicode/utf8"
+
+import "github.com/prataprc/goparsec"
+
+// capture is the label of a named capture.
+type capture string
+
+//----------
+// Nodifiers
+//----------
+
+func grammarNode(ns []parsec.ParsecNode) parsec.ParsecNode {
+	items := ns[0].([]parsec.ParsecNode)
+	rules := make([]*Rule, 0, len(items))
+	for _, item := range items {
+		rules = append(rules, item.(*Rule))
+	}
+	return rules
+}
+
+func ruleNode(ns []parsec.ParsecNode) parsec.ParsecNode {
+	name := ns[0].(*parsec.Terminal)
+	return &Rule{Name: name.Value, Expr: ns[2].(*Expr), Pos: name.Position}
+}
+
+func choiceNode(ns []parsec.ParsecNode) parsec.ParsecNode {
+	items := exprs(ns)
+	if len(items) == 1 {
+		return items[0]
+	}
+	return &Expr{Op: Choice, Args: items, Pos: items[0].Pos}
+}
+
+func sequenceNode(ns []parsec.ParsecNode) parsec.ParsecNode {
+	items := exprs(ns)
+	if len(items) == 1 {
+		return items[0]
+	}
+	return &Expr{Op: Sequence, Args: items, Pos: items[0].Pos}
+}
+
+func prefixNode(ns []parsec.ParsecNode) parsec.ParsecNode {
+	expr := ns[len(ns)-1].(*Expr)
+	if t, ok := ns[len(ns)-2].(*parsec.Terminal); ok {
+		op := Lookahead
+		if t.Name == "NOT" {
+			op = Not
+		}
+		expr = &Expr{Op: op, Args: []*Expr{expr}, Pos: t.Position}
+	}
+	if name, ok := ns[0].(capture); ok {
+		expr.Label = string(name)
+	}
+	return expr
+}
+
+func suffixNode(ns []parsec.ParsecNode) parsec.ParsecNode {
+	expr := ns[0].(*Expr)
+	t, ok := ns[1].(*parsec.Terminal)
+	if !ok {
+		return expr
+	}
+	rep := &Expr{Op: Repetition, Args: []*Expr{expr}, Pos: expr.Pos}
+	switch t.Name {
+	case "STAR":
+		rep.Min, rep.Max = 0, -1
+	case "PLUS":
+		rep.Min, rep.Max = 1, -1
+	case "QUESTION":
+		rep.Min, rep.Max = 0, 1
+	case "COUNT":
+		body := t.Value[1 : len(t.Value)-1]
+		if i := strings.IndexByte(body, ','); i < 0 {
+			rep.Min = atoi(body, 0)
+			rep.Max = rep.Min
+		} else {
+			rep.Min, rep.Max = atoi(body[:i], 0), atoi(body[i+1:], -1)
+		}
+	}
+	return rep
+}
+
+func optionNode(ns []parsec.ParsecNode) parsec.ParsecNode {
+	expr := ns[0].(*Expr)
+	return &Expr{Op: Repetition, Args: []*Expr{expr}, Min: 0, Max: 1, Pos: expr.Pos}
+}
+
+func repeatNode(ns []parsec.ParsecNode) parsec.ParsecNode {
+	expr := ns[0].(*Expr)
+	return &Expr{Op: Repetition, Args: []*Expr{expr}, Min: 0, Max: -1, Pos: expr.Pos}
+}
+
+func refNode(ns []parsec.ParsecNode) parsec.ParsecNode {
+	t := ns[0].(*parsec.Terminal)
+	return &Expr{Op: Ref, Text: t.Value, Pos: t.Position}
+}
+
+func labelNode(ns []parsec.ParsecNode) parsec.ParsecNode {
+	return capture(ns[0].(*parsec.Terminal).Value)
+}
+
+func termNode(ns []parsec.ParsecNode) parsec.ParsecNode {
+	switch n := ns[0].(type) {
+	case *Expr:
+		return n
+	case *parsec.Terminal:
+		expr := &Expr{Op: Regexp, Pos: n.Position}
+		switch n.Name {
+		case "LITERAL":
+			text := n.Value
+			if strings.HasSuffix(text, "i") {
+				expr.Fold, text = true, text[:len(text)-1]
+			}
+			expr.Op, expr.Text = Literal, unquote(text)
+		case "REGEX":
+			expr.Text = n.Value[2 : len(n.Value)-1]
+		case "CLASS":
+			expr.Text = n.Value
+		case "DOT":
+			expr.Text = `(?s).`
+		}
+		return expr
+	}
+	return nil
+}
+
+//--------
+// Helpers
+//--------
+
+// exprs from a list of nodes, nested lists are flattened and other
+// nodes, like separators, are skipped.
+func exprs(ns []parsec.ParsecNode) []*Expr {
+	items := make([]*Expr, 0, len(ns))
+	for _, n := range ns {
+		switch x := n.(type) {
+		case *Expr:
+			items = append(items, x)
+		case []parsec.ParsecNode:
+			items = append(items, exprs(x)...)
+		}
+	}
+	return items
+}
+
+// unquote literal text, validated by the literal terminal.
+func unquote(text string) string {
+	text = text[1 : len(text)-1]
+	if strings.IndexByte(text, '\\') < 0 {
+		return text
+	}
+	buf := make([]byte, 0, len(text))
+	for i := 0; i < len(text); i++ {
+		if text[i] != '\\' {
+			buf = append(buf, text[i])
+			continue
+		}
+		i++
+		switch c := text[i]; c {
+		case 'a':
+			buf = append(buf, '\a')
+		case 'b':
+			buf = append(buf, '\b')
+		case 'f':
+			buf = append(buf, '\f')
+		case 'n':
+			buf = append(buf, '\n')
+		case 'r':
+			buf = append(buf, '\r')
+		case 't':
+			buf = append(buf, '\t')
+		case 'v':
+			buf = append(buf, '\v')
+		case 'x':
+			n, _ := strconv.ParseUint(text[i+1:i+3], 16, 8)
+			buf, i = append(buf, byte(n)), i+2
+		case 'u', 'U':
+			width := 4
+			if c == 'U' {
+				width = 8
+			}
+			n, _ := strconv.ParseUint(text[i+1:i+1+width], 16, 32)
+			var rb [utf8.UTFMax]byte
+			size := utf8.EncodeRune(rb[:], rune(n))
+			buf, i = append(buf, rb[:size]...), i+width
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return string(buf)
+}
+
+func atoi(text string, def int) int {
+	if n, err := strconv.Atoi(text); err == nil {
+		return n
+	}
+	return def
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package peg
+
+import "github.com/prataprc/goparsec"
+
+// notation is a parser for grammar text, along with the pattern for
+// white space and comments.
+type notation struct {
+	y  parsec.Parser
+	ws string
+}
+
+// notations are tried in order, until grammar text is parsed.
+var notations = []notation{
+	{y: pegparser(), ws: `^(?:[ \t\r\n]+|#[^\n]*)+`},
+	{y: ebnfparser(), ws: `^(?:[ \t\r\n]+|\(\*(?s:.*?)\*\)|/\*(?s:.*?)\*/)+`},
+}
+
+const escape = `\\(?:[abfnrtv\\'"]|x[0-9a-fA-F]{2}|u[0-9a-fA-F]{4}|U[0-9a-fA-F]{8})`
+
+// pegparser for grammar text in PEG notation.
+//
+//	grammar  -> rule+ EOF
+//	rule     -> IDENT "<-" choice
+//	choice   -> sequence ("/" sequence)*
+//	sequence -> prefix+
+//	prefix   -> label? ("&" / "!")? suffix
+//	suffix   -> primary ("*" / "+" / "?" / COUNT)?
+//	primary  -> ref / "(" choice ")" / LITERAL / REGEX / CLASS / "."
+//	ref      -> IDENT !"<-"
+func pegparser() parsec.Parser {
+	var choice parsec.Parser
+
+	arrow := parsec.Atom("<-", "ARROW")
+	ref := parsec.And(refNode, ident(), parsec.NotFollowedBy(arrow))
+	group := parsec.Between(
+		parsec.First, parsec.Atom("(", "OPEN"), parsec.Atom(")", "CLOSE"), &choice)
+	class := parsec.Token(`\[(?:[^\]\\\n]|\\.)*\]`, "CLASS")
+	primary := parsec.OrdChoice(
+		termNode, ref, group, literal(), regex(), class, parsec.Atom(".", "DOT"))
+	suffix := parsec.And(
+		suffixNode, primary, parsec.Maybe(parsec.First, suffixop()))
+	prefix := parsec.And(
+		prefixNode,
+		parsec.Maybe(parsec.First, label()),
+		parsec.Maybe(parsec.First, parsec.OrdChoice(
+			parsec.First, parsec.Atom("&", "AND"), parsec.Atom("!", "NOT"))),
+		suffix)
+	sequence := parsec.Many(sequenceNode, prefix)
+	choice = parsec.And(
+		choiceNode,
+		sequence,
+		parsec.Kleene(nil, parsec.And(nil, parsec.Atom("/", "SLASH"), sequence)))
+	rule := parsec.And(ruleNode, ident(), arrow, &choice)
+	return parsec.And(grammarNode, parsec.Many(nil, rule), parsec.EndWS())
+}
+
+// ebnfparser for grammar text in EBNF notation.
+//
+//	grammar  -> rule+ EOF
+//	rule     -> IDENT define choice ";"?
+//	define   -> "::=" | ":=" | "="
+//	choice   -> sequence { "|" sequence }
+//	sequence -> prefix { [","] prefix }
+//	prefix   -> [label] suffix
+//	suffix   -> primary [ "*" | "+" | "?" | COUNT ]
+//	primary  -> ref | "(" choice ")" | "[" choice "]" | "{" choice "}"
+//	          | LITERAL | REGEX
+//	ref      -> IDENT !define
+func ebnfparser() parsec.Parser {
+	var choice parsec.Parser
+
+	define := parsec.OrdChoice(
+		parsec.First,
+		parsec.Atom("::=", "DEFINE"), parsec.Atom(":=", "DEFINE"),
+		parsec.Atom("=", "DEFINE"))
+	ref := parsec.And(refNode, ident(), parsec.NotFollowedBy(define))
+	group := parsec.Between(
+		parsec.First, parsec.Atom("(", "OPEN"), parsec.Atom(")", "CLOSE"), &choice)
+	option := parsec.Between(
+		optionNode, parsec.Atom("[", "OPENSQR"), parsec.Atom("]", "CLOSESQR"),
+		&choice)
+	repeat := parsec.Between(
+		repeatNode, parsec.Atom("{", "OPENBRACE"), parsec.Atom("}", "CLOSEBRACE"),
+		&choice)
+	primary := parsec.OrdChoice(
+		termNode, ref, group, option, repeat, literal(), regex())
+	suffix := parsec.And(
+		suffixNode, primary, parsec.Maybe(parsec.First, suffixop()))
+	prefix := parsec.And(prefixNode, parsec.Maybe(parsec.First, label()), suffix)
+	sequence := parsec.And(
+		sequenceNode,
+		prefix,
+		parsec.Kleene(nil, parsec.And(
+			nil, parsec.Maybe(nil, parsec.Atom(",", "COMMA")), prefix)))
+	choice = parsec.And(
+		choiceNode,
+		sequence,
+		parsec.Kleene(nil, parsec.And(nil, parsec.Atom("|", "BAR"), sequence)))
+	rule := parsec.And(
+		ruleNode, ident(), define, &choice,
+		parsec.Maybe(nil, parsec.Atom(";", "SEMICOLON")))
+	return parsec.And(grammarNode, parsec.Many(nil, rule), parsec.EndWS())
+}
+
+//----------
+// Terminals
+//----------
+
+func ident() parsec.Parser {
+	return parsec.Token(`[A-Za-z_][A-Za-z0-9_-]*`, "IDENT")
+}
+
+// literal is a quoted string, optionally followed by `i` to match it
+// ignoring case.
+func literal() parsec.Parser {
+	dq := `"(?:[^"\\\n]|` + escape + `)*"`
+	sq := `'(?:[^'\\\n]|` + escape + `)*'`
+	return parsec.Token(`(?:`+dq+`|`+sq+`)(?:i\b)?`, "LITERAL")
+}
+
+// regex is a quoted regular expression prefixed with `~`, quoted text
+// is used verbatim as the pattern.
+func regex() parsec.Parser {
+	return parsec.Token(`~(?:"(?:[^"\\\n]|\\.)*"|'(?:[^'\\\n]|\\.)*')`, "REGEX")
+}
+
+// label of a named capture, `name:`, shall not be confused with rule
+// definition like `name := ...`.
+func label() parsec.Parser {
+	return parsec.And(
+		labelNode,
+		ident(),
+		parsec.AtomExact(":", "COLON"),
+		parsec.NotFollowedBy(parsec.TokenExact(`[:=]`, "DEFINE")))
+}
+
+func suffixop() parsec.Parser {
+	return parsec.OrdChoice(
+		parsec.First,
+		parsec.Atom("*", "STAR"), parsec.Atom("+", "PLUS"),
+		parsec.Atom("?", "QUESTION"),
+		parsec.TokenExact(`\{(?:[0-9]+|[0-9]*,[0-9]*)\}`, "COUNT"))
+}
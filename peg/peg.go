@@ -0,0 +1,304 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+// Package peg compile grammar text, written in PEG or EBNF notation,
+// into parsers composed with AST combinators. For example, in PEG
+// notation,
+//
+//	# comments start with hash.
+//	Sum     <- Product (op:("+" / "-") Product)*
+//	Product <- Value (op:("*" / "/") Value)*
+//	Value   <- Number / "(" Sum ")"
+//	Number  <- ~"[0-9]+"
+//
+// and the same grammar in EBNF notation,
+//
+//	(* comments are enclosed in parenthesis and asterisk. *)
+//	Sum     = Product { op:("+" | "-") Product } ;
+//	Product = Value { op:("*" | "/") Value } ;
+//	Value   = Number | "(" Sum ")" ;
+//	Number  = ~"[0-9]+" ;
+//
+// Rules are defined using `<-` in PEG, and `=`, `:=` or `::=` in EBNF,
+// terminating `;` is optional. Both notations support:
+//
+//	"text" 'text'   literal, matched using parsec.Atom.
+//	"text"i         literal, matched ignoring case.
+//	~"regexp"       regular expression, matched using parsec.Token.
+//	a b             sequence, compiled to AST.And.
+//	e*  e+  e?      repetition, compiled to AST.Kleene, AST.Many and AST.Maybe.
+//	e{n,m}          repetition, compiled to AST.Repeat, either bound is optional.
+//	( e )           grouping.
+//	name:e          named capture, node matched by e is named `name`,
+//	                binds looser than repetition and predicates.
+//
+// In PEG, `/` separates ordered choices, compiled to AST.OrdChoice,
+// `&e` and `!e` are lookahead predicates, compiled to AST.Lookahead and
+// AST.Not, `[a-z]` is a character class and `.` matches any character.
+// In EBNF, `|` separates choices, `[ e ]` is optional, `{ e }` is
+// repetition and sequence items can be separated by comma.
+//
+// Non-terminal nodes are named after the rule, unless named by a
+// capture. Rules defined by a single terminal construct terminal nodes
+// named after the rule, other terminals are named after their literal
+// text or pattern. Like parsec.Token and parsec.Atom, terminals skip
+// leading white space.
+//
+// Grammar.Generate emit Go source for a standalone parser, constructing
+// the same tree of nodes without the combinators, refer to `parsec gen`
+// in tools/parsec.
+package peg
+
+import "fmt"
+import "regexp"
+import "strconv"
+import "strings"
+import "unicode/utf8"
+
+import "github.com/prataprc/goparsec"
+
+// Op is the operator of an expression.
+type Op int
+
+const (
+	// Sequence matches Args in sequence.
+	Sequence Op = iota + 1
+	// Choice matches the first of Args that matches.
+	Choice
+	// Repetition matches Args[0] atleast Min and atmost Max times, a
+	// negative Max means there is no upper bound.
+	Repetition
+	// Lookahead succeeds if Args[0] matches, without consuming input.
+	Lookahead
+	// Not succeeds if Args[0] fails to match, without consuming input.
+	Not
+	// Literal matches Text.
+	Literal
+	// Regexp matches the regular expression Text.
+	Regexp
+	// Ref matches the rule named Text.
+	Ref
+)
+
+// Expr is an expression in grammar.
+type Expr struct {
+	Op    Op
+	Args  []*Expr
+	Text  string // literal text, regular expression or rule name.
+	Label string // name of the node, for named captures.
+	Min   int    // for Repetition.
+	Max   int    // for Repetition.
+	Fold  bool   // Literal is matched ignoring case.
+	Exact bool   // terminal is matched without skipping white space.
+	Pos   int    // offset of the expression in grammar text.
+}
+
+// Rule defines Name as Expr.
+type Rule struct {
+	Name string
+	Expr *Expr
+	Pos  int // offset of the rule in grammar text.
+}
+
+// Grammar is a list of rules, parsed from grammar text.
+type Grammar struct {
+	Rules []*Rule
+	Text  []byte // grammar text, to locate errors.
+}
+
+// Parse grammar text, in PEG or EBNF notation, refer to package
+// documentation for the syntax. Return *parsec.ParseError for malformed
+// text, and parsec.Diagnostics for invalid rules, refer to Validate.
+func Parse(text []byte) (*Grammar, error) {
+	var perr *parsec.ParseError
+
+	for _, notation := range notations {
+		s := parsec.NewScanner(text).SetWSPattern(notation.ws)
+		node, _, err := parsec.Parse(notation.y, s)
+		if err == nil {
+			g := &Grammar{Rules: node.([]*Rule), Text: text}
+			if err := g.Validate(); err != nil {
+				return nil, err
+			}
+			return g, nil
+		}
+		// report error from the notation that parsed farther.
+		if x, ok := err.(*parsec.ParseError); !ok {
+			return nil, err
+		} else if perr == nil || x.Cursor > perr.Cursor {
+			perr = x
+		}
+	}
+	return nil, perr
+}
+
+// Rule return the rule `name`, nil if not defined.
+func (g *Grammar) Rule(name string) *Rule {
+	for _, rule := range g.Rules {
+		if rule.Name == name {
+			return rule
+		}
+	}
+	return nil
+}
+
+// Validate the grammar, return parsec.Diagnostics listing rules that
+// are defined more than once, references to undefined rules, invalid
+// regular expressions and invalid repetition counts.
+func (g *Grammar) Validate() error {
+	var diags parsec.Diagnostics
+
+	if len(g.Rules) == 0 {
+		return parsec.Diagnostics{g.errorf(0, "no rules")}
+	}
+	rules := make(map[string]bool)
+	for _, rule := range g.Rules {
+		if rules[rule.Name] {
+			diags = append(diags, g.errorf(rule.Pos, "rule %q redefined", rule.Name))
+		}
+		rules[rule.Name] = true
+	}
+	var validate func(expr *Expr)
+	validate = func(expr *Expr) {
+		switch expr.Op {
+		case Ref:
+			if !rules[expr.Text] {
+				diags = append(diags, g.errorf(expr.Pos, "undefined rule %q", expr.Text))
+			}
+		case Regexp:
+			if _, err := regexp.Compile(expr.Text); err != nil {
+				diags = append(diags, g.errorf(expr.Pos, "%v", err))
+			}
+		case Repetition:
+			if expr.Min < 0 || (expr.Max >= 0 && expr.Max < expr.Min) {
+				fmsg := "invalid repetition %v..%v"
+				diags = append(diags, g.errorf(expr.Pos, fmsg, expr.Min, expr.Max))
+			}
+		}
+		for _, arg := range expr.Args {
+			validate(arg)
+		}
+	}
+	for _, rule := range g.Rules {
+		validate(rule.Expr)
+	}
+	if len(diags) > 0 {
+		return diags
+	}
+	return nil
+}
+
+// String return the grammar in PEG notation.
+func (g *Grammar) String() string {
+	lines := make([]string, 0, len(g.Rules))
+	for _, rule := range g.Rules {
+		lines = append(lines, fmt.Sprintf("%v <- %v", rule.Name, rule.Expr))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// String return the expression in PEG notation.
+func (expr *Expr) String() string {
+	var s string
+	switch expr.Op {
+	case Sequence, Choice:
+		sep := " "
+		if expr.Op == Choice {
+			sep = " / "
+		}
+		items := make([]string, 0, len(expr.Args))
+		for _, arg := range expr.Args {
+			items = append(items, arg.operand(expr.Op))
+		}
+		s = strings.Join(items, sep)
+	case Repetition:
+		switch operand := expr.Args[0].operand(expr.Op); {
+		case expr.Min == 0 && expr.Max < 0:
+			s = operand + "*"
+		case expr.Min == 1 && expr.Max < 0:
+			s = operand + "+"
+		case expr.Min == 0 && expr.Max == 1:
+			s = operand + "?"
+		case expr.Max < 0:
+			s = fmt.Sprintf("%v{%v,}", operand, expr.Min)
+		case expr.Min == expr.Max:
+			s = fmt.Sprintf("%v{%v}", operand, expr.Min)
+		default:
+			s = fmt.Sprintf("%v{%v,%v}", operand, expr.Min, expr.Max)
+		}
+	case Lookahead:
+		s = "&" + expr.Args[0].operand(expr.Op)
+	case Not:
+		s = "!" + expr.Args[0].operand(expr.Op)
+	case Literal:
+		s = strconv.Quote(expr.Text)
+		if expr.Fold {
+			s += "i"
+		}
+	case Regexp:
+		s = "~" + quoteregexp(expr.Text)
+	case Ref:
+		s = expr.Text
+	}
+	if expr.Label != "" && precedence(expr.Op) < precedence(Lookahead) {
+		return expr.Label + ":(" + s + ")"
+	} else if expr.Label != "" {
+		return expr.Label + ":" + s
+	}
+	return s
+}
+
+// operand return the expression as operand of op, in parenthesis if
+// required. Named captures bind looser than predicates and repetition.
+func (expr *Expr) operand(op Op) string {
+	s := expr.String()
+	if expr.Label != "" && precedence(op) <= precedence(Sequence) {
+		return s
+	} else if expr.Label == "" && precedence(expr.Op) > precedence(op) {
+		return s
+	}
+	return "(" + s + ")"
+}
+
+// quoteregexp in double quotes, escaping quotes that are not already
+// escaped, pattern is otherwise used verbatim.
+func quoteregexp(pattern string) string {
+	buf := make([]byte, 0, len(pattern)+2)
+	buf = append(buf, '"')
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '\\' && i+1 < len(pattern):
+			buf, i = append(buf, c, pattern[i+1]), i+1
+		case c == '"':
+			buf = append(buf, '\\', c)
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return string(append(buf, '"'))
+}
+
+func precedence(op Op) int {
+	switch op {
+	case Choice:
+		return 0
+	case Sequence:
+		return 1
+	case Lookahead, Not:
+		return 2
+	case Repetition:
+		return 3
+	}
+	return 4
+}
+
+// errorf return a *parsec.ParseError for grammar text at pos.
+func (g *Grammar) errorf(pos int, fmsg string, args ...interface{}) *parsec.ParseError {
+	err := &parsec.ParseError{Cursor: pos, Message: fmt.Sprintf(fmsg, args...)}
+	if g.Text != nil && pos <= len(g.Text) {
+		text := string(g.Text[:pos])
+		err.Lineno = strings.Count(text, "\n") + 1
+		err.Column = utf8.RuneCountInString(text[strings.LastIndex(text, "\n")+1:]) + 1
+	}
+	return err
+}
@@ -0,0 +1,204 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package peg
+
+import "fmt"
+import "reflect"
+import "strings"
+import "testing"
+
+import "github.com/prataprc/goparsec"
+
+var pegText = []byte(`
+# arithmetic expressions.
+Sum     <- Product (op:("+" / "-") Product)*
+Product <- Value (op:("*" / "/") Value)*
+Value   <- Number / "(" Sum ")"
+Number  <- ~"[0-9]+"
+`)
+
+var ebnfText = []byte(`
+(* arithmetic expressions. *)
+Sum     = Product, { op:("+" | "-"), Product } ;
+Product ::= Value { op:("*" | "/") Value }
+Value   := Number | "(" Sum ")" ;
+Number  = ~"[0-9]+" ;
+`)
+
+func TestCompile(t *testing.T) {
+	ref := "Sum(Product(Number:1@0 Product()) Sum(Sum(op:+@2 " +
+		"Product(Number:2@4 Product(Product(op:*@6 Value((:(@8 " +
+		"Sum(Product(Number:3@9 Product()) Sum(Sum(op:-@11 " +
+		"Product(Number:4@13 Product())))) ):)@14)))))))"
+	for _, text := range [][]byte{pegText, ebnfText} {
+		ast := parsec.NewAST("expr", 100)
+		y, err := Compile(ast, text)
+		if err != nil {
+			t.Fatalf("unexpected %v", err)
+		}
+		root, s := ast.Parsewith(y, parsec.NewScanner([]byte("1 + 2 * (3 - 4)")))
+		if ast.Error() != nil {
+			t.Fatalf("unexpected %v", ast.Error())
+		} else if out := dumpquery(root); out != ref {
+			t.Errorf("expected %v, got %v", ref, out)
+		} else if !s.Endof() {
+			t.Errorf("expected end of text")
+		}
+	}
+}
+
+func TestNotations(t *testing.T) {
+	peg, err := Parse(pegText)
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+	ebnf, err := Parse(ebnfText)
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+	if x, y := peg.String(), ebnf.String(); x != y {
+		t.Errorf("expected %v, got %v", x, y)
+	}
+	ref := `Sum <- Product (op:("+" / "-") Product)*`
+	if line := strings.Split(peg.String(), "\n")[0]; line != ref {
+		t.Errorf("expected %v, got %v", ref, line)
+	}
+}
+
+func TestString(t *testing.T) {
+	text := `
+	A <- B / C D
+	B <- !"x\n" &C ("a"i / 'b') c:C{2,} d:(C D)? [a-z]
+	C <- ~"[\"0-9]+" . D{,3} D{1,2} D{3}
+	D <- ("a" "b")+ (C / D) "é\t"`
+	g, err := Parse([]byte(text))
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+	regen, err := Parse([]byte(g.String()))
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+	if x, y := g.String(), regen.String(); x != y {
+		t.Errorf("expected %v, got %v", x, y)
+	}
+	d := g.Rule("D").Expr.Args[2]
+	if d.Op != Literal || d.Text != "é\t" {
+		t.Errorf("expected %q, got %q", "é\t", d.Text)
+	}
+	rep := g.Rule("C").Expr.Args[2]
+	if rep.Op != Repetition || rep.Min != 0 || rep.Max != 3 {
+		t.Errorf("expected %v, got %v", "{0,3}", rep)
+	}
+	if g.Rule("E") != nil {
+		t.Errorf("expected nil")
+	}
+}
+
+func TestTerminals(t *testing.T) {
+	text := `
+	words   <- (!end word)* end
+	word    <- kw:"select"i / id:~"[a-z]+" / num:[0-9]+ / quote:("'" (!"'" .)* "'")
+	end     <- ";" !.`
+	ast := parsec.NewAST("words", 100)
+	y, err := Compile(ast, []byte(text))
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+	root, _ := ast.Parsewith(y, parsec.NewScanner([]byte("SELECT a 12 'x y';")))
+	// terminals skip leading white space, hence space in quote is skipped.
+	ref := "words(words(words(kw:SELECT@0) words(id:a@7) " +
+		"words(num([0-9]:1@9 [0-9]:2@10)) words(quote(':'@12 " +
+		"quote(quote((?s).:x@13) quote((?s).:y@15)) ':'@16))) end(;:;@17))"
+	if ast.Error() != nil {
+		t.Fatalf("unexpected %v", ast.Error())
+	} else if out := dumpquery(root); out != ref {
+		t.Errorf("expected %v, got %v", ref, out)
+	}
+
+	ast = parsec.NewAST("words", 100)
+	y, _ = Compile(ast, []byte(text))
+	if ast.Parsewith(y, parsec.NewScanner([]byte("a;b"))); ast.Error() == nil {
+		t.Errorf("expected error")
+	}
+}
+
+func TestLeftRecursion(t *testing.T) {
+	text := `Sum <- Sum "+" Num / Num
+	Num <- ~"[0-9]+"`
+	ast := parsec.NewAST("sum", 100)
+	y, err := Compile(ast, []byte(text))
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+	root, _ := ast.Parsewith(y, parsec.NewScanner([]byte("1 + 2 + 3")))
+	ref := "Sum(Sum(Num:1@0 +:+@2 Num:2@4) +:+@6 Num:3@8)"
+	if ast.Error() != nil {
+		t.Fatalf("unexpected %v", ast.Error())
+	} else if out := dumpquery(root); out != ref {
+		t.Errorf("expected %v, got %v", ref, out)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	g, err := Parse(pegText)
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+	ast := parsec.NewAST("expr", 100)
+	y, err := g.Build(ast, "Number")
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+	root, _ := ast.Parsewith(y, parsec.NewScanner([]byte("42")))
+	if ref, out := "Number:42@0", dumpquery(root); out != ref {
+		t.Errorf("expected %v, got %v", ref, out)
+	}
+	if _, err := g.Build(parsec.NewAST("expr", 100), "Term"); err == nil {
+		t.Errorf("expected error")
+	}
+}
+
+func TestErrors(t *testing.T) {
+	testcases := []struct {
+		text string
+		err  string
+	}{
+		{"A <- \"a\"\nB <- (\"b\"", "parse error at line 2 col 10, expected one of"},
+		{"A <- B C\nB <- \"b\"", `parse error at line 1 col 8, undefined rule "C"`},
+		{"A <- ~\"[a-\"", "parse error at line 1 col 6, error parsing regexp"},
+		{"A <- \"a\"\nA <- \"b\"", `parse error at line 2 col 1, rule "A" redefined`},
+		{"A <- \"a\"{3,2}", "parse error at line 1 col 6, invalid repetition 3..2"},
+		{"# nothing", "parse error at line 1 col 10, expected"},
+		{"A = \"a\" | ;", "parse error at line 1 col 11, expected one of"},
+	}
+	for _, tcase := range testcases {
+		_, err := Parse([]byte(tcase.text))
+		if err == nil {
+			t.Errorf("%q expected error", tcase.text)
+		} else if !strings.HasPrefix(err.Error(), tcase.err) {
+			t.Errorf("%q expected %v, got %v", tcase.text, tcase.err, err)
+		}
+	}
+
+	g := &Grammar{}
+	if err := g.Validate(); err == nil {
+		t.Errorf("expected error")
+	} else if _, ok := err.(parsec.Diagnostics); !ok {
+		t.Errorf("expected %v, got %v", reflect.TypeOf(parsec.Diagnostics{}), err)
+	}
+}
+
+func dumpquery(q parsec.Queryable) string {
+	if q == nil {
+		return "<nil>"
+	} else if q.IsTerminal() {
+		return fmt.Sprintf("%v:%v@%v", q.GetName(), q.GetValue(), q.GetPosition())
+	}
+	children := make([]string, 0)
+	for _, child := range q.GetChildren() {
+		children = append(children, dumpquery(child))
+	}
+	return fmt.Sprintf("%v(%v)", q.GetName(), strings.Join(children, " "))
+}
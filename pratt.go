@@ -0,0 +1,336 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+// Assoc specifies the associativity of an infix operator.
+type Assoc int
+
+const (
+	// AssocLeft operators group from left, `a - b - c` is `(a - b) - c`.
+	AssocLeft Assoc = iota
+	// AssocRight operators group from right, `a ^ b ^ c` is `a ^ (b ^ c)`.
+	AssocRight
+	// AssocNone operators don't chain, `a < b < c` will parse `a < b`.
+	AssocNone
+)
+
+const (
+	opPrefix = iota + 1
+	opInfix
+	opPostfix
+	opTernary
+)
+
+// Pratt builds an expression parser using operator precedence, a.k.a
+// Pratt parsing, on top of an operand parser. Operators are registered
+// with a binding power, operators with higher binding power bind
+// tighter. Same operator token can be registered both as prefix and
+// infix, like `-`, and shall be disambiguated by its position in the
+// expression. Typically used as:
+//
+//	y := NewPratt(Int()).
+//		Parens(Atom("(", "OPEN"), Atom(")", "CLOSE")).
+//		Infix(Atom("+", "ADD"), 10, AssocLeft, addNode).
+//		Infix(Atom("*", "MUL"), 20, AssocLeft, mulNode).
+//		Prefix(Atom("-", "NEG"), 30, negNode).
+//		Parser()
+type Pratt struct {
+	name        string
+	operand     interface{}
+	open, close interface{}
+	group       func(s Scanner, ns []ParsecNode) ParsecNode
+	ops         []*prattop
+}
+
+type prattop struct {
+	kind    int
+	op, op2 interface{}
+	bp      int
+	assoc   Assoc
+	build   func(s Scanner, ns []ParsecNode) ParsecNode
+}
+
+// NewPratt return a builder for an expression parser using operand
+// parser, or reference to a parser, to match the operands.
+func NewPratt(operand interface{}) *Pratt {
+	return &Pratt{operand: operand}
+}
+
+// Parens register the delimiters for grouping a sub-expression. Node
+// from the sub-expression is returned as is, dropping the delimiters.
+func (pr *Pratt) Parens(open, close interface{}) *Pratt {
+	pr.open, pr.close = open, close
+	pr.group = func(_ Scanner, ns []ParsecNode) ParsecNode { return ns[1] }
+	return pr
+}
+
+// Prefix register a prefix operator, its callback is dispatched with
+// operator node and operand node.
+func (pr *Pratt) Prefix(op interface{}, bp int, callb Nodify) *Pratt {
+	return pr.register(&prattop{kind: opPrefix, op: op, bp: bp}, callb)
+}
+
+// Infix register a binary operator, its callback is dispatched with
+// left operand node, operator node and right operand node.
+func (pr *Pratt) Infix(
+	op interface{}, bp int, assoc Assoc, callb Nodify) *Pratt {
+
+	return pr.register(&prattop{kind: opInfix, op: op, bp: bp, assoc: assoc}, callb)
+}
+
+// Postfix register a postfix operator, its callback is dispatched with
+// operand node and operator node.
+func (pr *Pratt) Postfix(op interface{}, bp int, callb Nodify) *Pratt {
+	return pr.register(&prattop{kind: opPostfix, op: op, bp: bp}, callb)
+}
+
+// Ternary register a right associative ternary operator, like
+// `cond ? a : b`, its callback is dispatched with condition node, op1
+// node, first operand node, op2 node and second operand node.
+func (pr *Pratt) Ternary(op1, op2 interface{}, bp int, callb Nodify) *Pratt {
+	op := &prattop{kind: opTernary, op: op1, op2: op2, bp: bp, assoc: AssocRight}
+	return pr.register(op, callb)
+}
+
+// Parser return the expression parser. Operators registered after
+// this call are also applicable.
+func (pr *Pratt) Parser() Parser {
+	y := memoize(nil, func(s Scanner) (ParsecNode, Scanner) {
+		return pr.parse(s, 0)
+	})
+	return func(s Scanner) (ParsecNode, Scanner) {
+		if _, ok := s.(probe); ok { // operators can be registered later.
+			return pr.structure(), s
+		}
+		return y(s)
+	}
+}
+
+func (pr *Pratt) register(op *prattop, callb Nodify) *Pratt {
+	op.build = func(_ Scanner, ns []ParsecNode) ParsecNode {
+		return docallback(callb, ns)
+	}
+	pr.ops = append(pr.ops, op)
+	return pr
+}
+
+// ASTPratt is same as Pratt, but constructs NonTerminal nodes named
+// after the operator, with operator and operand nodes as its children.
+type ASTPratt struct {
+	ast   *AST
+	pratt *Pratt
+}
+
+// Pratt return a builder for an expression parser, named `name`, using
+// operand parser, or reference to a parser, to match the operands. Refer
+// to Pratt type for details.
+func (ast *AST) Pratt(name string, operand interface{}) *ASTPratt {
+	pr := NewPratt(operand)
+	pr.name = name
+	return &ASTPratt{ast: ast, pratt: pr}
+}
+
+// Parens register the delimiters for grouping a sub-expression,
+// constructing `name` NonTerminal with delimiters and sub-expression as
+// its children.
+func (ap *ASTPratt) Parens(
+	name string, open, close interface{}, callb ASTNodify) *ASTPratt {
+
+	ap.pratt.open, ap.pratt.close = open, close
+	ap.pratt.group = ap.ast.builder(name, callb)
+	return ap
+}
+
+// Prefix register a prefix operator, `name` identifies the NonTerminal
+// constructed for this operator.
+func (ap *ASTPratt) Prefix(
+	name string, op interface{}, bp int, callb ASTNodify) *ASTPratt {
+
+	return ap.register(name, &prattop{kind: opPrefix, op: op, bp: bp}, callb)
+}
+
+// Infix register a binary operator, `name` identifies the NonTerminal
+// constructed for this operator.
+func (ap *ASTPratt) Infix(
+	name string, op interface{}, bp int, assoc Assoc,
+	callb ASTNodify) *ASTPratt {
+
+	op2 := &prattop{kind: opInfix, op: op, bp: bp, assoc: assoc}
+	return ap.register(name, op2, callb)
+}
+
+// Postfix register a postfix operator, `name` identifies the
+// NonTerminal constructed for this operator.
+func (ap *ASTPratt) Postfix(
+	name string, op interface{}, bp int, callb ASTNodify) *ASTPratt {
+
+	return ap.register(name, &prattop{kind: opPostfix, op: op, bp: bp}, callb)
+}
+
+// Ternary register a right associative ternary operator, `name`
+// identifies the NonTerminal constructed for this operator.
+func (ap *ASTPratt) Ternary(
+	name string, op1, op2 interface{}, bp int, callb ASTNodify) *ASTPratt {
+
+	op := &prattop{kind: opTernary, op: op1, op2: op2, bp: bp, assoc: AssocRight}
+	return ap.register(name, op, callb)
+}
+
+// Parser return the expression parser.
+func (ap *ASTPratt) Parser() Parser {
+	y := ap.pratt.Parser()
+	return func(s Scanner) (ParsecNode, Scanner) {
+		if _, ok := s.(probe); ok || ap.ast.tracer == nil {
+			return y(s)
+		}
+		return ap.ast.trace("Pratt", ap.pratt.name, y, s)
+	}
+}
+
+func (ap *ASTPratt) register(
+	name string, op *prattop, callb ASTNodify) *ASTPratt {
+
+	op.build = ap.ast.builder(name, callb)
+	ap.pratt.ops = append(ap.pratt.ops, op)
+	return ap
+}
+
+//---- local functions
+
+// structure of the expression parser, operand, prefix operators and
+// parenthesis can match first.
+func (pr *Pratt) structure() *structure {
+	st := &structure{
+		kind: "Pratt", name: pr.name, parsers: []interface{}{pr.operand},
+		pratt: pr,
+	}
+	if pr.open != nil {
+		st.parsers = append(st.parsers, pr.open)
+		st.after = append(st.after, pr.close)
+	}
+	for _, op := range pr.ops {
+		if op.kind == opPrefix {
+			st.parsers = append(st.parsers, op.op)
+		} else {
+			st.after = append(st.after, nonnil(op.op, op.op2)...)
+		}
+	}
+	return st
+}
+
+// binding powers, for left and right side of the operator.
+func (op *prattop) bindings() (lbp, rbp int) {
+	switch {
+	case op.kind == opPrefix:
+		return -1, 2 * op.bp
+	case op.assoc == AssocRight:
+		return 2*op.bp + 1, 2 * op.bp
+	}
+	return 2 * op.bp, 2*op.bp + 1
+}
+
+// parse an expression whose operators bind atleast minbp. Limits are
+// checked for every sub-expression, like nested parenthesis and
+// prefix operators, that are parsed recursively.
+func (pr *Pratt) parse(s Scanner, minbp int) (ParsecNode, Scanner) {
+	if lim := enterlimits(s); lim != nil {
+		defer lim.exit()
+	}
+	left, news := pr.operandof(s)
+	if left == nil {
+		return nil, s
+	}
+	nonassoc := -1
+	for {
+		node, next, op := pr.operator(left, news, minbp, nonassoc)
+		if node == nil {
+			return left, news
+		}
+		nonassoc = -1
+		if op.kind == opInfix && op.assoc == AssocNone {
+			nonassoc = op.bp
+		}
+		left, news = node, next
+	}
+}
+
+// operandof match a prefix expression, a sub-expression within
+// parenthesis or an operand.
+func (pr *Pratt) operandof(s Scanner) (ParsecNode, Scanner) {
+	for _, op := range pr.ops {
+		if op.kind != opPrefix {
+			continue
+		}
+		n, news := doParse(op.op, s.Clone())
+		if n == nil {
+			continue
+		}
+		_, rbp := op.bindings()
+		if operand, news := pr.parse(news, rbp); operand != nil {
+			if node := op.build(news, []ParsecNode{n, operand}); node != nil {
+				return node, news
+			}
+		}
+	}
+	if pr.open != nil {
+		if open, news := doParse(pr.open, s.Clone()); open != nil {
+			if expr, news := pr.parse(news, 0); expr != nil {
+				if close, news := doParse(pr.close, news); close != nil {
+					ns := []ParsecNode{open, expr, close}
+					if node := pr.group(news, ns); node != nil {
+						return node, news
+					}
+				}
+			}
+		}
+	}
+	return doParse(pr.operand, s.Clone())
+}
+
+// operator match an infix, postfix or ternary operator and its right
+// side operands, whose left binding power is atleast minbp.
+func (pr *Pratt) operator(
+	left ParsecNode, s Scanner,
+	minbp, nonassoc int) (ParsecNode, Scanner, *prattop) {
+
+	for _, op := range pr.ops {
+		lbp, rbp := op.bindings()
+		if op.kind == opPrefix || lbp < minbp {
+			continue
+		} else if op.kind == opInfix && op.assoc == AssocNone && op.bp == nonassoc {
+			continue
+		}
+		n, news := doParse(op.op, s.Clone())
+		if n == nil {
+			continue
+		}
+		var ns []ParsecNode
+		switch op.kind {
+		case opPostfix:
+			ns = []ParsecNode{left, n}
+
+		case opInfix:
+			var right ParsecNode
+			if right, news = pr.parse(news, rbp); right == nil {
+				continue
+			}
+			ns = []ParsecNode{left, n, right}
+
+		case opTernary:
+			var mid, n2, right ParsecNode
+			if mid, news = pr.parse(news, 0); mid == nil {
+				continue
+			} else if n2, news = doParse(op.op2, news); n2 == nil {
+				continue
+			} else if right, news = pr.parse(news, rbp); right == nil {
+				continue
+			}
+			ns = []ParsecNode{left, n, mid, n2, right}
+		}
+		if node := op.build(news, ns); node != nil {
+			return node, news, op
+		}
+	}
+	return nil, s, nil
+}
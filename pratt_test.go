@@ -0,0 +1,146 @@
+package parsec
+
+import "strconv"
+import "testing"
+
+func TestPratt(t *testing.T) {
+	y := makeprattcalc()
+	testcases := []struct {
+		text   string
+		value  int
+		cursor int
+	}{
+		{"10", 10, 2},
+		{"1 - 2 - 3", -4, 9},
+		{"2 ^ 3 ^ 2", 512, 9},
+		{"- 2 ^ 2", -4, 7},
+		{"1 - -2", 3, 6},
+		{"-3!", -6, 3},
+		{"1 + 2 * 3 - 4", 3, 13},
+		{"(1 + 2) * 3", 9, 11},
+		{"2 * (3 - (4 - 5))!", 48, 18},
+		{"1 < 2 ? 10 : 20", 10, 15},
+		{"1 ? 0 ? 1 : 2 : 3", 2, 17},
+		{"1 < 2 < 3", 1, 5}, // non associative
+		{"1 + ", 1, 1},      // backtrack
+	}
+	for _, tcase := range testcases {
+		node, s := y(NewScanner([]byte(tcase.text)))
+		if node == nil {
+			t.Errorf("%q failed", tcase.text)
+		} else if node.(int) != tcase.value {
+			t.Errorf("%q expected %v, got %v", tcase.text, tcase.value, node)
+		} else if s.GetCursor() != tcase.cursor {
+			t.Errorf("%q expected %v, got %v", tcase.text, tcase.cursor, s.GetCursor())
+		}
+	}
+
+	// negative cases
+	for _, text := range []string{"", "+ 1", "(1 + 2", "1 ? 2"} {
+		node, s := And(nil, y, End())(NewScanner([]byte(text)))
+		if node != nil {
+			t.Errorf("%q unexpected %v", text, node)
+		} else if s.GetCursor() != 0 {
+			t.Errorf("%q expected %v, got %v", text, 0, s.GetCursor())
+		}
+	}
+}
+
+func TestASTPratt(t *testing.T) {
+	ast := NewAST("testpratt", 100)
+	sub := Atom("-", "SUB")
+	y := ast.Pratt("expr", Int()).
+		Parens("group", Atom("(", "OPEN"), Atom(")", "CLOSE"), nil).
+		Infix("sub", sub, 10, AssocLeft, nil).
+		Infix("mul", Atom("*", "MUL"), 20, AssocLeft, nil).
+		Prefix("neg", sub, 30, nil).
+		Postfix("fact", Atom("!", "FACT"), 40, nil).
+		Ternary("cond", Atom("?", "QN"), Atom(":", "COLON"), 5, nil).
+		Parser()
+
+	text := "1 - -(2 - 3) * 4! ? 5 : 6"
+	node, s := ast.Parsewith(y, NewScanner([]byte(text)))
+	if node == nil {
+		t.Fatalf("expected node")
+	} else if !s.Endof() {
+		t.Errorf("expected end of text")
+	}
+	ref := "cond(sub(INT SUB mul(neg(SUB group(OPEN sub(INT SUB INT) CLOSE)) " +
+		"MUL fact(INT FACT))) QN INT COLON INT)"
+	if out := sexpr(node); out != ref {
+		t.Errorf("expected %v", ref)
+		t.Errorf("got %v", out)
+	} else if node.GetValue() != "1--(2-3)*4!?5:6" {
+		t.Errorf("unexpected %v", node.GetValue())
+	}
+}
+
+func makeprattcalc() Parser {
+	num := func(n ParsecNode) int {
+		return n.(int)
+	}
+	operand := And(
+		func(ns []ParsecNode) ParsecNode {
+			n, _ := strconv.Atoi(ns[0].(*Terminal).Value)
+			return n
+		},
+		Int(),
+	)
+	sub := Atom("-", "SUB")
+	return NewPratt(operand).
+		Parens(Atom("(", "OPEN"), Atom(")", "CLOSE")).
+		Ternary(Atom("?", "QN"), Atom(":", "COLON"), 1,
+			func(ns []ParsecNode) ParsecNode {
+				if num(ns[0]) != 0 {
+					return ns[2]
+				}
+				return ns[4]
+			}).
+		Infix(Atom("<", "LT"), 5, AssocNone,
+			func(ns []ParsecNode) ParsecNode {
+				if num(ns[0]) < num(ns[2]) {
+					return 1
+				}
+				return 0
+			}).
+		Infix(Atom("+", "ADD"), 10, AssocLeft,
+			func(ns []ParsecNode) ParsecNode { return num(ns[0]) + num(ns[2]) }).
+		Infix(sub, 10, AssocLeft,
+			func(ns []ParsecNode) ParsecNode { return num(ns[0]) - num(ns[2]) }).
+		Infix(Atom("*", "MUL"), 20, AssocLeft,
+			func(ns []ParsecNode) ParsecNode { return num(ns[0]) * num(ns[2]) }).
+		Prefix(sub, 30,
+			func(ns []ParsecNode) ParsecNode { return -num(ns[1]) }).
+		Infix(Atom("^", "POW"), 40, AssocRight,
+			func(ns []ParsecNode) ParsecNode {
+				x := 1
+				for i := 0; i < num(ns[2]); i++ {
+					x *= num(ns[0])
+				}
+				return x
+			}).
+		Postfix(Atom("!", "FACT"), 50,
+			func(ns []ParsecNode) ParsecNode {
+				x := 1
+				for i := 2; i <= num(ns[0]); i++ {
+					x *= i
+				}
+				return x
+			}).
+		Parser()
+}
+
+// sexpr return the syntax-tree as s-expression of node names.
+func sexpr(node Queryable) string {
+	if node.IsTerminal() {
+		return node.GetName()
+	}
+	out := node.GetName() + "("
+	for i, child := range node.GetChildren() {
+		if i > 0 {
+			out += " "
+		}
+		out += sexpr(child)
+	}
+	return out + ")"
+}
@@ -7,6 +7,7 @@ import "regexp"
 import "reflect"
 import "unsafe"
 import "unicode"
+import "unicode/utf8"
 import "bytes"
 import "strings"
 
@@ -62,11 +63,13 @@ type Scanner interface {
 // SimpleScanner implements Scanner interface based on
 // golang's regexp module.
 type SimpleScanner struct {
-	buf          []byte // input buffer
-	cursor       int    // cursor within input buffer
-	lineno       int
-	patternCache map[string]*regexp.Regexp
-	wsPattern    string // white space pattern used by SkipWS()
+	buf       []byte // input buffer
+	cursor    int    // cursor within input buffer
+	lineno    int
+	wsPattern string        // white space pattern used by SkipWS()
+	patterns  *patterncache // shared by all clones of this scanner.
+	sess      *session      // shared by all clones of this scanner.
+	state     *userstate    // shared by clones, nil if never set.
 	// settings
 	tracklineno bool
 }
@@ -74,12 +77,13 @@ type SimpleScanner struct {
 // NewScanner create and return a new instance of SimpleScanner object.
 func NewScanner(text []byte) Scanner {
 	return &SimpleScanner{
-		buf:          text,
-		cursor:       0,
-		lineno:       1,
-		patternCache: make(map[string]*regexp.Regexp),
-		wsPattern:    `^[ \t\r\n]+`,
-		tracklineno:  false,
+		buf:         text,
+		cursor:      0,
+		lineno:      1,
+		wsPattern:   `^[ \t\r\n]+`,
+		patterns:    &patterncache{},
+		sess:        newsession(),
+		tracklineno: false,
 	}
 }
 
@@ -100,12 +104,14 @@ func (s *SimpleScanner) TrackLineno() Scanner {
 // Clone implement Scanner{} interface.
 func (s *SimpleScanner) Clone() Scanner {
 	return &SimpleScanner{
-		buf:          s.buf,
-		cursor:       s.cursor,
-		lineno:       s.lineno,
-		patternCache: s.patternCache,
-		wsPattern:    s.wsPattern,
-		tracklineno:  s.tracklineno,
+		buf:         s.buf,
+		cursor:      s.cursor,
+		lineno:      s.lineno,
+		wsPattern:   s.wsPattern,
+		patterns:    s.patterns,
+		sess:        s.sess,
+		state:       s.state,
+		tracklineno: s.tracklineno,
 	}
 }
 
@@ -116,20 +122,13 @@ func (s *SimpleScanner) GetCursor() int {
 
 // Match implement Scanner{} interface.
 func (s *SimpleScanner) Match(pattern string) ([]byte, Scanner) {
-	regc := s.getPattern(pattern)
-	if token := regc.Find(s.buf[s.cursor:]); token != nil {
-		if s.tracklineno && len(token) > 0 {
-			s.lineno += len(bytes.Split(token, []byte{'\n'})) - 1
-		}
-		s.cursor += len(token)
-		return token, s
-	}
-	return nil, s
+	return s.matchregexp(s.patterns.get(pattern))
 }
 
 // MatchString implement Scanner{} interface.
 func (s *SimpleScanner) MatchString(str string) (bool, Scanner) {
 	ln := len(str)
+	s.sess.examine(s.cursor, s.cursor+ln)
 	if len(s.buf[s.cursor:]) < ln {
 		return false, s
 	} else if bytes.Compare(s.buf[s.cursor:s.cursor+ln], []byte(str)) != 0 {
@@ -144,25 +143,7 @@ func (s *SimpleScanner) MatchString(str string) (bool, Scanner) {
 
 // SubmatchAll implement Scanner{} interface.
 func (s *SimpleScanner) SubmatchAll(patt string) (map[string][]byte, Scanner) {
-	regc := s.getPattern(patt)
-	matches := regc.FindSubmatch(s.buf[s.cursor:])
-
-	if matches != nil {
-		captures := make(map[string][]byte)
-		names := regc.SubexpNames()
-		for i, name := range names {
-			if i == 0 || name == "" || matches[i] == nil {
-				continue
-			}
-			captures[name] = matches[i]
-		}
-		if s.tracklineno && len(matches[0]) > 0 {
-			s.lineno += len(bytes.Split(matches[0], []byte{'\n'})) - 1
-		}
-		s.cursor += len(matches[0])
-		return captures, s
-	}
-	return nil, s
+	return s.submatchregexp(s.patterns.get(patt))
 }
 
 // SkipWS implement Scanner{} interface.
@@ -185,6 +166,7 @@ func (s *SimpleScanner) Lineno() int {
 
 // Endof implement Scanner{} interface.
 func (s *SimpleScanner) Endof() bool {
+	s.sess.examine(s.cursor, s.cursor+1)
 	return s.cursor >= len(s.buf)
 }
 
@@ -197,10 +179,12 @@ func (s *SimpleScanner) SkipWSUnicode() ([]byte, Scanner) {
 			}
 			continue
 		}
+		s.sess.examine(s.cursor, s.cursor+i+1)
 		token := s.buf[s.cursor : s.cursor+i]
 		s.cursor += len(token)
 		return token, s
 	}
+	s.sess.examine(s.cursor, len(s.buf)+1)
 	token := s.buf[s.cursor:]
 	s.cursor += len(token)
 	return token, s
@@ -208,17 +192,130 @@ func (s *SimpleScanner) SkipWSUnicode() ([]byte, Scanner) {
 
 //---- local methods
 
-func (s *SimpleScanner) getPattern(pattern string) *regexp.Regexp {
-	regc, ok := s.patternCache[pattern]
-	if !ok {
-		var err error
-		if regc, err = regexp.Compile(pattern); err != nil {
-			panic(err)
+// maxpatterns bound the number of compiled regular expressions cached
+// by a scanner.
+const maxpatterns = 64
+
+// patterncache compile and cache regular expressions passed to Match
+// and SubmatchAll. It is owned by a scanner and its clones, hence not
+// safe for concurrent use. Token, TokenExact and OrdTokens use the
+// process wide cache of compiled patterns and bypass this cache.
+type patterncache struct {
+	regexps map[string]*regexp.Regexp
+}
+
+// get return the compiled regular expression for pattern. Panics if
+// pattern is invalid.
+func (cache *patterncache) get(pattern string) *regexp.Regexp {
+	if regc, ok := cache.regexps[pattern]; ok {
+		return regc
+	}
+	regc, err := regexp.Compile(pattern)
+	if err != nil {
+		panic(err)
+	}
+	if cache.regexps == nil || len(cache.regexps) >= maxpatterns {
+		cache.regexps = make(map[string]*regexp.Regexp)
+	}
+	cache.regexps[pattern] = regc
+	return regc
+}
+
+func (s *SimpleScanner) matchregexp(regc *regexp.Regexp) ([]byte, Scanner) {
+	if token := s.find(regc); token != nil {
+		if s.tracklineno && len(token) > 0 {
+			s.lineno += len(bytes.Split(token, []byte{'\n'})) - 1
+		}
+		s.cursor += len(token)
+		return token, s
+	}
+	return nil, s
+}
+
+func (s *SimpleScanner) submatchregexp(regc *regexp.Regexp) (map[string][]byte, Scanner) {
+	matches := s.findsubmatch(regc)
+
+	if matches != nil {
+		captures := make(map[string][]byte)
+		names := regc.SubexpNames()
+		for i, name := range names {
+			if i == 0 || name == "" || matches[i] == nil {
+				continue
+			}
+			captures[name] = matches[i]
+		}
+		if s.tracklineno && len(matches[0]) > 0 {
+			s.lineno += len(bytes.Split(matches[0], []byte{'\n'})) - 1
 		}
-		s.patternCache[pattern] = regc
+		s.cursor += len(matches[0])
+		return captures, s
 	}
+	return nil, s
+}
 
-	return regc
+// find is same as regc.Find on the remaining input, if the parse is
+// incremental input is read via trackreader to learn the text examined
+// by the regular expression.
+func (s *SimpleScanner) find(regc *regexp.Regexp) []byte {
+	if s.sess.tracker == nil {
+		return regc.Find(s.buf[s.cursor:])
+	}
+	r := &trackreader{buf: s.buf, off: s.cursor}
+	loc := regc.FindReaderIndex(r)
+	s.sess.examine(s.cursor, r.examined())
+	if loc == nil {
+		return nil
+	}
+	return s.buf[s.cursor+loc[0] : s.cursor+loc[1]]
+}
+
+// findsubmatch is same as regc.FindSubmatch on the remaining input,
+// refer to find for incremental parse.
+func (s *SimpleScanner) findsubmatch(regc *regexp.Regexp) [][]byte {
+	if s.sess.tracker == nil {
+		return regc.FindSubmatch(s.buf[s.cursor:])
+	}
+	r := &trackreader{buf: s.buf, off: s.cursor}
+	locs := regc.FindReaderSubmatchIndex(r)
+	s.sess.examine(s.cursor, r.examined())
+	if locs == nil {
+		return nil
+	}
+	matches := make([][]byte, len(locs)/2)
+	for i := range matches {
+		if locs[2*i] >= 0 {
+			matches[i] = s.buf[s.cursor+locs[2*i] : s.cursor+locs[2*i+1]]
+		}
+	}
+	return matches
+}
+
+func (s *SimpleScanner) getsession() *session {
+	return s.sess
+}
+
+func (s *SimpleScanner) getstate() *userstate {
+	return s.state
+}
+
+func (s *SimpleScanner) setstate(value interface{}) {
+	state := &userstate{value: value, version: s.sess.nextversion()}
+	if s.state != nil {
+		state.indents = s.state.indents
+	}
+	s.state = state
+}
+
+func (s *SimpleScanner) linecol(cursor int) (lineno, column int) {
+	if cursor > len(s.buf) {
+		cursor = len(s.buf)
+	}
+	text := s.buf[:cursor]
+	lineno = bytes.Count(text, []byte{'\n'}) + 1
+	if i := bytes.LastIndexByte(text, '\n'); i >= 0 {
+		text = text[i+1:]
+	}
+	return lineno, utf8.RuneCount(text) + 1
 }
 
 func (s *SimpleScanner) resetcursor() {
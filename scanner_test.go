@@ -53,6 +53,34 @@ func TestSubmatchAll(t *testing.T) {
 	}
 }
 
+func TestPatternCache(t *testing.T) {
+	s := NewScanner([]byte(strings.Repeat("a", 200)))
+	for i := 1; i <= 200; i++ {
+		if tok, _ := s.Clone().Match(fmt.Sprintf("^a{%v}", i)); len(tok) != i {
+			t.Errorf("expected %v, got %v", i, len(tok))
+		}
+	}
+	cache := s.(*SimpleScanner).patterns
+	if n := len(cache.regexps); n == 0 || n > maxpatterns {
+		t.Errorf("expected upto %v patterns, got %v", maxpatterns, n)
+	}
+
+	// tokens compile their pattern upfront, and bypass the cache.
+	s = NewScanner([]byte("hello world"))
+	Token(`[a-z]+`, "WORD")(s)
+	if n := len(s.(*SimpleScanner).patterns.regexps); n != 1 { // SkipWS
+		t.Errorf("expected %v, got %v", 1, n)
+	}
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("expected panic")
+			}
+		}()
+		Token(`[a-z`, "WORD")
+	}()
+}
+
 func TestMatchString(t *testing.T) {
 	text := `myString0`
 
@@ -0,0 +1,83 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+// User state, like symbol tables, declared type names, indentation
+// levels etc.. can be threaded through a parse via scanner. State is
+// carried by the scanner and copied to its clones, hence when a
+// combinator backtracks to an earlier scanner, the earlier state is
+// restored as well. For this to work, state values shall be treated
+// as immutable, and updates shall create a new value:
+//
+//	names := GetState(s).(map[string]bool)
+//	update := map[string]bool{name: true}
+//	for name := range names {
+//		update[name] = true
+//	}
+//	s = SetState(s, update)
+//
+// Parser functions can access state on their input scanner, while
+// ASTNodify callbacks can access state on the scanner passed to them.
+
+// GetState return the user state carried by scanner s, return nil if
+// state was never set, or if s does not support user state.
+func GetState(s Scanner) interface{} {
+	if x, ok := s.(stateful); ok {
+		if state := x.getstate(); state != nil {
+			return state.value
+		}
+	}
+	return nil
+}
+
+// SetState set the user state carried by scanner s, and return s.
+// Scanners that do not support user state, like custom scanners, are
+// returned as is.
+func SetState(s Scanner, value interface{}) Scanner {
+	if x, ok := s.(stateful); ok {
+		x.setstate(value)
+	}
+	return s
+}
+
+// userstate is the user state and indentation levels carried by a
+// scanner, allocated only when either is set. It is shared by clones,
+// hence replaced and never modified in place. It is identified by a
+// version unique within the parse session, so that memoized results are
+// not shared across states.
+type userstate struct {
+	value   interface{}
+	indents []int // stack of indentation levels.
+	version int64
+}
+
+// stateful is implemented by scanners that can carry user state.
+type stateful interface {
+	getstate() *userstate // nil if state was never set.
+	setstate(value interface{})
+}
+
+// stateof return the version of user state carried by scanner s.
+func stateof(s Scanner) int64 {
+	if x, ok := s.(stateful); ok {
+		if state := x.getstate(); state != nil {
+			return state.version
+		}
+	}
+	return 0
+}
+
+// states generate versions of user state, unique within a parse session.
+type states struct {
+	version int64
+}
+
+// nextversion return a new version for user state.
+func (sess *session) nextversion() int64 {
+	if sess.states == nil {
+		sess.states = &states{}
+	}
+	sess.states.version++
+	return sess.states.version
+}
@@ -0,0 +1,88 @@
+package parsec
+
+import "reflect"
+import "testing"
+
+func TestState(t *testing.T) {
+	var typename, declare Parser
+
+	// typedef-name ambiguity: `T * x;` is a declaration if T is a type.
+	typename = func(s Scanner) (ParsecNode, Scanner) {
+		node, news := Ident()(s)
+		if node != nil {
+			if types, _ := GetState(news).(map[string]bool); types[node.(*Terminal).Value] {
+				return node, news
+			}
+		}
+		return nil, s
+	}
+	declare = func(s Scanner) (ParsecNode, Scanner) {
+		node, news := Ident()(s)
+		if node != nil {
+			types, _ := GetState(news).(map[string]bool)
+			update := map[string]bool{node.(*Terminal).Value: true}
+			for name := range types {
+				update[name] = true
+			}
+			news = SetState(news, update)
+		}
+		return node, news
+	}
+	semi := Atom(";", "SEMI")
+	typedef := And(func(_ []ParsecNode) ParsecNode { return "typedef" },
+		Atom("typedef", "TYPEDEF"), declare, semi)
+	decl := And(func(_ []ParsecNode) ParsecNode { return "decl" },
+		typename, Atom("*", "STAR"), Ident(), semi)
+	expr := And(func(_ []ParsecNode) ParsecNode { return "expr" },
+		Ident(), Atom("*", "STAR"), Ident(), semi)
+	// typedef that fails after declaring the name, shall be forgotten.
+	bad := And(nil, Atom("typedef", "TYPEDEF"), declare, Atom("!", "BANG"))
+	y := Kleene(nil, OrdChoice(one2one, bad, typedef, decl, expr))
+
+	text := "U * x; typedef T; T * x; U * y;"
+	ref := []ParsecNode{"expr", "typedef", "decl", "expr"}
+	for _, s := range []Scanner{
+		NewScanner([]byte(text)),
+		Packrat(NewScanner([]byte(text)), 1000),
+	} {
+		node, news := y(s)
+		if nodes := node.([]ParsecNode); !reflect.DeepEqual(nodes, ref) {
+			t.Errorf("expected %v, got %v", ref, nodes)
+		} else if !news.Endof() {
+			t.Errorf("expected end of text")
+		} else if types := GetState(news).(map[string]bool); len(types) != 1 {
+			t.Errorf("unexpected %v", types)
+		}
+	}
+
+	// initial state
+	s := SetState(NewScanner([]byte("U * y;")), map[string]bool{"U": true})
+	if node, _ := y(s); node.([]ParsecNode)[0] != "decl" {
+		t.Errorf("expected %v, got %v", "decl", node)
+	} else if GetState(NewScanner(nil)) != nil {
+		t.Errorf("expected nil")
+	}
+}
+
+func TestASTState(t *testing.T) {
+	ast := NewAST("teststate", 100)
+	depth := func(name string, s Scanner, node Queryable) Queryable {
+		level, _ := GetState(s).(int)
+		node.SetAttribute("depth", string(rune('0'+level)))
+		SetState(s, level+1)
+		return node
+	}
+	open := ast.And("open", depth, Atom("(", "OPEN"))
+	y := ast.Kleene("opens", nil, open)
+
+	node, s := ast.Parsewith(y, NewScanner([]byte("(((")))
+	depths := []string{}
+	for _, child := range node.GetChildren() {
+		depths = append(depths, child.GetAttribute("depth")...)
+	}
+	if ref := []string{"0", "1", "2"}; !reflect.DeepEqual(depths, ref) {
+		t.Errorf("expected %v, got %v", ref, depths)
+	} else if GetState(s).(int) != 3 {
+		t.Errorf("expected %v, got %v", 3, GetState(s))
+	}
+}
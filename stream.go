@@ -0,0 +1,317 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "fmt"
+import "io"
+import "bytes"
+import "regexp"
+import "unicode/utf8"
+
+// size of a single read from the underlying io.Reader.
+const streamchunk = 32 * 1024
+
+// StreamScanner implements Scanner interface over an io.Reader, input
+// is read lazily as parsers advance the cursor. Clones of the scanner
+// share the buffered input, and backtracking is supported within the
+// window of input buffered since the last commit point. Use Commit
+// combinator to discard input consumed so far, after which backtracking
+// to a cursor before the commit point aborts the parse with a
+// *ParseError. For example, parsing a log file record by record,
+//
+//	y := Kleene(nil, And(nil, record, Commit()))
+//	node, s, err := Parse(y, NewStreamScanner(file))
+//
+// Cursor positions, hence Terminal positions and ParseError, are
+// offsets from the beginning of the stream. Token, Atom, OrdTokens and
+// other parsers that use the Scanner interface work with StreamScanner.
+// Packrat parsing is not recommended, since the cache retains nodes for
+// the entire stream.
+//
+// Clones are plain values, and the scanner can't learn when a clone is
+// no longer referenced, hence buffered input is discarded only at
+// commit points. A stream parsed without Commit buffers all of its
+// input.
+type StreamScanner struct {
+	stream    *stream // shared by all clones of this scanner.
+	cursor    int     // offset from the beginning of the stream.
+	lineno    int
+	wsPattern string        // white space pattern used by SkipWS()
+	patterns  *patterncache // shared by all clones of this scanner.
+	sess      *session      // shared by all clones of this scanner.
+	state     *userstate    // shared by clones, nil if never set.
+	// settings
+	tracklineno bool
+}
+
+// stream buffers input from io.Reader.
+type stream struct {
+	r      io.Reader
+	buf    []byte // buffered input, starting at offset base.
+	base   int    // offset of buf[0] from the beginning of the stream.
+	err    error  // error from the last read, io.EOF at end of stream.
+	lines  int    // number of newlines in the discarded input.
+	column int    // number of runes after the last discarded newline.
+}
+
+// NewStreamScanner create and return a new instance of StreamScanner
+// reading input from r.
+func NewStreamScanner(r io.Reader) Scanner {
+	return &StreamScanner{
+		stream:      &stream{r: r},
+		cursor:      0,
+		lineno:      1,
+		wsPattern:   `^[ \t\r\n]+`,
+		patterns:    &patterncache{},
+		sess:        newsession(),
+		tracklineno: false,
+	}
+}
+
+//---- Scanner{} interface.
+
+// SetWSPattern implement Scanner{} interface.
+func (s *StreamScanner) SetWSPattern(pattern string) Scanner {
+	s.wsPattern = pattern
+	return s
+}
+
+// TrackLineno implement Scanner{} interface.
+func (s *StreamScanner) TrackLineno() Scanner {
+	s.tracklineno = true
+	return s
+}
+
+// Clone implement Scanner{} interface.
+func (s *StreamScanner) Clone() Scanner {
+	return &StreamScanner{
+		stream:      s.stream,
+		cursor:      s.cursor,
+		lineno:      s.lineno,
+		wsPattern:   s.wsPattern,
+		patterns:    s.patterns,
+		sess:        s.sess,
+		state:       s.state,
+		tracklineno: s.tracklineno,
+	}
+}
+
+// GetCursor implement Scanner{} interface.
+func (s *StreamScanner) GetCursor() int {
+	return s.cursor
+}
+
+// Match implement Scanner{} interface.
+func (s *StreamScanner) Match(pattern string) ([]byte, Scanner) {
+	return s.matchregexp(s.patterns.get(pattern))
+}
+
+// MatchString implement Scanner{} interface.
+func (s *StreamScanner) MatchString(str string) (bool, Scanner) {
+	ln := len(str)
+	if !s.stream.fill(s.cursor + ln) {
+		return false, s
+	}
+	off := s.offset()
+	if !bytes.Equal(s.stream.buf[off:off+ln], []byte(str)) {
+		return false, s
+	}
+	s.advance(ln)
+	return true, s
+}
+
+// SubmatchAll implement Scanner{} interface.
+func (s *StreamScanner) SubmatchAll(patt string) (map[string][]byte, Scanner) {
+	return s.submatchregexp(s.patterns.get(patt))
+}
+
+// SkipWS implement Scanner{} interface.
+func (s *StreamScanner) SkipWS() ([]byte, Scanner) {
+	return s.SkipAny(s.wsPattern)
+}
+
+// SkipAny implement Scanner{} interface.
+func (s *StreamScanner) SkipAny(pattern string) ([]byte, Scanner) {
+	if pattern[0] != '^' {
+		pattern = "^" + pattern
+	}
+	return s.Match(pattern)
+}
+
+// Lineno implement Scanner{} interface.
+func (s *StreamScanner) Lineno() int {
+	return s.lineno
+}
+
+// Endof implement Scanner{} interface.
+func (s *StreamScanner) Endof() bool {
+	return !s.stream.fill(s.cursor + 1)
+}
+
+// Err return the error, other than io.EOF, from reading the input.
+// Parsers see the error as end of input.
+func (s *StreamScanner) Err() error {
+	if s.stream.err == io.EOF {
+		return nil
+	}
+	return s.stream.err
+}
+
+//---- local methods
+
+func (s *StreamScanner) matchregexp(regc *regexp.Regexp) ([]byte, Scanner) {
+	loc := regc.FindReaderIndex(s.reader())
+	if loc == nil {
+		return nil, s
+	}
+	return s.advance(loc[1]), s
+}
+
+func (s *StreamScanner) submatchregexp(regc *regexp.Regexp) (map[string][]byte, Scanner) {
+	locs := regc.FindReaderSubmatchIndex(s.reader())
+	if locs == nil {
+		return nil, s
+	}
+	off := s.offset()
+	captures := make(map[string][]byte)
+	for i, name := range regc.SubexpNames() {
+		if i == 0 || name == "" || locs[2*i] < 0 {
+			continue
+		}
+		captures[name] = s.stream.buf[off+locs[2*i] : off+locs[2*i+1]]
+	}
+	s.advance(locs[1])
+	return captures, s
+}
+
+// offset of cursor in the buffer. If cursor is before the last commit
+// point, abort the parse with a *ParseError.
+func (s *StreamScanner) offset() int {
+	if s.cursor < s.stream.base {
+		err := &ParseError{Cursor: s.cursor}
+		fmsg := "cannot backtrack, input is committed upto offset %v"
+		err.Message = fmt.Sprintf(fmsg, s.stream.base)
+		panic(err)
+	}
+	return s.cursor - s.stream.base
+}
+
+// reader return a io.RuneReader starting from cursor.
+func (s *StreamScanner) reader() io.RuneReader {
+	return &streamreader{stream: s.stream, cursor: s.cursor, off: s.offset()}
+}
+
+// advance the cursor by n bytes, that are already buffered, and return
+// them.
+func (s *StreamScanner) advance(n int) []byte {
+	off := s.offset()
+	token := s.stream.buf[off : off+n]
+	if s.tracklineno && n > 0 {
+		s.lineno += bytes.Count(token, []byte{'\n'})
+	}
+	s.cursor += n
+	return token
+}
+
+// commit discards input before cursor.
+func (s *StreamScanner) commit() {
+	s.stream.discard(s.offset())
+}
+
+func (s *StreamScanner) getsession() *session {
+	return s.sess
+}
+
+func (s *StreamScanner) getstate() *userstate {
+	return s.state
+}
+
+func (s *StreamScanner) setstate(value interface{}) {
+	s.state = &userstate{value: value, version: s.sess.nextversion()}
+}
+
+func (s *StreamScanner) linecol(cursor int) (lineno, column int) {
+	st := s.stream
+	off := cursor - st.base
+	if off < 0 {
+		off = 0
+	} else if off > len(st.buf) {
+		off = len(st.buf)
+	}
+	text := st.buf[:off]
+	lineno = st.lines + bytes.Count(text, []byte{'\n'}) + 1
+	if i := bytes.LastIndexByte(text, '\n'); i >= 0 {
+		return lineno, utf8.RuneCount(text[i+1:]) + 1
+	}
+	return lineno, st.column + utf8.RuneCount(text) + 1
+}
+
+// fill the buffer until it holds input upto offset `upto` from the
+// beginning of the stream, return false if the stream ends before.
+func (st *stream) fill(upto int) bool {
+	for st.base+len(st.buf) < upto && st.err == nil {
+		if n := len(st.buf); cap(st.buf)-n < streamchunk {
+			// slices of the old buffer are handed out as tokens, hence
+			// never modified.
+			buf := make([]byte, n, 2*n+streamchunk)
+			copy(buf, st.buf)
+			st.buf = buf
+		}
+		n, err := st.r.Read(st.buf[len(st.buf):cap(st.buf)])
+		st.buf, st.err = st.buf[:len(st.buf)+n], err
+	}
+	return st.base+len(st.buf) >= upto
+}
+
+// discard first n bytes of the buffer. The memory is released when the
+// buffer is grown next time.
+func (st *stream) discard(n int) {
+	text := st.buf[:n]
+	st.lines += bytes.Count(text, []byte{'\n'})
+	if i := bytes.LastIndexByte(text, '\n'); i >= 0 {
+		st.column = utf8.RuneCount(text[i+1:])
+	} else {
+		st.column += utf8.RuneCount(text)
+	}
+	st.buf, st.base = st.buf[n:], st.base+n
+}
+
+// streamreader implements io.RuneReader over stream, filling the buffer
+// as regular expressions consume runes.
+type streamreader struct {
+	stream *stream
+	cursor int // offset from the beginning of the stream.
+	off    int // offset in the buffer.
+}
+
+func (r *streamreader) ReadRune() (ch rune, size int, err error) {
+	st := r.stream
+	st.fill(r.cursor + utf8.UTFMax)
+	if r.off >= len(st.buf) {
+		return 0, 0, io.EOF
+	}
+	ch, size = utf8.DecodeRune(st.buf[r.off:])
+	r.cursor, r.off = r.cursor+size, r.off+size
+	return ch, size, nil
+}
+
+// committer is implemented by scanners that can discard the input
+// consumed so far.
+type committer interface {
+	commit()
+}
+
+// Commit combinator always succeed without consuming the input, and
+// discard the input before the cursor, if the scanner supports it, like
+// StreamScanner. Subsequent backtracking to a cursor before the commit
+// point aborts the parse with a *ParseError. Like Cut, And combinator
+// shall not include its node in the list of ParsecNode.
+func Commit() Parser {
+	return record(&structure{kind: "Commit"}, func(s Scanner) (ParsecNode, Scanner) {
+		if x, ok := s.(committer); ok {
+			x.commit()
+		}
+		return voidnode{}, s
+	})
+}
@@ -0,0 +1,110 @@
+package parsec
+
+import "bytes"
+import "errors"
+import "io"
+import "strings"
+import "testing"
+import "testing/iotest"
+
+func TestStreamScanner(t *testing.T) {
+	text := "key = 10, name = \"hello\""
+	newscanner := func() Scanner {
+		r := iotest.OneByteReader(strings.NewReader(text))
+		return NewStreamScanner(r)
+	}
+
+	// Token, Atom and OrdTokens.
+	value := OrdTokens([]string{`[0-9]+`, `"[^"]*"`}, []string{"INT", "STRING"})
+	pair := And(nil, Ident(), Atom("=", "EQUAL"), value)
+	y := And(nil, pair, Atom(",", "COMMA"), pair, End())
+	node, s, err := Parse(y, newscanner())
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	} else if !s.Endof() {
+		t.Errorf("expected end of text")
+	}
+	ns := node.([]ParsecNode)
+	if ref, term := `"hello"`, ns[2].([]ParsecNode)[2].(*Terminal); term.Value != ref {
+		t.Errorf("expected %v, got %v", ref, term.Value)
+	} else if term.Position != 17 {
+		t.Errorf("expected %v, got %v", 17, term.Position)
+	}
+
+	// backtracking
+	y = OrdChoice(nil,
+		And(nil, Ident(), Atom("=", "EQUAL"), Ident()),
+		And(nil, Ident(), Atom("=", "EQUAL"), Int()))
+	if node, s, _ = Parse(y, newscanner()); node == nil {
+		t.Errorf("unexpected failure")
+	} else if s.GetCursor() != 8 {
+		t.Errorf("expected %v, got %v", 8, s.GetCursor())
+	}
+
+	// failure
+	y = And(nil, pair, Atom(";", "SEMICOLON"))
+	if _, _, err = Parse(y, newscanner()); err == nil {
+		t.Errorf("expected error")
+	} else if ref := "parse error at line 1 col 9, expected SEMICOLON"; err.Error() != ref {
+		t.Errorf("expected %q, got %q", ref, err.Error())
+	}
+}
+
+func TestStreamCommit(t *testing.T) {
+	lines := make([]string, 0)
+	for i := 0; i < 1000; i++ {
+		lines = append(lines, "record 1234567890")
+	}
+	text := strings.Join(lines, "\n") + "\nrecord x"
+
+	record := And(nil, Atom("record", "RECORD"), Int(), Commit())
+	s := NewStreamScanner(strings.NewReader(text)).TrackLineno()
+	node, s, err := Parse(Kleene(nil, record), s)
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	} else if n := len(node.([]ParsecNode)); n != 1000 {
+		t.Errorf("expected %v, got %v", 1000, n)
+	} else if s.Lineno() != 1000 {
+		t.Errorf("expected %v, got %v", 1000, s.Lineno())
+	}
+	// consumed input is discarded.
+	stream := s.(*StreamScanner).stream
+	if stream.base != s.GetCursor() {
+		t.Errorf("expected %v, got %v", s.GetCursor(), stream.base)
+	} else if len(stream.buf) > 2*streamchunk {
+		t.Errorf("unexpected buffer size %v", len(stream.buf))
+	}
+	// errors after commit point.
+	_, _, err = Parse(And(nil, record, End()), s)
+	if ref := "parse error at line 1001 col 8, expected INT"; err == nil {
+		t.Errorf("expected error")
+	} else if err.Error() != ref {
+		t.Errorf("expected %q, got %q", ref, err.Error())
+	}
+
+	// backtracking beyond commit point.
+	s = NewStreamScanner(strings.NewReader("record 10 record"))
+	y := OrdChoice(nil, And(nil, record, record), Atom("record", "RECORD"))
+	ref := "parse error at offset 0, cannot backtrack, input is committed upto offset 9"
+	if node, _, err := Parse(y, s); node != nil {
+		t.Errorf("unexpected %v", node)
+	} else if err == nil {
+		t.Errorf("expected error")
+	} else if err.Error() != ref {
+		t.Errorf("expected %q, got %q", ref, err.Error())
+	}
+}
+
+func TestStreamErr(t *testing.T) {
+	experr := errors.New("network failure")
+	r := io.MultiReader(bytes.NewReader([]byte("10 20")), iotest.ErrReader(experr))
+	s := NewStreamScanner(r)
+	node, s := Many(nil, Int())(s)
+	if n := len(node.([]ParsecNode)); n != 2 {
+		t.Errorf("expected %v, got %v", 2, n)
+	} else if !s.Endof() {
+		t.Errorf("expected end of text")
+	} else if err := s.(*StreamScanner).Err(); err != experr {
+		t.Errorf("expected %v, got %v", experr, err)
+	}
+}
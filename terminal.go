@@ -123,3 +123,63 @@ func (mn MaybeNone) GetAttribute(attrname string) []string {
 func (mn MaybeNone) GetAttributes() map[string][]string {
 	return nil
 }
+
+// voidnode is a placeholder node returned by parsers that succeed
+// without matching any input, like Lookahead and NotFollowedBy.
+// And combinator omit voidnode from its list of nodes.
+type voidnode struct{}
+
+// cutnode is the voidnode returned by Cut.
+type cutnode struct {
+	voidnode
+}
+
+func isvoid(node ParsecNode) bool {
+	switch node.(type) {
+	case voidnode, cutnode:
+		return true
+	}
+	return false
+}
+
+//---- implement Queryable interface
+
+// GetName implement Queryable interface.
+func (vn voidnode) GetName() string {
+	return ""
+}
+
+// IsTerminal implement Queryable interface.
+func (vn voidnode) IsTerminal() bool {
+	return true
+}
+
+// GetValue implement Queryable interface.
+func (vn voidnode) GetValue() string {
+	return ""
+}
+
+// GetChildren implement Queryable interface.
+func (vn voidnode) GetChildren() []Queryable {
+	return nil
+}
+
+// GetPosition implement Queryable interface.
+func (vn voidnode) GetPosition() int {
+	return -1
+}
+
+// SetAttribute implement Queryable interface.
+func (vn voidnode) SetAttribute(attrname, value string) Queryable {
+	return vn
+}
+
+// GetAttribute implement Queryable interface.
+func (vn voidnode) GetAttribute(attrname string) []string {
+	return nil
+}
+
+// GetAttributes implement Queryable interface.
+func (vn voidnode) GetAttributes() map[string][]string {
+	return nil
+}
@@ -10,7 +10,9 @@ used with AST combinators and return a Terminal node.
 
 package parsec
 
+import "regexp"
 import "strings"
+import "sync"
 import "strconv"
 import "unicode"
 import "unicode/utf8"
@@ -20,19 +22,23 @@ import "unicode/utf16"
 // returns string type as ParsecNode, hence incompatible with
 // AST combinators. Skip leading whitespace.
 func String() Parser {
-	return func(s Scanner) (ParsecNode, Scanner) {
+	return record(&structure{kind: "String"}, func(s Scanner) (ParsecNode, Scanner) {
 		s.SkipWS()
 		scanner := s.(*SimpleScanner)
 		if !scanner.Endof() && scanner.buf[scanner.cursor] == '"' {
 			str, readn := scanString(scanner.buf[scanner.cursor:])
 			if str == nil || len(str) == 0 {
+				scanner.sess.examine(scanner.cursor, len(scanner.buf)+1)
+				expect(scanner, scanner.cursor, "STRING")
 				return nil, scanner
 			}
+			scanner.sess.examine(scanner.cursor, scanner.cursor+readn)
 			scanner.cursor += readn
 			return string(str), scanner
 		}
+		expect(scanner, scanner.cursor, "STRING")
 		return nil, scanner
-	}
+	})
 }
 
 // Char return parser function to match a single character
@@ -75,34 +81,43 @@ func Ident() Parser {
 
 // Token takes a regular-expression pattern and return a parser that
 // will match input stream with supplied pattern. Skip leading whitespace.
-// `name` will be used as the Terminal's name.
+// `name` will be used as the Terminal's name. Panics if pattern is not a
+// valid regular expression.
 func Token(pattern string, name string) Parser {
 	if pattern[0] != '^' {
 		pattern = "^" + pattern
 	}
-	return func(s Scanner) (ParsecNode, Scanner) {
+	regc := compile(pattern)
+	st := &structure{kind: "Token", name: name, text: pattern}
+	return record(st, func(s Scanner) (ParsecNode, Scanner) {
 		news := s.Clone()
 		news.SkipWS()
 		cursor := news.GetCursor()
-		if tok, _ := news.Match(pattern); tok != nil {
+		if tok, _ := matchregexp(news, regc); tok != nil {
 			return NewTerminal(name, string(tok), cursor), news
 		}
+		expect(s, cursor, name)
 		return nil, s
-	}
+	})
 }
 
 // TokenExact same as Token() but pattern will be matched
 // without skipping leading whitespace. `name` will be used as
-// the terminal's name.
+// the terminal's name. Panics if pattern is not a valid regular
+// expression.
 func TokenExact(pattern string, name string) Parser {
-	return func(s Scanner) (ParsecNode, Scanner) {
+	pattern = "^" + pattern
+	regc := compile(pattern)
+	st := &structure{kind: "TokenExact", name: name, text: pattern, exact: true}
+	return record(st, func(s Scanner) (ParsecNode, Scanner) {
 		news := s.Clone()
 		cursor := news.GetCursor()
-		if tok, _ := news.Match("^" + pattern); tok != nil {
+		if tok, _ := matchregexp(news, regc); tok != nil {
 			return NewTerminal(name, string(tok), cursor), news
 		}
+		expect(s, cursor, name)
 		return nil, s
-	}
+	})
 }
 
 // Atom is similar to Token, takes a string to match with input
@@ -111,32 +126,37 @@ func TokenExact(pattern string, name string) Parser {
 //		scanner := NewScanner([]byte("cosmos"))
 //		Atom("cos", "ATOM")(scanner) // will match
 func Atom(match string, name string) Parser {
-	return func(s Scanner) (ParsecNode, Scanner) {
+	st := &structure{kind: "Atom", name: name, text: match}
+	return record(st, func(s Scanner) (ParsecNode, Scanner) {
 		news := s.Clone()
 		news.SkipWS()
 		cursor := news.GetCursor()
 		if ok, _ := news.MatchString(match); ok {
 			return NewTerminal(name, match, cursor), news
 		}
+		expect(s, cursor, name)
 		return nil, s
-	}
+	})
 }
 
 // AtomExact is similar to Atom(), but string will be matched without
 // skipping leading whitespace.
 func AtomExact(match string, name string) Parser {
-	return func(s Scanner) (ParsecNode, Scanner) {
+	st := &structure{kind: "AtomExact", name: name, text: match, exact: true}
+	return record(st, func(s Scanner) (ParsecNode, Scanner) {
 		news := s.Clone()
 		cursor := news.GetCursor()
 		if ok, _ := news.MatchString(match); ok {
 			return NewTerminal(name, match, cursor), news
 		}
+		expect(s, cursor, name)
 		return nil, s
-	}
+	})
 }
 
 // OrdTokens to parse a single token based on one of the
-// specified `patterns`. Skip leading whitespaces.
+// specified `patterns`. Skip leading whitespaces. Panics if patterns
+// are not valid regular expressions.
 func OrdTokens(patterns []string, names []string) Parser {
 	var group string
 	groups := make([]string, 0, len(patterns))
@@ -149,41 +169,59 @@ func OrdTokens(patterns []string, names []string) Parser {
 		groups = append(groups, group)
 	}
 	ordPattern := strings.Join(groups, "|")
-	return func(s Scanner) (ParsecNode, Scanner) {
+	regc := compile(ordPattern)
+	st := &structure{kind: "OrdTokens", text: ordPattern}
+	return record(st, func(s Scanner) (ParsecNode, Scanner) {
 		news := s.Clone()
 		news.SkipWS()
 		cursor := news.GetCursor()
-		if captures, _ := news.SubmatchAll(ordPattern); captures != nil {
+		if captures, _ := submatchregexp(news, regc); captures != nil {
 			for name, tok := range captures {
 				return NewTerminal(name, string(tok), cursor), news
 			}
 		}
+		for _, name := range names {
+			expect(s, cursor, name)
+		}
 		return nil, s
-	}
+	})
 }
 
 // End is a parser function to detect end of scanner output, return
 // boolean as ParseNode, hence incompatible with AST{}. Instead, use
 // AST:End method.
 func End() Parser {
-	return func(s Scanner) (ParsecNode, Scanner) {
+	return record(&structure{kind: "End"}, func(s Scanner) (ParsecNode, Scanner) {
 		if s.Endof() {
 			return true, s
 		}
 		return nil, s
-	}
+	})
+}
+
+// EndWS is same as End, but skip leading white space, refer to
+// Scanner.SkipWS, before detecting end of scanner output.
+func EndWS() Parser {
+	return record(&structure{kind: "End"}, func(s Scanner) (ParsecNode, Scanner) {
+		news := s.Clone()
+		news.SkipWS()
+		if news.Endof() {
+			return true, news
+		}
+		return nil, s
+	})
 }
 
 // NoEnd is a parser function to detect not-an-end of
 // scanner output, return boolean as ParsecNode, hence
 // incompatible with AST{}.
 func NoEnd() Parser {
-	return func(s Scanner) (ParsecNode, Scanner) {
+	return record(&structure{kind: "NoEnd"}, func(s Scanner) (ParsecNode, Scanner) {
 		if !s.Endof() {
 			return true, s
 		}
 		return nil, s
-	}
+	})
 }
 
 var escapeCode = [256]byte{ // TODO: size can be optimized
@@ -294,3 +332,39 @@ func getu4(s []byte) rune {
 	}
 	return rune(r)
 }
+
+// compiled caches regular expressions compiled for token parsers,
+// shared by all parsers in the process. Parsers are often built again
+// for every use, like Int() inside a closure.
+var compiled sync.Map // pattern -> *regexp.Regexp
+
+// compile return the compiled regular expression for pattern, from
+// cache if available. Panics if pattern is invalid.
+func compile(pattern string) *regexp.Regexp {
+	if regc, ok := compiled.Load(pattern); ok {
+		return regc.(*regexp.Regexp)
+	}
+	regc, _ := compiled.LoadOrStore(pattern, regexp.MustCompile(pattern))
+	return regc.(*regexp.Regexp)
+}
+
+// regexper is implemented by scanners that can match a compiled regular
+// expression, saving the pattern lookup for every match.
+type regexper interface {
+	matchregexp(regc *regexp.Regexp) ([]byte, Scanner)
+	submatchregexp(regc *regexp.Regexp) (map[string][]byte, Scanner)
+}
+
+func matchregexp(s Scanner, regc *regexp.Regexp) ([]byte, Scanner) {
+	if x, ok := s.(regexper); ok {
+		return x.matchregexp(regc)
+	}
+	return s.Match(regc.String())
+}
+
+func submatchregexp(s Scanner, regc *regexp.Regexp) (map[string][]byte, Scanner) {
+	if x, ok := s.(regexper); ok {
+		return x.submatchregexp(regc)
+	}
+	return s.SubmatchAll(regc.String())
+}
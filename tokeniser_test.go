@@ -250,6 +250,21 @@ func TestNotEnd(t *testing.T) {
 	}
 }
 
+func TestEndWS(t *testing.T) {
+	p := And(First, Token("test", "T"), EndWS())
+	s := NewScanner([]byte("test \n"))
+	if v, e := p(s); v == nil {
+		t.Errorf("EndWS() didn't match %q", e)
+	} else if v.(*Terminal).Name != "T" {
+		t.Errorf("expected %v, got %v", "T", v)
+	} else if !e.Endof() {
+		t.Errorf("expected end of text")
+	}
+	if v, _ := p(NewScanner([]byte("test x"))); v != nil {
+		t.Errorf("EndWS() shouldn't have matched %q", v)
+	}
+}
+
 func TestNoEnd(t *testing.T) {
 	p := And(nil, Token("test", "T"), NoEnd())
 	s := NewScanner([]byte("testing"))
@@ -3,14 +3,17 @@
 
 package main
 
+import "bytes"
 import "flag"
 import "fmt"
 import "io/ioutil"
 import "os"
 
 import "github.com/prataprc/goparsec"
+import "github.com/prataprc/goparsec/abnf"
 import "github.com/prataprc/goparsec/expr"
 import "github.com/prataprc/goparsec/json"
+import "github.com/prataprc/goparsec/peg"
 
 var options struct {
 	expr string
@@ -26,6 +29,10 @@ func argParse() {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "gen" {
+		doGen(os.Args[2:])
+		return
+	}
 	argParse()
 	if options.expr != "" {
 		doExpr(getText(options.expr))
@@ -46,6 +53,54 @@ func doJSON(text string) {
 	fmt.Println(v)
 }
 
+// doGen generate Go source for parser from grammar file, usage:
+//
+//	parsec gen [-package name] [-root rule] [-abnf] [-o file] grammar
+func doGen(args []string) {
+	var pkg, root, out string
+	var isabnf bool
+
+	f := flag.NewFlagSet("gen", flag.ExitOnError)
+	f.StringVar(&pkg, "package", "main", "Package name for generated source")
+	f.StringVar(&root, "root", "", "Root rule, default is the first rule")
+	f.BoolVar(&isabnf, "abnf", false, "Grammar is in ABNF notation")
+	f.StringVar(&out, "o", "", "Output file, default is stdout")
+	f.Parse(args)
+	if f.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: parsec gen [options] grammar")
+		f.PrintDefaults()
+		os.Exit(2)
+	}
+
+	text, err := ioutil.ReadFile(f.Arg(0))
+	if err != nil {
+		exit(err)
+	}
+	var g *peg.Grammar
+	if isabnf {
+		g, err = abnf.Parse(text)
+	} else {
+		g, err = peg.Parse(text)
+	}
+	if err != nil {
+		exit(fmt.Errorf("%v: %v", f.Arg(0), err))
+	}
+	var buf bytes.Buffer
+	if err := g.Generate(&buf, pkg, root); err != nil {
+		exit(err)
+	}
+	if out == "" {
+		os.Stdout.Write(buf.Bytes())
+	} else if err := ioutil.WriteFile(out, buf.Bytes(), 0644); err != nil {
+		exit(err)
+	}
+}
+
+func exit(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
+
 func getText(filename string) string {
 	if _, err := os.Stat(filename); err != nil {
 		return filename
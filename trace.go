@@ -0,0 +1,274 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+package parsec
+
+import "fmt"
+import "io"
+import "sort"
+import "strings"
+import "text/tabwriter"
+import "time"
+
+// TraceEvent describes the invocation of a named AST combinator.
+type TraceEvent struct {
+	Kind   string // combinator, like "And", "OrdChoice" etc..
+	Name   string // name of the combinator.
+	Cursor int    // cursor position where the combinator was invoked.
+	Lineno int    // line number of Cursor, if scanner tracks lineno.
+	Depth  int    // nesting level of the combinator.
+	// Following fields are valid only for Match and Fail events.
+	End      int           // cursor position after a match.
+	Duration time.Duration // time spent in the combinator.
+}
+
+// Tracer receives events for every named AST combinator invoked during
+// a parse. Use AST.SetTracer to trace a parse.
+type Tracer interface {
+	// Enter is called when the combinator is invoked.
+	Enter(ev TraceEvent)
+	// Match is called when the combinator matched the input.
+	Match(ev TraceEvent)
+	// Fail is called when the combinator failed to match the input.
+	Fail(ev TraceEvent)
+}
+
+// SetTracer to receive events for every named combinator created by
+// this AST, pass nil to disable tracing. Refer to TextTracer and
+// Profiler for builtin tracers. Events from concurrent parses are
+// dispatched to the same tracer, builtin tracers are not safe for
+// concurrent use.
+func (ast *AST) SetTracer(tracer Tracer) *AST {
+	ast.tracer = tracer
+	return ast
+}
+
+// traced wraps a combinator to dispatch trace events, and record its
+// structure.
+func (ast *AST) traced(st *structure, parser Parser) Parser {
+	return record(st, func(s Scanner) (ParsecNode, Scanner) {
+		if ast.tracer == nil {
+			return parser(s)
+		}
+		return ast.trace(st.kind, st.name, parser, s)
+	})
+}
+
+func (ast *AST) trace(
+	kind, name string, parser Parser, s Scanner) (node ParsecNode, news Scanner) {
+
+	tracer, depth := ast.tracer, new(int)
+	if sess := sessionof(s); sess != nil {
+		if sess.tracing == nil {
+			sess.tracing = &tracing{}
+		}
+		depth = &sess.tracing.depth
+	}
+	ev := TraceEvent{
+		Kind: kind, Name: name, Cursor: s.GetCursor(), Lineno: s.Lineno(),
+		Depth: *depth,
+	}
+	tracer.Enter(ev)
+	*depth++
+	start := time.Now()
+	defer func() {
+		*depth--
+		ev.Duration = time.Since(start)
+		if node != nil {
+			ev.End = news.GetCursor()
+			tracer.Match(ev)
+			return
+		}
+		ev.End = ev.Cursor
+		tracer.Fail(ev)
+	}()
+	return parser(s)
+}
+
+// tracing state of a parse session.
+type tracing struct {
+	depth int // nesting level of traced combinators.
+}
+
+// TextTracer writes an indented trace of the parse, one line for each
+// event.
+type TextTracer struct {
+	w io.Writer
+}
+
+// NewTextTracer return a tracer that writes to w.
+func NewTextTracer(w io.Writer) *TextTracer {
+	return &TextTracer{w: w}
+}
+
+// Enter implement Tracer interface.
+func (tt *TextTracer) Enter(ev TraceEvent) {
+	tt.printf(ev, "%v(%v) @ %v:%v", ev.Kind, ev.Name, ev.Lineno, ev.Cursor)
+}
+
+// Match implement Tracer interface.
+func (tt *TextTracer) Match(ev TraceEvent) {
+	fmsg := "%v(%v) match %v..%v %v"
+	tt.printf(ev, fmsg, ev.Kind, ev.Name, ev.Cursor, ev.End, ev.Duration)
+}
+
+// Fail implement Tracer interface.
+func (tt *TextTracer) Fail(ev TraceEvent) {
+	fmsg := "%v(%v) fail %v %v"
+	tt.printf(ev, fmsg, ev.Kind, ev.Name, ev.Cursor, ev.Duration)
+}
+
+func (tt *TextTracer) printf(ev TraceEvent, fmsg string, args ...interface{}) {
+	prefix := strings.Repeat("  ", ev.Depth)
+	fmt.Fprintf(tt.w, prefix+fmsg+"\n", args...)
+}
+
+// RuleProfile is the profile of a named combinator, gathered by
+// Profiler.
+type RuleProfile struct {
+	Kind       string
+	Name       string
+	Calls      int64         // number of times invoked.
+	Matches    int64         // number of times matched.
+	Backtracks int64         // number of times failed.
+	Time       time.Duration // total time, including nested combinators.
+}
+
+// Hotspot is a position in the input text parsed by a named combinator
+// more than once, gathered by Profiler.
+type Hotspot struct {
+	Kind   string
+	Name   string
+	Cursor int
+	Lineno int
+	Calls  int64
+}
+
+// Profiler is a tracer that gathers per rule profile and hot-spots,
+// positions in the input text that are re-parsed most often. Enabling
+// packrat parsing, via AST.SetPackrat, can help with hot-spots.
+type Profiler struct {
+	rules map[profkey]*RuleProfile
+	spots map[hotkey]*Hotspot
+}
+
+type profkey struct {
+	kind string
+	name string
+}
+
+type hotkey struct {
+	kind   string
+	name   string
+	cursor int
+}
+
+// NewProfiler return a new instance of Profiler.
+func NewProfiler() *Profiler {
+	return &Profiler{
+		rules: make(map[profkey]*RuleProfile),
+		spots: make(map[hotkey]*Hotspot),
+	}
+}
+
+// Enter implement Tracer interface.
+func (p *Profiler) Enter(ev TraceEvent) {
+	p.rule(ev).Calls++
+	key := hotkey{kind: ev.Kind, name: ev.Name, cursor: ev.Cursor}
+	spot, ok := p.spots[key]
+	if !ok {
+		spot = &Hotspot{
+			Kind: ev.Kind, Name: ev.Name, Cursor: ev.Cursor, Lineno: ev.Lineno,
+		}
+		p.spots[key] = spot
+	}
+	spot.Calls++
+}
+
+// Match implement Tracer interface.
+func (p *Profiler) Match(ev TraceEvent) {
+	rule := p.rule(ev)
+	rule.Matches++
+	rule.Time += ev.Duration
+}
+
+// Fail implement Tracer interface.
+func (p *Profiler) Fail(ev TraceEvent) {
+	rule := p.rule(ev)
+	rule.Backtracks++
+	rule.Time += ev.Duration
+}
+
+// Profile return profile of all rules, sorted by time spent.
+func (p *Profiler) Profile() []RuleProfile {
+	profile := make([]RuleProfile, 0, len(p.rules))
+	for _, rule := range p.rules {
+		profile = append(profile, *rule)
+	}
+	sort.Slice(profile, func(i, j int) bool {
+		if profile[i].Time != profile[j].Time {
+			return profile[i].Time > profile[j].Time
+		}
+		if profile[i].Name != profile[j].Name {
+			return profile[i].Name < profile[j].Name
+		}
+		return profile[i].Kind < profile[j].Kind
+	})
+	return profile
+}
+
+// Hotspots return atmost n positions that were parsed more than once by
+// the same rule, sorted by number of calls.
+func (p *Profiler) Hotspots(n int) []Hotspot {
+	spots := make([]Hotspot, 0)
+	for _, spot := range p.spots {
+		if spot.Calls > 1 {
+			spots = append(spots, *spot)
+		}
+	}
+	sort.Slice(spots, func(i, j int) bool {
+		if spots[i].Calls != spots[j].Calls {
+			return spots[i].Calls > spots[j].Calls
+		} else if spots[i].Cursor != spots[j].Cursor {
+			return spots[i].Cursor < spots[j].Cursor
+		} else if spots[i].Name != spots[j].Name {
+			return spots[i].Name < spots[j].Name
+		}
+		return spots[i].Kind < spots[j].Kind
+	})
+	if len(spots) > n {
+		spots = spots[:n]
+	}
+	return spots
+}
+
+// Report writes the profile table and atmost n hot-spots to w.
+func (p *Profiler) Report(w io.Writer, n int) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "rule\tkind\tcalls\tmatches\tbacktracks\ttime\t")
+	for _, rule := range p.Profile() {
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\t%v\t\n",
+			rule.Name, rule.Kind, rule.Calls, rule.Matches, rule.Backtracks,
+			rule.Time)
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "rule\tkind\tlineno\tcursor\tcalls\t")
+	for _, spot := range p.Hotspots(n) {
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\t\n",
+			spot.Name, spot.Kind, spot.Lineno, spot.Cursor, spot.Calls)
+	}
+	tw.Flush()
+}
+
+func (p *Profiler) rule(ev TraceEvent) *RuleProfile {
+	key := profkey{kind: ev.Kind, name: ev.Name}
+	rule, ok := p.rules[key]
+	if !ok {
+		rule = &RuleProfile{Kind: ev.Kind, Name: ev.Name}
+		p.rules[key] = rule
+	}
+	return rule
+}
@@ -0,0 +1,141 @@
+package parsec
+
+import "bytes"
+import "strings"
+import "testing"
+
+func TestTextTracer(t *testing.T) {
+	var buf bytes.Buffer
+
+	ast := NewAST("trace", 100).SetTracer(NewTextTracer(&buf))
+	y := ast.And("pair", nil,
+		ast.OrdChoice("key", nil, Atom("x", "X"), Ident()),
+		Atom("=", "EQUAL"), Int())
+	if ast.Parsewith(y, NewScanner([]byte("key = 10"))); ast.Error() != nil {
+		t.Fatalf("unexpected %v", ast.Error())
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	refs := []string{
+		"And(pair) @ 1:0",
+		"  OrdChoice(key) @ 1:0",
+		"  OrdChoice(key) match 0..3 ",
+		"And(pair) match 0..8 ",
+	}
+	if len(lines) != len(refs) {
+		t.Fatalf("expected %v, got %v", len(refs), lines)
+	}
+	for i, ref := range refs {
+		if !strings.HasPrefix(lines[i], ref) {
+			t.Errorf("expected %q, got %q", ref, lines[i])
+		}
+	}
+
+	// failure
+	buf.Reset()
+	ast.Parsewith(y, NewScanner([]byte("10")))
+	if ref := "  OrdChoice(key) fail 0 "; !strings.Contains(buf.String(), ref) {
+		t.Errorf("expected %q, got %q", ref, buf.String())
+	}
+
+	// disable tracing
+	buf.Reset()
+	ast.SetTracer(nil).Parsewith(y, NewScanner([]byte("key = 10")))
+	if buf.Len() != 0 {
+		t.Errorf("unexpected %q", buf.String())
+	}
+}
+
+func TestProfiler(t *testing.T) {
+	ast := NewAST("profile", 100)
+	term := ast.OrdChoice("term", nil, Int(), Ident())
+	y := ast.OrdChoice("expr", nil,
+		ast.And("add", nil, term, Atom("+", "ADD"), term),
+		ast.And("sub", nil, term, Atom("-", "SUB"), term),
+		term)
+
+	profiler := NewProfiler()
+	ast.SetTracer(profiler).Parsewith(y, NewScanner([]byte("x - 10")))
+	if ast.Error() != nil {
+		t.Fatalf("unexpected %v", ast.Error())
+	}
+
+	rules := make(map[string]RuleProfile)
+	for _, rule := range profiler.Profile() {
+		rules[rule.Name] = rule
+	}
+	if rule := rules["term"]; rule.Calls != 3 || rule.Matches != 3 {
+		t.Errorf("unexpected %+v", rule)
+	} else if rule.Kind != "OrdChoice" {
+		t.Errorf("expected %v, got %v", "OrdChoice", rule.Kind)
+	}
+	if rule := rules["add"]; rule.Calls != 1 || rule.Backtracks != 1 {
+		t.Errorf("unexpected %+v", rule)
+	}
+	if rule := rules["sub"]; rule.Calls != 1 || rule.Matches != 1 {
+		t.Errorf("unexpected %+v", rule)
+	}
+
+	// term at cursor 0 is parsed twice, once each by add and sub.
+	spots := profiler.Hotspots(10)
+	if len(spots) != 1 {
+		t.Fatalf("unexpected %v", spots)
+	} else if spot := spots[0]; spot.Name != "term" || spot.Kind != "OrdChoice" {
+		t.Errorf("unexpected %+v", spot)
+	} else if spot.Cursor != 0 {
+		t.Errorf("unexpected %+v", spot)
+	} else if spot.Calls != 2 {
+		t.Errorf("expected %v, got %v", 2, spot.Calls)
+	}
+
+	var buf bytes.Buffer
+	profiler.Report(&buf, 10)
+	for _, ref := range []string{"backtracks", "OrdChoice", "term"} {
+		if !strings.Contains(buf.String(), ref) {
+			t.Errorf("expected %q in %q", ref, buf.String())
+		}
+	}
+}
+
+func TestProfilerKinds(t *testing.T) {
+	ast := NewAST("profilekinds", 100)
+	num := ast.Maybe("num", nil, Int())
+	expr := ast.Pratt("expr", ast.And("num", nil, num)).
+		Infix("add", Atom("+", "ADD"), 10, AssocLeft, nil).
+		Parser()
+
+	profiler := NewProfiler()
+	ast.SetTracer(profiler).Parsewith(expr, NewScanner([]byte("1 + 2")))
+	if ast.Error() != nil {
+		t.Fatalf("unexpected %v", ast.Error())
+	}
+
+	rules := make(map[string]RuleProfile)
+	for _, rule := range profiler.Profile() {
+		rules[rule.Kind+":"+rule.Name] = rule
+	}
+	if len(rules) != 3 {
+		t.Errorf("unexpected %v", rules)
+	}
+	for _, key := range []string{"And:num", "Maybe:num"} {
+		if rule := rules[key]; rule.Calls != 2 || rule.Matches != 2 {
+			t.Errorf("%v unexpected %+v", key, rule)
+		}
+	}
+	if rule := rules["Pratt:expr"]; rule.Calls != 1 || rule.Matches != 1 {
+		t.Errorf("unexpected %+v", rule)
+	}
+
+	// parse again, every position is a hot-spot.
+	ast.Parsewith(expr, NewScanner([]byte("1 + 2")))
+	spots := make(map[string]Hotspot)
+	for _, spot := range profiler.Hotspots(10) {
+		if spot.Cursor == 0 {
+			spots[spot.Kind+":"+spot.Name] = spot
+		}
+	}
+	for _, key := range []string{"And:num", "Maybe:num", "Pratt:expr"} {
+		if spot := spots[key]; spot.Calls != 2 {
+			t.Errorf("%v unexpected %+v", key, spot)
+		}
+	}
+}
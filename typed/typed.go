@@ -0,0 +1,231 @@
+// Copyright (c) 2013 Goparsec AUTHORS. All rights reserved.
+// Use of this source code is governed by LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+// Package typed provide a type-safe API for parser combinators, using
+// generics. Result type of sub-parsers are checked at compile time and
+// callbacks receive typed values instead of []ParsecNode:
+//
+//	num := typed.Map(typed.Term(parsec.Int()), func(t *parsec.Terminal) int {
+//		n, _ := strconv.Atoi(t.Value)
+//		return n
+//	})
+//	add := typed.Seq3(num, typed.Term(parsec.Atom("+", "ADD")), num,
+//		func(x int, _ *parsec.Terminal, y int) int { return x + y })
+//	value, s, err := typed.Parse(add, parsec.NewScanner([]byte("1 + 2")))
+//
+// Use Lift to use parsec.Parser within typed combinators, and Parser
+// method to use typed combinators as parsec.Parser.
+package typed
+
+import "fmt"
+
+import "github.com/prataprc/goparsec"
+
+// P is a parser that return a value of type T, on success. If P fails
+// to match the input it shall return false, without consuming the input.
+type P[T any] func(s parsec.Scanner) (T, parsec.Scanner, bool)
+
+// Parser return p as parsec.Parser, that return value of type T as
+// ParsecNode. Note that nil values, like nil pointers, are interpreted
+// as failure by parsec combinators.
+func (p P[T]) Parser() parsec.Parser {
+	return func(s parsec.Scanner) (parsec.ParsecNode, parsec.Scanner) {
+		if v, news, ok := p(s); ok {
+			return v, news
+		}
+		return nil, s
+	}
+}
+
+// Lift parser y, whose ParsecNode is of type T, as P[T]. Panics if y
+// return a ParsecNode that is not of type T.
+func Lift[T any](y parsec.Parser) P[T] {
+	return func(s parsec.Scanner) (T, parsec.Scanner, bool) {
+		var zero T
+		node, news := y(s)
+		if node == nil {
+			return zero, s, false
+		}
+		v, ok := node.(T)
+		if !ok {
+			fmsg := "node of type `%T` is not of type `%T`"
+			panic(fmt.Errorf(fmsg, node, zero))
+		}
+		return v, news, true
+	}
+}
+
+// Term lift terminal parser y, like parsec.Token, parsec.Atom etc.., as
+// P[*parsec.Terminal].
+func Term(y parsec.Parser) P[*parsec.Terminal] {
+	return Lift[*parsec.Terminal](y)
+}
+
+// Ref return a parser that invokes the parser referenced by p, to
+// define recursive rules.
+func Ref[T any](p *P[T]) P[T] {
+	return func(s parsec.Scanner) (T, parsec.Scanner, bool) {
+		return (*p)(s)
+	}
+}
+
+// Map return a parser that convert value matched by p, using callb.
+func Map[T, R any](p P[T], callb func(T) R) P[R] {
+	return func(s parsec.Scanner) (R, parsec.Scanner, bool) {
+		var zero R
+		v, news, ok := p(s.Clone())
+		if !ok {
+			return zero, s, false
+		}
+		return callb(v), news, true
+	}
+}
+
+// Seq2 return a parser that match a followed by b, and construct value
+// from the matched values using callb.
+func Seq2[A, B, R any](a P[A], b P[B], callb func(A, B) R) P[R] {
+	return func(s parsec.Scanner) (R, parsec.Scanner, bool) {
+		var zero R
+		va, news, ok := a(s.Clone())
+		if !ok {
+			return zero, s, false
+		}
+		vb, news, ok := b(news)
+		if !ok {
+			return zero, s, false
+		}
+		return callb(va, vb), news, true
+	}
+}
+
+// Seq3 return a parser that match a, b and c in sequence, and construct
+// value from the matched values using callb.
+func Seq3[A, B, C, R any](
+	a P[A], b P[B], c P[C], callb func(A, B, C) R) P[R] {
+
+	ab := Seq2(a, b, func(va A, vb B) pair[A, B] { return pair[A, B]{va, vb} })
+	return Seq2(ab, c, func(x pair[A, B], vc C) R {
+		return callb(x.first, x.second, vc)
+	})
+}
+
+// Seq4 return a parser that match a, b, c and d in sequence, and
+// construct value from the matched values using callb.
+func Seq4[A, B, C, D, R any](
+	a P[A], b P[B], c P[C], d P[D], callb func(A, B, C, D) R) P[R] {
+
+	ab := Seq2(a, b, func(va A, vb B) pair[A, B] { return pair[A, B]{va, vb} })
+	return Seq3(ab, c, d, func(x pair[A, B], vc C, vd D) R {
+		return callb(x.first, x.second, vc, vd)
+	})
+}
+
+// Choice return a parser that return the value matched by first of
+// the parsers, ps, that match the input.
+func Choice[T any](ps ...P[T]) P[T] {
+	return func(s parsec.Scanner) (T, parsec.Scanner, bool) {
+		var zero T
+		for _, p := range ps {
+			if v, news, ok := p(s.Clone()); ok {
+				return v, news, true
+			}
+		}
+		return zero, s, false
+	}
+}
+
+// Maybe return a parser that return the value matched by p, or def
+// if p fails to match the input. Maybe never fails.
+func Maybe[T any](p P[T], def T) P[T] {
+	return func(s parsec.Scanner) (T, parsec.Scanner, bool) {
+		if v, news, ok := p(s.Clone()); ok {
+			return v, news, true
+		}
+		return def, s, true
+	}
+}
+
+// Kleene return a parser that match p zero or more times, and return
+// the list of matched values. Kleene never fails, unless p matches
+// without consuming the input, refer to SepBy.
+func Kleene[T any](p P[T]) P[[]T] {
+	return SepBy(p, P[struct{}](nil))
+}
+
+// Many return a parser that match p one or more times, and return the
+// list of matched values.
+func Many[T any](p P[T]) P[[]T] {
+	return atleastone(Kleene(p))
+}
+
+// SepBy return a parser that match zero or more p separated by sep,
+// and return the list of values matched by p. If an iteration matches
+// without consuming the input, the parse is aborted, refer to
+// parsec.NoProgress.
+func SepBy[T, S any](p P[T], sep P[S]) P[[]T] {
+	return func(s parsec.Scanner) ([]T, parsec.Scanner, bool) {
+		vs, news := make([]T, 0), s.Clone()
+		for {
+			after, ok := news, true
+			if sep != nil && len(vs) > 0 {
+				if _, after, ok = sep(news.Clone()); !ok {
+					break
+				}
+			}
+			v, next, ok := p(after.Clone())
+			if !ok {
+				break
+			}
+			if next.GetCursor() == news.GetCursor() {
+				parsec.NoProgress(next)
+			}
+			vs, news = append(vs, v), next
+		}
+		return vs, news, true
+	}
+}
+
+// SepBy1 is same as SepBy, but p shall match atleast once.
+func SepBy1[T, S any](p P[T], sep P[S]) P[[]T] {
+	return atleastone(SepBy(p, sep))
+}
+
+// Parse execute root parser p with scanner s, same as parsec.Parse
+// but return value of type T.
+func Parse[T any](p P[T], s parsec.Scanner) (T, parsec.Scanner, error) {
+	var v T
+	var ok bool
+
+	y := func(s parsec.Scanner) (parsec.ParsecNode, parsec.Scanner) {
+		var news parsec.Scanner
+		if v, news, ok = p(s); ok {
+			return true, news
+		}
+		return nil, s
+	}
+	_, news, err := parsec.Parse(y, s)
+	if !ok {
+		var zero T
+		return zero, news, err
+	}
+	return v, news, err
+}
+
+//---- local functions
+
+type pair[A, B any] struct {
+	first  A
+	second B
+}
+
+func atleastone[T any](p P[[]T]) P[[]T] {
+	return func(s parsec.Scanner) ([]T, parsec.Scanner, bool) {
+		if vs, news, _ := p(s); len(vs) > 0 {
+			return vs, news, true
+		}
+		return nil, s, false
+	}
+}
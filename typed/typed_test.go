@@ -0,0 +1,114 @@
+//go:build go1.18
+// +build go1.18
+
+package typed
+
+import "reflect"
+import "strconv"
+import "testing"
+
+import "github.com/prataprc/goparsec"
+
+func TestTyped(t *testing.T) {
+	var expr P[int]
+
+	num := Map(Term(parsec.Int()), func(t *parsec.Terminal) int {
+		n, _ := strconv.Atoi(t.Value)
+		return n
+	})
+	group := Seq3(
+		Term(parsec.Atom("(", "OPEN")), Ref(&expr), Term(parsec.Atom(")", "CLOSE")),
+		func(_ *parsec.Terminal, n int, _ *parsec.Terminal) int { return n })
+	value := Choice(num, group)
+	expr = Map(
+		SepBy1(value, Term(parsec.Atom("+", "ADD"))),
+		func(ns []int) int {
+			sum := 0
+			for _, n := range ns {
+				sum += n
+			}
+			return sum
+		})
+
+	testcases := []struct {
+		text   string
+		value  int
+		cursor int
+	}{
+		{"10", 10, 2},
+		{"1 + 2 + (3 + 4)", 10, 15},
+		{"1 + ", 1, 1},
+	}
+	for _, tcase := range testcases {
+		n, s, err := Parse(expr, parsec.NewScanner([]byte(tcase.text)))
+		if err != nil {
+			t.Errorf("unexpected %v", err)
+		} else if n != tcase.value {
+			t.Errorf("expected %v, got %v", tcase.value, n)
+		} else if s.GetCursor() != tcase.cursor {
+			t.Errorf("expected %v, got %v", tcase.cursor, s.GetCursor())
+		}
+	}
+
+	// failure
+	_, s, err := Parse(expr, parsec.NewScanner([]byte("(1 + 2")))
+	if perr, ok := err.(*parsec.ParseError); !ok {
+		t.Errorf("unexpected %v", err)
+	} else if perr.Cursor != 6 {
+		t.Errorf("expected %v, got %v", 6, perr.Cursor)
+	} else if s.GetCursor() != 0 {
+		t.Errorf("expected %v, got %v", 0, s.GetCursor())
+	}
+
+	// interoperate with parsec combinators.
+	y := parsec.And(nil, expr.Parser(), parsec.End())
+	node, _ := y(parsec.NewScanner([]byte("1 + 2")))
+	if ns := node.([]parsec.ParsecNode); ns[0].(int) != 3 {
+		t.Errorf("expected %v, got %v", 3, ns[0])
+	}
+}
+
+func TestRepetition(t *testing.T) {
+	ident := Map(Term(parsec.Ident()), func(t *parsec.Terminal) string {
+		return t.Value
+	})
+	text := []byte("a b c")
+
+	names, s, _ := Kleene(ident)(parsec.NewScanner(text))
+	if ref := []string{"a", "b", "c"}; !reflect.DeepEqual(names, ref) {
+		t.Errorf("expected %v, got %v", ref, names)
+	} else if !s.Endof() {
+		t.Errorf("expected end of text")
+	}
+	names, _, ok := Kleene(ident)(parsec.NewScanner([]byte("10")))
+	if !ok || len(names) != 0 {
+		t.Errorf("unexpected %v", names)
+	}
+	if _, _, ok = Many(ident)(parsec.NewScanner([]byte("10"))); ok {
+		t.Errorf("expected failure")
+	}
+	name, s, ok := Maybe(ident, "none")(parsec.NewScanner([]byte("10")))
+	if !ok || name != "none" {
+		t.Errorf("expected %v, got %v", "none", name)
+	} else if s.GetCursor() != 0 {
+		t.Errorf("expected %v, got %v", 0, s.GetCursor())
+	}
+
+	// repetition of parser that does not consume input.
+	empty := Maybe(ident, "")
+	_, _, err := Parse(Kleene(empty), parsec.NewScanner([]byte("a 10")))
+	if perr, ok := err.(*parsec.ParseError); !ok {
+		t.Errorf("unexpected %v", err)
+	} else if perr.Cursor != 1 || perr.Message != "repetition made no progress" {
+		t.Errorf("unexpected %v", perr)
+	}
+}
+
+func TestLift(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("expected panic")
+		}
+	}()
+	Lift[string](parsec.Int())(parsec.NewScanner([]byte("10")))
+}